@@ -0,0 +1,29 @@
+package wg
+
+import "context"
+
+// Client is the set of wg operations used by long running services,
+// swap it out in tests (see package wgtest)
+type Client interface {
+	ShowInterfaces(ctx context.Context) ([]string, error)
+	Show(ctx context.Context, iface string) (Conf, error)
+	Set(ctx context.Context, opt Opt) error
+}
+
+// Cli is a Client using the wg binary
+type Cli struct{}
+
+// ShowInterfaces calls ShowInterfacesCtx
+func (Cli) ShowInterfaces(ctx context.Context) ([]string, error) {
+	return ShowInterfacesCtx(ctx)
+}
+
+// Show calls ShowCtx
+func (Cli) Show(ctx context.Context, iface string) (Conf, error) {
+	return ShowCtx(ctx, iface)
+}
+
+// Set calls SetCtx
+func (Cli) Set(ctx context.Context, opt Opt) error {
+	return SetCtx(ctx, opt)
+}
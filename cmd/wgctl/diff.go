@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/netip"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	wg "seankhliao.com/go-wg"
+)
+
+// diff computes the options to turn live into target for iface,
+// changes describes them for humans,
+// keyFile writes a key to a file and returns its path (Opt only takes key files),
+// AllowedIPs are compared as sets of masked prefixes,
+// a target peer without AllowedIPs keeps the live ones
+// as Opt can't clear them short of removing the peer
+func diff(iface string, live, target wg.Conf, keyFile func(key string) (string, error)) (opt wg.Opt, changes []string, err error) {
+	opt.Interface = iface
+	if target.ListenPort != 0 && target.ListenPort != live.ListenPort {
+		opt.ListenPort = target.ListenPort
+		changes = append(changes, "~ interface listen-port "+strconv.Itoa(live.ListenPort)+" -> "+strconv.Itoa(target.ListenPort))
+	}
+	if target.FwMark != live.FwMark {
+		opt.FwMark = target.FwMark
+		if opt.FwMark == "" {
+			opt.FwMark = "off"
+		}
+		changes = append(changes, "~ interface fwmark "+live.FwMark+" -> "+opt.FwMark)
+	}
+	if target.PrivateKey != "" && target.PrivateKey != live.PrivateKey {
+		opt.PrivKeyFpath, err = keyFile(target.PrivateKey)
+		if err != nil {
+			return opt, nil, err
+		}
+		changes = append(changes, "~ interface private-key")
+	}
+
+	livePeers := make(map[string]wg.Peer)
+	for _, p := range live.Peers {
+		livePeers[p.PublicKey] = p
+	}
+	targetPeers := make(map[string]bool)
+	for _, p := range target.Peers {
+		targetPeers[p.PublicKey] = true
+	}
+	for _, p := range live.Peers {
+		if !targetPeers[p.PublicKey] {
+			opt.Peers = append(opt.Peers, wg.OptPeer{PublicKey: p.PublicKey, Remove: true})
+			changes = append(changes, "- peer "+p.PublicKey)
+		}
+	}
+
+	for _, p := range target.Peers {
+		lp, exists := livePeers[p.PublicKey]
+		op := wg.OptPeer{PublicKey: p.PublicKey}
+		var fields []string
+		if p.PresharedKey != lp.PresharedKey {
+			if p.PresharedKey == "" {
+				op.PskFpath = "/dev/null"
+			} else {
+				op.PskFpath, err = keyFile(p.PresharedKey)
+				if err != nil {
+					return opt, nil, err
+				}
+			}
+			fields = append(fields, "preshared-key")
+		}
+		if p.Endpoint != "" && p.Endpoint != lp.Endpoint {
+			op.Endpoint = p.Endpoint
+			fields = append(fields, "endpoint "+p.Endpoint)
+		}
+		if p.PersistentKeepalive != lp.PersistentKeepalive {
+			pka := p.PersistentKeepalive
+			op.PersistentKeepalive = &pka
+			fields = append(fields, "persistent-keepalive "+strconv.Itoa(pka))
+		}
+		if len(p.AllowedIPs) != 0 && !reflect.DeepEqual(prefixSet(p.AllowedIPs), prefixSet(lp.AllowedIPs)) {
+			op.AllowedIPs = p.AllowedIPs
+			fields = append(fields, "allowed-ips "+strings.Join(p.AllowedIPs, ","))
+		}
+		switch {
+		case !exists:
+			changes = append(changes, "+ peer "+p.PublicKey+" "+strings.Join(fields, " "))
+		case len(fields) != 0:
+			changes = append(changes, "~ peer "+p.PublicKey+" "+strings.Join(fields, " "))
+		default:
+			continue
+		}
+		opt.Peers = append(opt.Peers, op)
+	}
+	return opt, changes, nil
+}
+
+// prefixSet returns ips masked, deduplicated and sorted,
+// invalid entries are kept as is so they still show up as a difference
+func prefixSet(ips []string) []string {
+	seen := make(map[string]bool)
+	var set []string
+	for _, ip := range ips {
+		if prefix, err := netip.ParsePrefix(strings.TrimSpace(ip)); err == nil {
+			ip = prefix.Masked().String()
+		}
+		if !seen[ip] {
+			seen[ip] = true
+			set = append(set, ip)
+		}
+	}
+	sort.Strings(set)
+	return set
+}
@@ -0,0 +1,568 @@
+// Command wgctl is a scriptable superset of wg built on go-wg
+//
+//	wgctl show [-json] [-sort handshake] [-names file] [iface...]
+//	wgctl peer add|update [-endpoint host:port] [-allowed-ips ip/mask,...] [-keepalive n] [-psk file] iface pubkey
+//	wgctl peer remove iface pubkey
+//	wgctl genkey | genpsk | pubkey < privkey
+//	wgctl diff [-keystore spec] iface file.conf
+//	wgctl apply [-keystore spec] iface file.conf
+//	wgctl lint [-format text|json|sarif] file.conf...
+//	wgctl export [-format conf|json|yaml] iface
+//	wgctl client -endpoint host:port -prefix ip/mask,... [-reserve ip,...] [-dns ip,...] [-full] [-allowed-ips ip/mask,...] [-psk] [-qr ansi|png|svg] iface
+//	wgctl psk [-replace] iface
+//	wgctl keystore -store spec get|put|rm name
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	wg "seankhliao.com/go-wg"
+	"seankhliao.com/go-wg/ipam"
+	"seankhliao.com/go-wg/keystore"
+	"seankhliao.com/go-wg/lint"
+	"seankhliao.com/go-wg/provision"
+	"seankhliao.com/go-wg/psk"
+	"seankhliao.com/go-wg/qrcode"
+)
+
+const usage = `wgctl is a scriptable superset of wg
+
+usage:
+	wgctl show [-json] [-sort handshake] [-names file] [iface...]
+	wgctl peer add|update [-endpoint host:port] [-allowed-ips ip/mask,...] [-keepalive n] [-psk file] iface pubkey
+	wgctl peer remove iface pubkey
+	wgctl genkey
+	wgctl genpsk
+	wgctl pubkey < privkey
+	wgctl diff [-keystore spec] iface file.conf
+	wgctl apply [-keystore spec] iface file.conf
+	wgctl lint [-format text|json|sarif] file.conf...
+	wgctl export [-format conf|json|yaml] iface
+	wgctl client -endpoint host:port -prefix ip/mask,... [-reserve ip,...] [-dns ip,...] [-full] [-allowed-ips ip/mask,...] [-psk] [-qr ansi|png|svg] iface
+	wgctl psk [-replace] iface
+	wgctl keystore -store spec get|put|rm name
+
+keystore specs: dir:path, file:path ($WG_KEYSTORE_PASSPHRASE), env:PREFIX, fd:N
+apply keeps the live allowed ips of peers that have none in file.conf
+`
+
+// client talks to wireguard, swapped out in tests
+var client wg.Client = wg.Cli{}
+
+func main() {
+	err := run(context.Background(), os.Args[1:], os.Stdin, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wgctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(stdout, usage)
+		return nil
+	}
+	switch args[0] {
+	case "show":
+		return show(ctx, args[1:], stdout)
+	case "peer":
+		return peer(ctx, args[1:])
+	case "genkey":
+		k, err := wg.NewPrivateKey()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, k)
+	case "genpsk":
+		k, err := wg.NewPresharedKey()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, k)
+	case "pubkey":
+		b, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+		k, err := wg.PublicKeyFor(strings.TrimSpace(string(b)))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, k)
+	case "diff":
+		return apply(ctx, args[1:], stdout, true)
+	case "apply":
+		return apply(ctx, args[1:], stdout, false)
+	case "lint":
+		return lintCmd(args[1:], stdout)
+	case "export":
+		return export(ctx, args[1:], stdout)
+	case "client":
+		return clientCmd(ctx, args[1:], stdout)
+	case "psk":
+		return pskCmd(ctx, args[1:], stdout)
+	case "keystore":
+		return keystoreCmd(args[1:], stdin, stdout)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+	default:
+		return fmt.Errorf("unknown command %v\n%v", args[0], usage)
+	}
+	return nil
+}
+
+// readNames reads a file of "pubkey name" lines, # starts a comment
+func readNames(fpath string) (map[string]string, error) {
+	names := make(map[string]string)
+	if fpath == "" {
+		return names, nil
+	}
+	f, err := os.Open(fpath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words := strings.SplitN(line, " ", 2)
+		if len(words) != 2 {
+			return nil, fmt.Errorf("names: invalid line %v", line)
+		}
+		names[words[0]] = strings.TrimSpace(words[1])
+	}
+	return names, sc.Err()
+}
+
+// sortHandshake sorts peers by most recent handshake first, never last
+func sortHandshake(peers []wg.Peer) {
+	sort.SliceStable(peers, func(i, j int) bool {
+		hi, hj := peers[i].LatestHandshake, peers[j].LatestHandshake
+		if hi == 0 || hj == 0 {
+			return hi != 0
+		}
+		return hi < hj
+	})
+}
+
+func show(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("show", flag.ContinueOnError)
+	jsonOut := fs.Bool("json", false, "output json")
+	sortBy := fs.String("sort", "", "sort peers by: handshake")
+	namesFile := fs.String("names", "", "file of \"pubkey name\" lines")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	names, err := readNames(*namesFile)
+	if err != nil {
+		return err
+	}
+	ifaces := fs.Args()
+	if len(ifaces) == 0 {
+		ifaces, err = client.ShowInterfaces(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	confs := make(map[string]wg.Conf)
+	for _, iface := range ifaces {
+		c, err := client.Show(ctx, iface)
+		if err != nil {
+			return err
+		}
+		switch *sortBy {
+		case "":
+		case "handshake":
+			sortHandshake(c.Peers)
+		default:
+			return fmt.Errorf("show: unknown sort %v", *sortBy)
+		}
+		// never print secrets, as in wg show
+		c.PrivateKey = ""
+		for i := range c.Peers {
+			c.Peers[i].PresharedKey = ""
+		}
+		confs[iface] = c
+	}
+
+	if *jsonOut {
+		e := json.NewEncoder(stdout)
+		e.SetIndent("", "  ")
+		return e.Encode(confs)
+	}
+	for i, iface := range ifaces {
+		if i != 0 {
+			fmt.Fprintln(stdout)
+		}
+		printConf(stdout, iface, confs[iface], names)
+	}
+	return nil
+}
+
+func printConf(w io.Writer, iface string, c wg.Conf, names map[string]string) {
+	fmt.Fprintf(w, "interface: %v\n", iface)
+	fmt.Fprintf(w, "  public key: %v\n", c.PublicKey)
+	if c.ListenPort != 0 {
+		fmt.Fprintf(w, "  listening port: %v\n", c.ListenPort)
+	}
+	if c.FwMark != "" {
+		fmt.Fprintf(w, "  fwmark: %v\n", c.FwMark)
+	}
+	for _, p := range c.Peers {
+		fmt.Fprintln(w)
+		if name, ok := names[p.PublicKey]; ok {
+			fmt.Fprintf(w, "peer: %v (%v)\n", name, p.PublicKey)
+		} else {
+			fmt.Fprintf(w, "peer: %v\n", p.PublicKey)
+		}
+		if p.Endpoint != "" {
+			fmt.Fprintf(w, "  endpoint: %v\n", p.Endpoint)
+		}
+		fmt.Fprintf(w, "  allowed ips: %v\n", strings.Join(p.AllowedIPs, ", "))
+		if p.LatestHandshake != 0 {
+			fmt.Fprintf(w, "  latest handshake: %v seconds ago\n", p.LatestHandshake)
+		} else {
+			fmt.Fprintf(w, "  latest handshake: never\n")
+		}
+		fmt.Fprintf(w, "  transfer: %v B received, %v B sent\n", p.Received, p.Sent)
+		if p.PersistentKeepalive != 0 {
+			fmt.Fprintf(w, "  persistent keepalive: every %v seconds\n", p.PersistentKeepalive)
+		}
+	}
+}
+
+func peer(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("peer: missing add, update or remove")
+	}
+	fs := flag.NewFlagSet("peer "+args[0], flag.ContinueOnError)
+	endpoint := fs.String("endpoint", "", "host:port")
+	allowedIPs := fs.String("allowed-ips", "", "comma separated ip/mask")
+	keepalive := fs.String("keepalive", "", "persistent keepalive interval in seconds")
+	psk := fs.String("psk", "", "preshared key file")
+	err := fs.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("peer %v: need iface and pubkey", args[0])
+	}
+	op := wg.OptPeer{PublicKey: fs.Arg(1)}
+	switch args[0] {
+	case "add", "update":
+		op.Endpoint = *endpoint
+		op.PskFpath = *psk
+		if *allowedIPs != "" {
+			for _, ip := range strings.Split(*allowedIPs, ",") {
+				op.AllowedIPs = append(op.AllowedIPs, strings.TrimSpace(ip))
+			}
+		}
+		if *keepalive != "" {
+			pka, err := strconv.Atoi(*keepalive)
+			if err != nil {
+				return fmt.Errorf("peer %v: keepalive: %v", args[0], err)
+			}
+			op.PersistentKeepalive = &pka
+		}
+	case "remove":
+		op.Remove = true
+	default:
+		return fmt.Errorf("peer: unknown command %v", args[0])
+	}
+	return client.Set(ctx, wg.Opt{Interface: fs.Arg(0), Peers: []wg.OptPeer{op}})
+}
+
+func apply(ctx context.Context, args []string, stdout io.Writer, dryRun bool) error {
+	fs := flag.NewFlagSet("apply", flag.ContinueOnError)
+	store := fs.String("keystore", "", "resolve keystore:name key references from this key store")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	args = fs.Args()
+	if len(args) != 2 {
+		return fmt.Errorf("need iface and file")
+	}
+	target, err := wg.LoadFile(args[1])
+	if err != nil {
+		return err
+	}
+	if *store != "" {
+		ks, err := keystore.Open(*store)
+		if err != nil {
+			return err
+		}
+		target, err = keystore.Resolve(target, ks)
+		if err != nil {
+			return err
+		}
+	}
+	if refs := target.KeyRefs(); len(refs) != 0 {
+		return fmt.Errorf("%v: unresolved key references %v, use -keystore", args[1], strings.Join(refs, ", "))
+	}
+	live, err := client.Show(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	keyFile, cleanup, err := wg.KeyFiles()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	opt, changes, err := diff(args[0], live, target, keyFile)
+	if err != nil {
+		return err
+	}
+	for _, c := range changes {
+		fmt.Fprintln(stdout, c)
+	}
+	if dryRun || len(changes) == 0 {
+		return nil
+	}
+	return client.Set(ctx, opt)
+}
+
+func lintCmd(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	format := fs.String("format", "text", "output format: text, json, sarif")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	var issues []lint.Issue
+	for _, fpath := range fs.Args() {
+		is, err := lint.LintFile(fpath)
+		if err != nil {
+			return err
+		}
+		issues = append(issues, is...)
+	}
+
+	switch *format {
+	case "text":
+		for _, i := range issues {
+			fmt.Fprintf(stdout, "%v: %v %v: %v\n", i.Path, i.Severity, i.Rule, i.Message)
+		}
+	case "json", "sarif":
+		var b []byte
+		if *format == "json" {
+			b, err = lint.JSON(issues)
+		} else {
+			b, err = lint.Default.SARIF(issues)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, string(b))
+	default:
+		return fmt.Errorf("lint: unknown format %v", *format)
+	}
+	for _, i := range issues {
+		if i.Severity == lint.Error {
+			return fmt.Errorf("lint: found errors")
+		}
+	}
+	return nil
+}
+
+func export(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "conf", "output format: conf, json, yaml")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("export: need iface")
+	}
+	c, err := client.Show(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var b []byte
+	switch *format {
+	case "conf":
+		b = c.Bytes()
+	case "json":
+		b, err = json.MarshalIndent(c, "", "  ")
+		b = append(b, '\n')
+	case "yaml":
+		b, err = yaml.Marshal(c)
+	default:
+		return fmt.Errorf("export: unknown format %v", *format)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(b)
+	return err
+}
+
+// splitList splits a comma separated flag value
+func splitList(s string) []string {
+	var l []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			l = append(l, v)
+		}
+	}
+	return l
+}
+
+func clientCmd(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("client", flag.ContinueOnError)
+	endpoint := fs.String("endpoint", "", "server host:port for the client to connect to")
+	prefixes := fs.String("prefix", "", "comma separated tunnel prefixes to allocate client addresses from")
+	reserve := fs.String("reserve", "", "comma separated addresses to never allocate, eg the server's")
+	dns := fs.String("dns", "", "comma separated client DNS servers and search domains")
+	full := fs.Bool("full", false, "route all client traffic through the server")
+	allowedIPs := fs.String("allowed-ips", "", "comma separated extra routes for split tunnel")
+	psk := fs.Bool("psk", false, "generate a preshared key")
+	qr := fs.String("qr", "", "output a QR code instead of text: ansi, png, svg")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *endpoint == "" || *prefixes == "" {
+		return fmt.Errorf("client: need -endpoint, -prefix and iface")
+	}
+	iface := fs.Arg(0)
+
+	pool, err := ipam.New(splitList(*prefixes)...)
+	if err != nil {
+		return err
+	}
+	err = pool.Reserve(splitList(*reserve)...)
+	if err != nil {
+		return err
+	}
+	server, err := client.Show(ctx, iface)
+	if err != nil {
+		return err
+	}
+	conf, err := provision.GenerateClientConf(&server, *endpoint, provision.Params{
+		IPAM:         pool,
+		DNS:          splitList(*dns),
+		FullTunnel:   *full,
+		AllowedIPs:   splitList(*allowedIPs),
+		PresharedKey: *psk,
+	})
+	if err != nil {
+		return err
+	}
+
+	keyFile, cleanup, err := wg.KeyFiles()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	p := server.Peers[len(server.Peers)-1]
+	op := wg.OptPeer{PublicKey: p.PublicKey, AllowedIPs: p.AllowedIPs}
+	if p.PresharedKey != "" {
+		op.PskFpath, err = keyFile(p.PresharedKey)
+		if err != nil {
+			return err
+		}
+	}
+	err = client.Set(ctx, wg.Opt{Interface: iface, Peers: []wg.OptPeer{op}})
+	if err != nil {
+		return err
+	}
+
+	var b []byte
+	switch *qr {
+	case "":
+		b = conf.Bytes()
+	case "ansi":
+		b, err = qrcode.Terminal(conf)
+	case "png":
+		b, err = qrcode.PNG(conf, 0)
+	case "svg":
+		b, err = qrcode.SVG(conf)
+	default:
+		return fmt.Errorf("client: unknown qr format %v", *qr)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(b)
+	return err
+}
+
+func pskCmd(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("psk", flag.ContinueOnError)
+	replace := fs.Bool("replace", false, "also replace existing preshared keys")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("psk: need iface")
+	}
+	r, err := psk.Enable(ctx, client, fs.Arg(0), *replace)
+	if err != nil {
+		return err
+	}
+	for _, k := range r.Keys {
+		b, err := r.Snippet(k.Peer)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "# peer %v\n%s\n", k.Peer, b)
+	}
+	return nil
+}
+
+func keystoreCmd(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("keystore", flag.ContinueOnError)
+	store := fs.String("store", "", "key store spec")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *store == "" || fs.NArg() != 2 {
+		return fmt.Errorf("keystore: need -store, command and name")
+	}
+	ks, err := keystore.Open(*store)
+	if err != nil {
+		return err
+	}
+	name := fs.Arg(1)
+	switch fs.Arg(0) {
+	case "get":
+		k, err := ks.Get(name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, k)
+	case "put":
+		b, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+		return ks.Put(name, strings.TrimSpace(string(b)))
+	case "rm":
+		return ks.Delete(name)
+	default:
+		return fmt.Errorf("keystore: unknown command %v", fs.Arg(0))
+	}
+	return nil
+}
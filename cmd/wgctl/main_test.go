@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	wg "seankhliao.com/go-wg"
+	"seankhliao.com/go-wg/wgtest"
+)
+
+var (
+	se = "%v #%v errored: %v"
+	sf = "%v #%v \nexp: >%v< \ngot: >%v<"
+)
+
+func TestDiff(t *testing.T) {
+	pka := 25
+	zero := 0
+	cases := []struct {
+		Live, Target wg.Conf
+		O            wg.Opt
+		Changes      []string
+	}{
+		{
+			wg.Conf{},
+			wg.Conf{},
+			wg.Opt{Interface: "wg0"},
+			nil,
+		}, {
+			wg.Conf{
+				Interface: wg.Interface{ListenPort: 51820, PrivateKey: "privkey_a"},
+				Peers: []wg.Peer{
+					{PublicKey: "pubkey_a", AllowedIPs: []string{"10.0.0.2/32"}},
+					{PublicKey: "pubkey_b", AllowedIPs: []string{"10.0.0.3/32"}, PersistentKeepalive: 25},
+					{PublicKey: "pubkey_c", AllowedIPs: []string{"10.0.0.4/32"}},
+				},
+			},
+			wg.Conf{
+				Interface: wg.Interface{ListenPort: 51821, PrivateKey: "privkey_b"},
+				Peers: []wg.Peer{
+					{PublicKey: "pubkey_b", AllowedIPs: []string{"10.0.0.3/32", "10.1.0.0/16"}, PresharedKey: "psk_b"},
+					{PublicKey: "pubkey_c", AllowedIPs: []string{"10.0.0.4/32"}},
+					{PublicKey: "pubkey_d", AllowedIPs: []string{"10.0.0.5/32"}, Endpoint: "1.2.3.4:51820", PersistentKeepalive: 25},
+				},
+			},
+			wg.Opt{
+				Interface:    "wg0",
+				ListenPort:   51821,
+				PrivKeyFpath: "key:privkey_b",
+				Peers: []wg.OptPeer{
+					{PublicKey: "pubkey_a", Remove: true},
+					{PublicKey: "pubkey_b", PskFpath: "key:psk_b", PersistentKeepalive: &zero, AllowedIPs: []string{"10.0.0.3/32", "10.1.0.0/16"}},
+					{PublicKey: "pubkey_d", Endpoint: "1.2.3.4:51820", PersistentKeepalive: &pka, AllowedIPs: []string{"10.0.0.5/32"}},
+				},
+			},
+			[]string{
+				"~ interface listen-port 51820 -> 51821",
+				"~ interface private-key",
+				"- peer pubkey_a",
+				"~ peer pubkey_b preshared-key persistent-keepalive 0 allowed-ips 10.0.0.3/32,10.1.0.0/16",
+				"+ peer pubkey_d endpoint 1.2.3.4:51820 persistent-keepalive 25 allowed-ips 10.0.0.5/32",
+			},
+		}, {
+			// same prefixes reordered and unmasked, no AllowedIPs keeps the live ones
+			wg.Conf{Peers: []wg.Peer{
+				{PublicKey: "pubkey_a", AllowedIPs: []string{"10.0.0.2/32", "10.1.0.0/16"}},
+				{PublicKey: "pubkey_b", AllowedIPs: []string{"10.0.0.3/32"}},
+			}},
+			wg.Conf{Peers: []wg.Peer{
+				{PublicKey: "pubkey_a", AllowedIPs: []string{"10.1.2.3/16", "10.0.0.2/32"}},
+				{PublicKey: "pubkey_b"},
+			}},
+			wg.Opt{Interface: "wg0"},
+			nil,
+		},
+	}
+	keyFile := func(key string) (string, error) { return "key:" + key, nil }
+	for i, c := range cases {
+		opt, changes, err := diff("wg0", c.Live, c.Target, keyFile)
+		if err != nil {
+			t.Errorf(se, "diff", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(opt, c.O) {
+			t.Errorf(sf, "diff opt", i, c.O, opt)
+		}
+		if !reflect.DeepEqual(changes, c.Changes) {
+			t.Errorf(sf, "diff changes", i, c.Changes, changes)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wgctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	names := filepath.Join(dir, "names")
+	ioutil.WriteFile(names, []byte("# comment\npubkey_b laptop\n"), 0644)
+	conf := filepath.Join(dir, "wg0.conf")
+	ioutil.WriteFile(conf, []byte("[Interface]\nListenPort = 51820\n[Peer]\nPublicKey = pubkey_c\nAllowedIPs = 10.0.0.4/32\n"), 0600)
+	refConf := filepath.Join(dir, "wg1.conf")
+	ioutil.WriteFile(refConf, []byte("[Interface]\nListenPort = 51820\nPrivateKey = keystore:wg0\n[Peer]\nPublicKey = pubkey_a\nPresharedKey = keystore:wg0-a\nAllowedIPs = 10.0.0.2/32\n"), 0600)
+	keys := "dir:" + filepath.Join(dir, "keys")
+
+	cases := []struct {
+		Args    []string
+		Stdin   string
+		Contain []string
+		Peers   []string // peers after
+	}{
+		{
+			[]string{"show", "-sort", "handshake", "-names", names},
+			"",
+			[]string{"interface: wg0", "peer: laptop (pubkey_b)\n  allowed ips: 10.0.0.3/32\n  latest handshake: 5 seconds ago", "latest handshake: never"},
+			[]string{"pubkey_a", "pubkey_b"},
+		}, {
+			[]string{"show", "-json", "wg0"},
+			"",
+			[]string{`"wg0": {`},
+			[]string{"pubkey_a", "pubkey_b"},
+		}, {
+			[]string{"peer", "add", "-allowed-ips", "10.0.0.4/32", "-keepalive", "25", "wg0", "pubkey_c"},
+			"",
+			nil,
+			[]string{"pubkey_a", "pubkey_b", "pubkey_c"},
+		}, {
+			[]string{"peer", "remove", "wg0", "pubkey_a"},
+			"",
+			nil,
+			[]string{"pubkey_b"},
+		}, {
+			[]string{"pubkey"},
+			"AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA=\n",
+			[]string{"B6N8vBQgk8i3VdwbEOhstCY3StFqqFPtC9/AsrhtHHw="},
+			[]string{"pubkey_a", "pubkey_b"},
+		}, {
+			[]string{"diff", "wg0", conf},
+			"",
+			[]string{"- peer pubkey_a", "- peer pubkey_b", "+ peer pubkey_c"},
+			[]string{"pubkey_a", "pubkey_b"},
+		}, {
+			[]string{"apply", "wg0", conf},
+			"",
+			[]string{"+ peer pubkey_c"},
+			[]string{"pubkey_c"},
+		}, {
+			[]string{"export", "wg0"},
+			"",
+			[]string{"[Interface]\nListenPort = 51820\nPrivateKey = AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA=\n\n[Peer]\nPublicKey = pubkey_a"},
+			[]string{"pubkey_a", "pubkey_b"},
+		}, {
+			[]string{"client", "-endpoint", "vpn.example.com:51820", "-prefix", "10.0.0.0/24", "-reserve", "10.0.0.1", "wg0"},
+			"",
+			[]string{"Address = 10.0.0.4/32\n", "PublicKey = B6N8vBQgk8i3VdwbEOhstCY3StFqqFPtC9/AsrhtHHw=\nAllowedIPs = 10.0.0.0/24\nEndpoint = vpn.example.com:51820\nPersistentKeepalive = 25\n"},
+			[]string{"pubkey_a", "pubkey_b", "client"},
+		}, {
+			[]string{"client", "-endpoint", "vpn.example.com:51820", "-prefix", "10.0.0.0/24", "-qr", "ansi", "wg0"},
+			"",
+			[]string{"\x1b[97;107m▀"},
+			[]string{"pubkey_a", "pubkey_b", "client"},
+		}, {
+			[]string{"psk", "wg0"},
+			"",
+			[]string{"# peer pubkey_a\n[Peer]\nPublicKey = B6N8vBQgk8i3VdwbEOhstCY3StFqqFPtC9/AsrhtHHw=\nPresharedKey = ", "# peer pubkey_b\n"},
+			[]string{"pubkey_a", "pubkey_b"},
+		}, {
+			[]string{"keystore", "-store", keys, "put", "wg0"},
+			"ICEiIyQlJicoKSorLC0uLzAxMjM0NTY3ODk6Ozw9Pj8=\n",
+			nil,
+			[]string{"pubkey_a", "pubkey_b"},
+		}, {
+			[]string{"keystore", "-store", keys, "put", "wg0-a"},
+			"AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA=\n",
+			nil,
+			[]string{"pubkey_a", "pubkey_b"},
+		}, {
+			[]string{"keystore", "-store", keys, "get", "wg0"},
+			"",
+			[]string{"ICEiIyQlJicoKSorLC0uLzAxMjM0NTY3ODk6Ozw9Pj8=\n"},
+			[]string{"pubkey_a", "pubkey_b"},
+		}, {
+			[]string{"apply", "-keystore", keys, "wg0", refConf},
+			"",
+			[]string{"~ interface private-key", "~ peer pubkey_a preshared-key", "- peer pubkey_b"},
+			[]string{"pubkey_a"},
+		}, {
+			[]string{"export", "-format", "yaml", "wg0"},
+			"",
+			[]string{"interface:\n    listen_port: 51820\n", "peers:\n    - public_key: pubkey_a"},
+			[]string{"pubkey_a", "pubkey_b"},
+		},
+	}
+	for i, c := range cases {
+		fake := wgtest.NewClient(map[string]wg.Conf{
+			"wg0": {
+				Interface: wg.Interface{ListenPort: 51820, PrivateKey: "AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA="},
+				Peers: []wg.Peer{
+					{PublicKey: "pubkey_a", AllowedIPs: []string{"10.0.0.2/32"}},
+					{PublicKey: "pubkey_b", AllowedIPs: []string{"10.0.0.3/32"}, LatestHandshake: 5},
+				},
+			},
+		})
+		client = fake
+		var out bytes.Buffer
+		err := run(context.Background(), c.Args, strings.NewReader(c.Stdin), &out)
+		if err != nil {
+			t.Errorf(se, strings.Join(c.Args, " "), i, err)
+			continue
+		}
+		for _, s := range c.Contain {
+			if !strings.Contains(out.String(), s) {
+				t.Errorf(sf, strings.Join(c.Args, " "), i, s, out.String())
+			}
+		}
+		live, _ := fake.Show(context.Background(), "wg0")
+		var peers []string
+		for j, p := range live.Peers {
+			if j == 2 && c.Args[0] == "client" {
+				// generated key
+				p.PublicKey = "client"
+			}
+			peers = append(peers, p.PublicKey)
+		}
+		if !reflect.DeepEqual(peers, c.Peers) {
+			t.Errorf(sf, strings.Join(c.Args, " ")+" peers", i, c.Peers, peers)
+		}
+	}
+
+	// key references need -keystore
+	for i, cmd := range []string{"diff", "apply"} {
+		fake := wgtest.NewClient(map[string]wg.Conf{"wg0": {}})
+		client = fake
+		err := run(context.Background(), []string{cmd, "wg0", refConf}, strings.NewReader(""), ioutil.Discard)
+		if err == nil || !strings.Contains(err.Error(), "wg0, wg0-a") {
+			t.Errorf(sf, cmd, i, "unresolved key references wg0, wg0-a", err)
+		}
+		if sets := fake.Sets(); len(sets) != 0 {
+			t.Errorf(sf, cmd+" sets", i, 0, sets)
+		}
+	}
+	// show never prints secrets
+	client = wgtest.NewClient(map[string]wg.Conf{
+		"wg0": {
+			Interface: wg.Interface{PrivateKey: "AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA="},
+			Peers:     []wg.Peer{{PublicKey: "pubkey_a", PresharedKey: "ICEiIyQlJicoKSorLC0uLzAxMjM0NTY3ODk6Ozw9Pj8="}},
+		},
+	})
+	for i, args := range [][]string{{"show", "wg0"}, {"show", "-json", "wg0"}} {
+		var out bytes.Buffer
+		err := run(context.Background(), args, strings.NewReader(""), &out)
+		if err != nil {
+			t.Errorf(se, strings.Join(args, " "), i, err)
+			continue
+		}
+		for _, secret := range []string{"AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA=", "ICEiIyQlJicoKSorLC0uLzAxMjM0NTY3ODk6Ozw9Pj8=", "private_key", "preshared_key"} {
+			if strings.Contains(out.String(), secret) {
+				t.Errorf(sf, strings.Join(args, " "), i, "no "+secret, out.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,224 @@
+// Package dns applies the DNS = entries of a wg-quick config on tunnel up
+// and restores the previous configuration on tunnel down
+package dns
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/netip"
+	"os"
+
+	wg "seankhliao.com/go-wg"
+)
+
+// Config is the DNS configuration for an interface
+type Config struct {
+	Servers []string // ip addresses
+	Search  []string // search domains
+}
+
+// NewConfig splits the DNS entries of an Interface into servers and search domains
+// the same way wg-quick does: anything that is an ip address is a server
+func NewConfig(i wg.Interface) Config {
+	var c Config
+	for _, d := range i.DNS {
+		if _, err := netip.ParseAddr(d); err == nil {
+			c.Servers = append(c.Servers, d)
+		} else {
+			c.Search = append(c.Search, d)
+		}
+	}
+	return c
+}
+
+// Bytes encodes a Config in resolv.conf format
+func (c Config) Bytes() []byte {
+	buf := &bytes.Buffer{}
+	for _, s := range c.Servers {
+		buf.WriteString("nameserver " + s + "\n")
+	}
+	if len(c.Search) != 0 {
+		buf.WriteString("search")
+		for _, s := range c.Search {
+			buf.WriteString(" " + s)
+		}
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
+
+// Applier sets DNS for an interface
+type Applier interface {
+	// Apply sets the DNS config for iface
+	Apply(ctx context.Context, iface string, c Config) error
+	// Revert restores DNS to what it was before Apply
+	Revert(ctx context.Context, iface string) error
+}
+
+func execOrDefault(e wg.Executor) wg.Executor {
+	if e == nil {
+		return wg.ExecCmd{}
+	}
+	return e
+}
+
+// Resolvconf applies DNS through resolvconf(8), like wg-quick
+type Resolvconf struct {
+	// Exec runs resolvconf, defaults to wg.ExecCmd
+	Exec wg.Executor
+}
+
+// Apply registers the config under tun.iface
+// resolvconf -a tun.iface -m 0 -x
+func (r Resolvconf) Apply(ctx context.Context, iface string, c Config) error {
+	_, err := execOrDefault(r.Exec).Exec(ctx, c.Bytes(), "resolvconf", "-a", "tun."+iface, "-m", "0", "-x")
+	if err != nil {
+		return fmt.Errorf("resolvconf apply: %v", err)
+	}
+	return nil
+}
+
+// Revert removes the config registered under tun.iface
+// resolvconf -d tun.iface -f
+func (r Resolvconf) Revert(ctx context.Context, iface string) error {
+	_, err := execOrDefault(r.Exec).Exec(ctx, nil, "resolvconf", "-d", "tun."+iface, "-f")
+	if err != nil {
+		return fmt.Errorf("resolvconf revert: %v", err)
+	}
+	return nil
+}
+
+// Resolved applies DNS through systemd-resolved with resolvectl(1)
+type Resolved struct {
+	// Exec runs resolvectl, defaults to wg.ExecCmd
+	Exec wg.Executor
+}
+
+// Apply sets per link servers and domains
+// resolvectl dns iface ...
+// resolvectl domain iface ...
+func (r Resolved) Apply(ctx context.Context, iface string, c Config) error {
+	e := execOrDefault(r.Exec)
+	_, err := e.Exec(ctx, nil, "resolvectl", append([]string{"dns", iface}, c.Servers...)...)
+	if err != nil {
+		return fmt.Errorf("resolvectl dns: %v", err)
+	}
+	if len(c.Search) != 0 {
+		_, err = e.Exec(ctx, nil, "resolvectl", append([]string{"domain", iface}, c.Search...)...)
+		if err != nil {
+			return fmt.Errorf("resolvectl domain: %v", err)
+		}
+	}
+	return nil
+}
+
+// Revert drops all per link settings
+// resolvectl revert iface
+func (r Resolved) Revert(ctx context.Context, iface string) error {
+	_, err := execOrDefault(r.Exec).Exec(ctx, nil, "resolvectl", "revert", iface)
+	if err != nil {
+		return fmt.Errorf("resolvectl revert: %v", err)
+	}
+	return nil
+}
+
+// File manages resolv.conf directly,
+// the original is kept next to it and put back on Revert,
+// a resolv.conf that didn't exist is removed again.
+// A symlinked resolv.conf (eg to systemd-resolved's stub) is replaced by a file
+// instead of written through, and the link itself is restored
+type File struct {
+	// Path of resolv.conf, defaults to /etc/resolv.conf
+	Path string
+}
+
+func (f File) path() string {
+	if f.Path == "" {
+		return "/etc/resolv.conf"
+	}
+	return f.Path
+}
+
+func (f File) backup(iface string) string {
+	return f.path() + ".wg-" + iface
+}
+
+// absent marks that there was no resolv.conf to back up
+func (f File) absent(iface string) string {
+	return f.backup(iface) + ".absent"
+}
+
+func exists(fpath string) bool {
+	_, err := os.Lstat(fpath)
+	return !os.IsNotExist(err)
+}
+
+// Apply backs up resolv.conf to resolv.conf.wg-iface and overwrites it,
+// if there was none resolv.conf.wg-iface.absent records that instead,
+// an existing backup is left untouched so repeated Apply calls don't lose the original
+func (f File) Apply(ctx context.Context, iface string, c Config) error {
+	if !exists(f.backup(iface)) && !exists(f.absent(iface)) {
+		err := f.save(iface)
+		if err != nil {
+			return fmt.Errorf("backup %v: %v", f.path(), err)
+		}
+	}
+	fi, err := os.Lstat(f.path())
+	if err == nil && fi.Mode()&os.ModeSymlink != 0 {
+		// don't write through the link
+		err = os.Remove(f.path())
+		if err != nil {
+			return fmt.Errorf("remove %v: %v", f.path(), err)
+		}
+	}
+	err = ioutil.WriteFile(f.path(), c.Bytes(), 0644)
+	if err != nil {
+		return fmt.Errorf("write %v: %v", f.path(), err)
+	}
+	return nil
+}
+
+// save backs up resolv.conf, a symlink is backed up as a link to the same target
+func (f File) save(iface string) error {
+	fi, err := os.Lstat(f.path())
+	if os.IsNotExist(err) {
+		return ioutil.WriteFile(f.absent(iface), nil, 0644)
+	} else if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(f.path())
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, f.backup(iface))
+	}
+	b, err := ioutil.ReadFile(f.path())
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(f.backup(iface), b, 0644)
+}
+
+// Revert restores resolv.conf from the backup,
+// or removes it if there was none before Apply
+func (f File) Revert(ctx context.Context, iface string) error {
+	if exists(f.absent(iface)) {
+		err := os.Remove(f.path())
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove %v: %v", f.path(), err)
+		}
+		err = os.Remove(f.absent(iface))
+		if err != nil {
+			return fmt.Errorf("remove %v: %v", f.absent(iface), err)
+		}
+		return nil
+	}
+	err := os.Rename(f.backup(iface), f.path())
+	if err != nil {
+		return fmt.Errorf("restore %v: %v", f.path(), err)
+	}
+	return nil
+}
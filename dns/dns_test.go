@@ -0,0 +1,177 @@
+package dns
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	wg "seankhliao.com/go-wg"
+)
+
+var (
+	se = "%v #%v errored: %v"
+	sf = "%v #%v \nexp: >%v< \ngot: >%v<"
+)
+
+// execRecorder records commands run
+type execRecorder struct {
+	cmds  []string
+	stdin []string
+}
+
+func (e *execRecorder) Exec(ctx context.Context, stdin []byte, name string, args ...string) ([]byte, error) {
+	e.cmds = append(e.cmds, strings.Join(append([]string{name}, args...), " "))
+	e.stdin = append(e.stdin, string(stdin))
+	return nil, nil
+}
+
+// Interface -> Config -> bytes
+func TestNewConfig(t *testing.T) {
+	cases := []struct {
+		I wg.Interface
+		C Config
+		B string
+	}{
+		{
+			wg.Interface{},
+			Config{},
+			"",
+		}, {
+			wg.Interface{DNS: []string{"10.0.0.1", "example.com", "fd00::1", "corp.example.com"}},
+			Config{
+				Servers: []string{"10.0.0.1", "fd00::1"},
+				Search:  []string{"example.com", "corp.example.com"},
+			},
+			"nameserver 10.0.0.1\nnameserver fd00::1\nsearch example.com corp.example.com\n",
+		},
+	}
+	for i, c := range cases {
+		conf := NewConfig(c.I)
+		if !reflect.DeepEqual(conf, c.C) {
+			t.Errorf(sf, "NewConfig", i, c.C, conf)
+		}
+		if b := string(conf.Bytes()); b != c.B {
+			t.Errorf(sf, "Config.Bytes", i, c.B, b)
+		}
+	}
+}
+
+func TestAppliers(t *testing.T) {
+	conf := Config{Servers: []string{"10.0.0.1"}, Search: []string{"example.com"}}
+	cases := []struct {
+		A      func(wg.Executor) Applier
+		Apply  []string
+		Revert []string
+	}{
+		{
+			func(e wg.Executor) Applier { return Resolvconf{e} },
+			[]string{"resolvconf -a tun.wg0 -m 0 -x"},
+			[]string{"resolvconf -d tun.wg0 -f"},
+		}, {
+			func(e wg.Executor) Applier { return Resolved{e} },
+			[]string{"resolvectl dns wg0 10.0.0.1", "resolvectl domain wg0 example.com"},
+			[]string{"resolvectl revert wg0"},
+		},
+	}
+	for i, c := range cases {
+		e := &execRecorder{}
+		err := c.A(e).Apply(context.Background(), "wg0", conf)
+		if err != nil {
+			t.Errorf(se, "Apply", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(e.cmds, c.Apply) {
+			t.Errorf(sf, "Apply", i, c.Apply, e.cmds)
+		}
+
+		e = &execRecorder{}
+		err = c.A(e).Revert(context.Background(), "wg0")
+		if err != nil {
+			t.Errorf(se, "Revert", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(e.cmds, c.Revert) {
+			t.Errorf(sf, "Revert", i, c.Revert, e.cmds)
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wg-dns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	orig := "nameserver 192.168.0.1\n"
+	f := File{Path: filepath.Join(dir, "resolv.conf")}
+	err = ioutil.WriteFile(f.Path, []byte(orig), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := Config{Servers: []string{"10.0.0.1"}}
+	for i := 0; i < 2; i++ {
+		err = f.Apply(context.Background(), "wg0", conf)
+		if err != nil {
+			t.Fatalf(se, "File.Apply", i, err)
+		}
+		b, _ := ioutil.ReadFile(f.Path)
+		if string(b) != string(conf.Bytes()) {
+			t.Errorf(sf, "File.Apply", i, string(conf.Bytes()), string(b))
+		}
+	}
+
+	err = f.Revert(context.Background(), "wg0")
+	if err != nil {
+		t.Fatalf(se, "File.Revert", 0, err)
+	}
+	b, _ := ioutil.ReadFile(f.Path)
+	if string(b) != orig {
+		t.Errorf(sf, "File.Revert", 0, orig, string(b))
+	}
+
+	// no resolv.conf before Apply
+	f = File{Path: filepath.Join(dir, "missing.conf")}
+	for i := 0; i < 2; i++ {
+		err = f.Apply(context.Background(), "wg0", conf)
+		if err != nil {
+			t.Fatalf(se, "File.Apply", 2+i, err)
+		}
+	}
+	err = f.Revert(context.Background(), "wg0")
+	if err != nil {
+		t.Fatalf(se, "File.Revert", 1, err)
+	}
+	if names, _ := filepath.Glob(filepath.Join(dir, "missing.conf*")); len(names) != 0 {
+		t.Errorf(sf, "File.Revert", 1, "removed", names)
+	}
+	// resolv.conf linked to a file managed by something else
+	stub := filepath.Join(dir, "stub-resolv.conf")
+	ioutil.WriteFile(stub, []byte(orig), 0644)
+	f = File{Path: filepath.Join(dir, "linked.conf")}
+	os.Symlink(stub, f.Path)
+	for i := 0; i < 2; i++ {
+		err = f.Apply(context.Background(), "wg0", conf)
+		if err != nil {
+			t.Fatalf(se, "File.Apply", 4+i, err)
+		}
+	}
+	if b, _ := ioutil.ReadFile(f.Path); string(b) != string(conf.Bytes()) {
+		t.Errorf(sf, "File.Apply", 4, string(conf.Bytes()), string(b))
+	}
+	if b, _ := ioutil.ReadFile(stub); string(b) != orig {
+		t.Errorf(sf, "File.Apply target", 4, orig, string(b))
+	}
+	err = f.Revert(context.Background(), "wg0")
+	if err != nil {
+		t.Fatalf(se, "File.Revert", 2, err)
+	}
+	if target, err := os.Readlink(f.Path); err != nil || target != stub {
+		t.Errorf(sf, "File.Revert", 2, stub, target)
+	}
+}
@@ -0,0 +1,266 @@
+package wg
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// now is the reference for converting LatestHandshake (seconds ago) to and from timestamps
+var now = time.Now
+
+// jsonInterface is the JSON / YAML form of an Interface
+type jsonInterface struct {
+	ListenPort int      `json:"listen_port,omitempty" yaml:"listen_port,omitempty"`
+	FwMark     string   `json:"fwmark,omitempty" yaml:"fwmark,omitempty"`
+	PrivateKey string   `json:"private_key,omitempty" yaml:"private_key,omitempty"`
+	Address    []string `json:"address,omitempty" yaml:"address,omitempty"`
+	DNS        []string `json:"dns,omitempty" yaml:"dns,omitempty"`
+
+	// Show only
+	PublicKey string `json:"public_key,omitempty" yaml:"public_key,omitempty"`
+}
+
+// jsonPeer is the JSON / YAML form of a Peer
+type jsonPeer struct {
+	PublicKey           string   `json:"public_key,omitempty" yaml:"public_key,omitempty"`
+	PresharedKey        string   `json:"preshared_key,omitempty" yaml:"preshared_key,omitempty"`
+	AllowedIPs          []string `json:"allowed_ips,omitempty" yaml:"allowed_ips,omitempty"`
+	Endpoint            string   `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	PersistentKeepalive int      `json:"persistent_keepalive,omitempty" yaml:"persistent_keepalive,omitempty"`
+
+	// Show only
+	LatestHandshake string `json:"latest_handshake,omitempty" yaml:"latest_handshake,omitempty"` // RFC3339
+	Received        int64  `json:"received,omitempty" yaml:"received,omitempty"`
+	Sent            int64  `json:"sent,omitempty" yaml:"sent,omitempty"`
+}
+
+// jsonConf is the JSON / YAML form of a Conf
+type jsonConf struct {
+	Interface jsonInterface `json:"interface" yaml:"interface"`
+	Peers     []jsonPeer    `json:"peers,omitempty" yaml:"peers,omitempty"`
+}
+
+// checkKey validates a base64 encoded 32 byte key, empty is allowed
+func checkKey(name, key string) error {
+	if key == "" {
+		return nil
+	}
+	b, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return fmt.Errorf("%v: %v", name, err)
+	}
+	if len(b) != 32 {
+		return fmt.Errorf("%v: expected 32 bytes, got %v", name, len(b))
+	}
+	return nil
+}
+
+// checkSecret is checkKey also allowing key references
+func checkSecret(name, key string) error {
+	if _, ok := KeyRef(key); ok {
+		return nil
+	}
+	return checkKey(name, key)
+}
+
+func (i Interface) toJSON() jsonInterface {
+	return jsonInterface{
+		ListenPort: i.ListenPort,
+		FwMark:     i.FwMark,
+		PrivateKey: i.PrivateKey,
+		Address:    i.Address,
+		DNS:        i.DNS,
+		PublicKey:  i.PublicKey,
+	}
+}
+
+func (j jsonInterface) toInterface() (Interface, error) {
+	if err := checkSecret("private_key", j.PrivateKey); err != nil {
+		return Interface{}, err
+	}
+	if err := checkKey("public_key", j.PublicKey); err != nil {
+		return Interface{}, err
+	}
+	return Interface{
+		ListenPort: j.ListenPort,
+		FwMark:     j.FwMark,
+		PrivateKey: j.PrivateKey,
+		Address:    j.Address,
+		DNS:        j.DNS,
+		PublicKey:  j.PublicKey,
+	}, nil
+}
+
+func (p Peer) toJSON() jsonPeer {
+	j := jsonPeer{
+		PublicKey:           p.PublicKey,
+		PresharedKey:        p.PresharedKey,
+		AllowedIPs:          p.AllowedIPs,
+		Endpoint:            p.Endpoint,
+		PersistentKeepalive: p.PersistentKeepalive,
+		Received:            p.Received,
+		Sent:                p.Sent,
+	}
+	if p.LatestHandshake != 0 {
+		j.LatestHandshake = now().Add(-time.Duration(p.LatestHandshake) * time.Second).UTC().Format(time.RFC3339)
+	}
+	return j
+}
+
+func (j jsonPeer) toPeer() (Peer, error) {
+	if err := checkKey("public_key", j.PublicKey); err != nil {
+		return Peer{}, err
+	}
+	if err := checkSecret("preshared_key", j.PresharedKey); err != nil {
+		return Peer{}, err
+	}
+	p := Peer{
+		PublicKey:           j.PublicKey,
+		PresharedKey:        j.PresharedKey,
+		AllowedIPs:          j.AllowedIPs,
+		Endpoint:            j.Endpoint,
+		PersistentKeepalive: j.PersistentKeepalive,
+		Received:            j.Received,
+		Sent:                j.Sent,
+	}
+	if j.LatestHandshake != "" {
+		t, err := time.Parse(time.RFC3339, j.LatestHandshake)
+		if err != nil {
+			return Peer{}, fmt.Errorf("latest_handshake: %v", err)
+		}
+		p.LatestHandshake = int64(now().Sub(t) / time.Second)
+	}
+	return p, nil
+}
+
+func (c Conf) toJSON() jsonConf {
+	j := jsonConf{Interface: c.Interface.toJSON()}
+	for _, p := range c.Peers {
+		j.Peers = append(j.Peers, p.toJSON())
+	}
+	return j
+}
+
+func (j jsonConf) toConf() (Conf, error) {
+	var c Conf
+	var err error
+	c.Interface, err = j.Interface.toInterface()
+	if err != nil {
+		return Conf{}, fmt.Errorf("interface: %v", err)
+	}
+	for i, jp := range j.Peers {
+		p, err := jp.toPeer()
+		if err != nil {
+			return Conf{}, fmt.Errorf("peer %v: %v", i, err)
+		}
+		c.Peers = append(c.Peers, p)
+	}
+	return c, nil
+}
+
+// MarshalJSON encodes an Interface with lowercase keys,
+// empty values are omitted like in Bytes
+func (i Interface) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.toJSON())
+}
+
+// UnmarshalJSON decodes an Interface, validating keys
+func (i *Interface) UnmarshalJSON(b []byte) error {
+	var j jsonInterface
+	err := json.Unmarshal(b, &j)
+	if err != nil {
+		return err
+	}
+	*i, err = j.toInterface()
+	return err
+}
+
+// MarshalJSON encodes a Peer with lowercase keys,
+// empty values are omitted like in Bytes,
+// LatestHandshake is encoded as an RFC3339 timestamp
+func (p Peer) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.toJSON())
+}
+
+// UnmarshalJSON decodes a Peer, validating keys
+func (p *Peer) UnmarshalJSON(b []byte) error {
+	var j jsonPeer
+	err := json.Unmarshal(b, &j)
+	if err != nil {
+		return err
+	}
+	*p, err = j.toPeer()
+	return err
+}
+
+// MarshalJSON encodes a Conf as {"interface": {...}, "peers": [...]}
+func (c Conf) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.toJSON())
+}
+
+// UnmarshalJSON decodes a Conf, validating keys
+func (c *Conf) UnmarshalJSON(b []byte) error {
+	var j jsonConf
+	err := json.Unmarshal(b, &j)
+	if err != nil {
+		return err
+	}
+	*c, err = j.toConf()
+	return err
+}
+
+// MarshalYAML encodes an Interface the same way as MarshalJSON
+// (gopkg.in/yaml.v2 and v3)
+func (i Interface) MarshalYAML() (interface{}, error) {
+	return i.toJSON(), nil
+}
+
+// UnmarshalYAML decodes an Interface the same way as UnmarshalJSON
+// (gopkg.in/yaml.v2 and v3)
+func (i *Interface) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var j jsonInterface
+	err := unmarshal(&j)
+	if err != nil {
+		return err
+	}
+	*i, err = j.toInterface()
+	return err
+}
+
+// MarshalYAML encodes a Peer the same way as MarshalJSON
+// (gopkg.in/yaml.v2 and v3)
+func (p Peer) MarshalYAML() (interface{}, error) {
+	return p.toJSON(), nil
+}
+
+// UnmarshalYAML decodes a Peer the same way as UnmarshalJSON
+// (gopkg.in/yaml.v2 and v3)
+func (p *Peer) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var j jsonPeer
+	err := unmarshal(&j)
+	if err != nil {
+		return err
+	}
+	*p, err = j.toPeer()
+	return err
+}
+
+// MarshalYAML encodes a Conf the same way as MarshalJSON
+// (gopkg.in/yaml.v2 and v3)
+func (c Conf) MarshalYAML() (interface{}, error) {
+	return c.toJSON(), nil
+}
+
+// UnmarshalYAML decodes a Conf the same way as UnmarshalJSON
+// (gopkg.in/yaml.v2 and v3)
+func (c *Conf) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var j jsonConf
+	err := unmarshal(&j)
+	if err != nil {
+		return err
+	}
+	*c, err = j.toConf()
+	return err
+}
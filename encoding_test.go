@@ -0,0 +1,149 @@
+package wg
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	keyA = "AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA="
+	keyB = "B6N8vBQgk8i3VdwbEOhstCY3StFqqFPtC9/AsrhtHHw="
+	keyC = "ICEiIyQlJicoKSorLC0uLzAxMjM0NTY3ODk6Ozw9Pj8="
+)
+
+func TestJSON(t *testing.T) {
+	ref := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	now = func() time.Time { return ref }
+	defer func() { now = time.Now }()
+
+	cases := []struct {
+		C Conf
+		J string
+	}{
+		{
+			Conf{},
+			`{"interface":{}}`,
+		}, {
+			Conf{
+				Interface{
+					ListenPort: 51820,
+					PrivateKey: keyA,
+					DNS:        []string{"10.0.0.1"},
+				},
+				[]Peer{
+					{
+						PublicKey:           keyB,
+						PresharedKey:        keyC,
+						AllowedIPs:          []string{"10.0.0.2/32"},
+						Endpoint:            "1.2.3.4:51820",
+						PersistentKeepalive: 25,
+						LatestHandshake:     65,
+						Received:            100,
+						Sent:                200,
+					},
+				},
+			},
+			`{"interface":{"listen_port":51820,"private_key":"` + keyA + `","dns":["10.0.0.1"]},` +
+				`"peers":[{"public_key":"` + keyB + `","preshared_key":"` + keyC + `","allowed_ips":["10.0.0.2/32"],"endpoint":"1.2.3.4:51820",` +
+				`"persistent_keepalive":25,"latest_handshake":"2020-01-02T03:03:00Z","received":100,"sent":200}]}`,
+		}, {
+			Conf{
+				Interface{PrivateKey: "keystore:wg0"},
+				[]Peer{{PublicKey: keyB, PresharedKey: "keystore:wg0-b"}},
+			},
+			`{"interface":{"private_key":"keystore:wg0"},"peers":[{"public_key":"` + keyB + `","preshared_key":"keystore:wg0-b"}]}`,
+		},
+	}
+	for i, c := range cases {
+		b, err := json.Marshal(c.C)
+		if err != nil {
+			t.Errorf(se, "MarshalJSON", i, err)
+			continue
+		}
+		if string(b) != c.J {
+			t.Errorf(sf, "MarshalJSON", i, c.J, string(b))
+		}
+		var conf Conf
+		err = json.Unmarshal(b, &conf)
+		if err != nil {
+			t.Errorf(se, "UnmarshalJSON", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(conf, c.C) {
+			t.Errorf(sf, "UnmarshalJSON", i, c.C, conf)
+		}
+	}
+}
+
+func TestJSONInvalidKey(t *testing.T) {
+	cases := []string{
+		`{"interface":{"private_key":"not_a_key"}}`,
+		`{"interface":{},"peers":[{"public_key":"AQID"}]}`,
+		`{"interface":{},"peers":[{"public_key":"keystore:peer"}]}`,
+		`{"interface":{},"peers":[{"public_key":"` + keyB + `","latest_handshake":"yesterday"}]}`,
+	}
+	for i, c := range cases {
+		var conf Conf
+		if err := json.Unmarshal([]byte(c), &conf); err == nil {
+			t.Errorf(se, "UnmarshalJSON", i, "expected error")
+		}
+	}
+}
+
+// Conf -> JSON / YAML -> Conf -> INI
+func TestRoundTrip(t *testing.T) {
+	ini := []byte(`[Interface]
+ListenPort = 51820
+FwMark = 0xca6c
+PrivateKey = ` + keyA + `
+Address = 10.0.0.1/24, fd00::1/64
+DNS = 10.0.0.1, example.com
+
+[Peer]
+PublicKey = ` + keyB + `
+PresharedKey = ` + keyC + `
+AllowedIPs = 10.0.0.2/32, fd00::2/128
+Endpoint = vpn.example.com:51820
+PersistentKeepalive = 25
+
+[Peer]
+PublicKey = ` + keyC + `
+AllowedIPs = 10.0.0.3/32
+
+`)
+	c, err := NewConfBytes(ini)
+	if err != nil {
+		t.Fatalf(se, "NewConfBytes", 0, err)
+	}
+	codecs := []struct {
+		Name      string
+		Marshal   func(interface{}) ([]byte, error)
+		Unmarshal func([]byte, interface{}) error
+	}{
+		{"json", json.Marshal, json.Unmarshal},
+		{"yaml", yaml.Marshal, yaml.Unmarshal},
+	}
+	for i, codec := range codecs {
+		b, err := codec.Marshal(c)
+		if err != nil {
+			t.Errorf(se, codec.Name+" marshal", i, err)
+			continue
+		}
+		var conf Conf
+		err = codec.Unmarshal(b, &conf)
+		if err != nil {
+			t.Errorf(se, codec.Name+" unmarshal", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(conf, c) {
+			t.Errorf(sf, codec.Name, i, c, conf)
+		}
+		if out := conf.Bytes(); string(out) != string(ini) {
+			t.Errorf(sf, codec.Name+" ini", i, string(ini), string(out))
+		}
+	}
+}
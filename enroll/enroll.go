@@ -0,0 +1,231 @@
+// Package enroll lets devices add themselves as peers with one time tokens:
+// an admin issues a token scoped to an interface and address pool,
+// the device posts the token and its public key (the private key never leaves the device),
+// the server allocates addresses, adds the peer and returns its own peer info
+package enroll
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+
+	wg "seankhliao.com/go-wg"
+	"seankhliao.com/go-wg/ipam"
+	"seankhliao.com/go-wg/provision"
+	"seankhliao.com/go-wg/registry"
+)
+
+// Interface is the server side of an interface enrolled devices connect to
+type Interface struct {
+	Endpoint   string   // host:port devices connect to
+	Reserve    []string // ip addresses in token pools not to hand out, eg the server's own
+	DNS        []string // device DNS servers and search domains
+	AllowedIPs []string // extra routes for devices, the token pool is always routed
+}
+
+// Request is the body of an enrollment
+type Request struct {
+	Token     string `json:"token"`
+	Interface string `json:"interface"`
+	PublicKey string `json:"public_key"`
+	Name      string `json:"name,omitempty"` // for the registry, must not be in use, defaults to enroll-<token id>
+}
+
+// Response is the result of an enrollment,
+// the device combines it with its private key into a config
+type Response struct {
+	Address []string `json:"address"`
+	DNS     []string `json:"dns,omitempty"`
+	Peer    wg.Peer  `json:"peer"` // the server
+}
+
+// Conf is the device config for privateKey
+func (r Response) Conf(privateKey string) wg.Conf {
+	return wg.Conf{
+		Interface: wg.Interface{
+			PrivateKey: privateKey,
+			Address:    r.Address,
+			DNS:        r.DNS,
+		},
+		Peers: []wg.Peer{r.Peer},
+	}
+}
+
+// Handler serves enrollments on POST
+type Handler struct {
+	Store      *Store
+	Client     wg.Client
+	Interfaces map[string]Interface
+	// Registry records enrolled peers if set,
+	// the interface must exist in the registry
+	Registry *registry.Registry
+}
+
+type errHTTP struct {
+	code int
+	err  error
+}
+
+func (e errHTTP) Error() string {
+	return e.err.Error()
+}
+
+// ServeHTTP handles an enrollment
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	var req Request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "decode request: " + err.Error()})
+		return
+	}
+	res, err := h.Enroll(r.Context(), req)
+	if err != nil {
+		code := http.StatusInternalServerError
+		var he errHTTP
+		switch {
+		case errors.Is(err, ErrInvalidToken):
+			code = http.StatusForbidden
+		case errors.Is(err, ipam.ErrExhausted):
+			code = http.StatusConflict
+		case errors.As(err, &he):
+			code = he.code
+		}
+		writeJSON(w, code, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Enroll redeems the token in req and adds the device as a peer
+func (h *Handler) Enroll(ctx context.Context, req Request) (Response, error) {
+	var res Response
+	iface, ok := h.Interfaces[req.Interface]
+	if !ok {
+		return res, errHTTP{http.StatusNotFound, fmt.Errorf("enroll: no interface %v", req.Interface)}
+	}
+	if b, err := base64.StdEncoding.DecodeString(req.PublicKey); err != nil || len(b) != 32 {
+		return res, errHTTP{http.StatusBadRequest, fmt.Errorf("enroll: invalid public_key")}
+	}
+
+	err := h.Store.Redeem(req.Token, req.Interface, req.PublicKey, func(t Token) ([]string, error) {
+		conf, err := h.Client.Show(ctx, req.Interface)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range conf.Peers {
+			if p.PublicKey == req.PublicKey {
+				return nil, errHTTP{http.StatusConflict, fmt.Errorf("public key already enrolled")}
+			}
+		}
+		pool, err := ipam.New(t.Pool...)
+		if err != nil {
+			return nil, err
+		}
+		err = pool.Reserve(inPool(t.Pool, iface.Reserve)...)
+		if err == nil {
+			err = pool.Load(conf)
+		}
+		if err != nil {
+			return nil, err
+		}
+		addrs, err := pool.Allocate()
+		if err != nil {
+			return nil, err
+		}
+		name := req.Name
+		if name == "" {
+			name = "enroll-" + t.ID
+		}
+		if h.Registry != nil {
+			// Put would replace another device's record
+			_, err = h.Registry.Get(req.Interface, name)
+			if err == nil {
+				return nil, errHTTP{http.StatusConflict, fmt.Errorf("name %v already in use", name)}
+			} else if !errors.Is(err, registry.ErrNotFound) {
+				return nil, err
+			}
+		}
+
+		err = h.Client.Set(ctx, wg.Opt{
+			Interface: req.Interface,
+			Peers:     []wg.OptPeer{{PublicKey: req.PublicKey, AllowedIPs: addrs}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if h.Registry != nil {
+			err = h.Registry.Put(registry.Peer{
+				Name:      name,
+				Interface: req.Interface,
+				Owner:     t.Note,
+				Tags:      []string{"enrolled"},
+				PublicKey: req.PublicKey,
+				Address:   addrs,
+			})
+			if err != nil {
+				// undo so the token can be retried
+				rerr := h.Client.Set(ctx, wg.Opt{
+					Interface: req.Interface,
+					Peers:     []wg.OptPeer{{PublicKey: req.PublicKey, Remove: true}},
+				})
+				if rerr != nil {
+					return nil, fmt.Errorf("%v, remove peer: %v", err, rerr)
+				}
+				return nil, err
+			}
+		}
+
+		res = Response{
+			Address: addrs,
+			DNS:     iface.DNS,
+			Peer: wg.Peer{
+				PublicKey:           conf.PublicKey,
+				AllowedIPs:          append(append([]string{}, t.Pool...), iface.AllowedIPs...),
+				Endpoint:            iface.Endpoint,
+				PersistentKeepalive: provision.DefaultKeepalive,
+			},
+		}
+		return addrs, nil
+	})
+	if err != nil {
+		return Response{}, fmt.Errorf("enroll: %w", err)
+	}
+	return res, nil
+}
+
+// inPool filters addrs to those in pool,
+// as a pool may be only part of the interface's network
+func inPool(pool, addrs []string) []string {
+	var out []string
+	for _, a := range addrs {
+		addr, err := netip.ParseAddr(a)
+		if err != nil {
+			// let Reserve report it
+			out = append(out, a)
+			continue
+		}
+		for _, p := range pool {
+			prefix, err := netip.ParsePrefix(p)
+			if err == nil && prefix.Contains(addr) {
+				out = append(out, a)
+				break
+			}
+		}
+	}
+	return out
+}
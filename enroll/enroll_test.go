@@ -0,0 +1,135 @@
+package enroll
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	wg "seankhliao.com/go-wg"
+	"seankhliao.com/go-wg/registry"
+	"seankhliao.com/go-wg/wgtest"
+)
+
+var (
+	privA = "AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA="
+	pubA  = "B6N8vBQgk8i3VdwbEOhstCY3StFqqFPtC9/AsrhtHHw="
+	keyC  = "ICEiIyQlJicoKSorLC0uLzAxMjM0NTY3ODk6Ozw9Pj8="
+	keyD  = "QEFCQ0RFRkdISUpLTE1OT1BRUlNUVVZXWFlaW1xdXl8="
+	keyE  = "YGFiY2RlZmdoaWprbG1ub3BxcnN0dXZ3eHl6e3x9fn8="
+)
+
+func TestHandler(t *testing.T) {
+	fake := wgtest.NewClient(map[string]wg.Conf{
+		"wg0": {
+			Interface: wg.Interface{ListenPort: 51820, PrivateKey: privA, PublicKey: pubA},
+			Peers:     []wg.Peer{{PublicKey: keyC, AllowedIPs: []string{"10.0.0.2/32"}}},
+		},
+	})
+	store, _ := Open(t.TempDir())
+	reg, _ := registry.Open(t.TempDir())
+	h := &Handler{
+		Store:  store,
+		Client: fake,
+		Interfaces: map[string]Interface{
+			"wg0": {Endpoint: "vpn.example.com:51820", Reserve: []string{"10.0.0.1"}, DNS: []string{"10.0.0.1"}},
+		},
+		Registry: reg,
+	}
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	secret, tok, _ := store.Issue("wg0", []string{"10.0.0.0/24"}, time.Hour, "alice")
+	full, _, _ := store.Issue("wg0", []string{"10.0.0.2/32"}, time.Hour, "")
+
+	post := func(req Request) (int, string) {
+		b, _ := json.Marshal(req)
+		res, err := ts.Client().Post(ts.URL, "application/json", strings.NewReader(string(b)))
+		if err != nil {
+			t.Fatalf(se, "post", 0, err)
+		}
+		defer res.Body.Close()
+		body, _ := ioutil.ReadAll(res.Body)
+		return res.StatusCode, string(body)
+	}
+
+	cases := []struct {
+		Req  Request
+		Code int
+	}{
+		{Request{Token: secret, Interface: "wg1", PublicKey: keyD}, http.StatusNotFound},
+		{Request{Token: secret, Interface: "wg0", PublicKey: "invalid"}, http.StatusBadRequest},
+		{Request{Token: "nope", Interface: "wg0", PublicKey: keyD}, http.StatusForbidden},
+		{Request{Token: secret, Interface: "wg0", PublicKey: keyC}, http.StatusConflict},
+		{Request{Token: full, Interface: "wg0", PublicKey: keyD}, http.StatusConflict},
+		// registry has no interface wg0 yet, the peer is removed again
+		{Request{Token: secret, Interface: "wg0", PublicKey: keyD}, http.StatusInternalServerError},
+	}
+	for i, c := range cases {
+		code, body := post(c.Req)
+		if code != c.Code {
+			t.Errorf(sf, "enroll", i, c.Code, code)
+			t.Log(body)
+		}
+	}
+	if conf, _ := fake.Show(context.Background(), "wg0"); len(conf.Peers) != 1 {
+		t.Errorf(sf, "peers", 0, 1, len(conf.Peers))
+	}
+
+	reg.PutInterface("wg0", wg.Interface{Address: []string{"10.0.0.1/24"}})
+	code, body := post(Request{Token: secret, Interface: "wg0", PublicKey: keyD, Name: "laptop"})
+	if code != http.StatusOK {
+		t.Fatalf(sf, "enroll", 0, http.StatusOK, code)
+	}
+	var res Response
+	json.Unmarshal([]byte(body), &res)
+	exp := Response{
+		Address: []string{"10.0.0.3/32"},
+		DNS:     []string{"10.0.0.1"},
+		Peer: wg.Peer{
+			PublicKey:           pubA,
+			AllowedIPs:          []string{"10.0.0.0/24"},
+			Endpoint:            "vpn.example.com:51820",
+			PersistentKeepalive: 25,
+		},
+	}
+	if !reflect.DeepEqual(res, exp) {
+		t.Errorf(sf, "response", 0, exp, res)
+	}
+	conf, _ := fake.Show(context.Background(), "wg0")
+	if p := conf.Peers[len(conf.Peers)-1]; p.PublicKey != keyD || !reflect.DeepEqual(p.AllowedIPs, exp.Address) {
+		t.Errorf(sf, "peer", 0, exp.Address, p)
+	}
+	p, err := reg.Get("wg0", "laptop")
+	if err != nil || p.Owner != "alice" || p.PublicKey != keyD {
+		t.Errorf(sf, "registry", 0, keyD, p)
+	}
+
+	if code, _ := post(Request{Token: secret, Interface: "wg0", PublicKey: keyD}); code != http.StatusForbidden {
+		t.Errorf(sf, "reuse", 0, http.StatusForbidden, code)
+	}
+	tokens, _ := store.Tokens()
+	if tokens[0].ID != tok.ID || tokens[0].PublicKey != keyD || !tokens[1].Used.IsZero() {
+		t.Errorf(sf, "tokens", 0, keyD, tokens)
+	}
+	if got := res.Conf(keyC).Interface.PrivateKey; got != keyC {
+		t.Errorf(sf, "Conf", 0, keyC, got)
+	}
+
+	// another device can't take over a registry record by name
+	other, _, _ := store.Issue("wg0", []string{"10.0.0.0/24"}, time.Hour, "mallory")
+	if code, _ := post(Request{Token: other, Interface: "wg0", PublicKey: keyE, Name: "laptop"}); code != http.StatusConflict {
+		t.Errorf(sf, "name in use", 0, http.StatusConflict, code)
+	}
+	if p, _ := reg.Get("wg0", "laptop"); p.PublicKey != keyD || p.Owner != "alice" {
+		t.Errorf(sf, "name in use registry", 0, keyD, p)
+	}
+	if conf, _ := fake.Show(context.Background(), "wg0"); len(conf.Peers) != 2 {
+		t.Errorf(sf, "name in use peers", 0, 2, len(conf.Peers))
+	}
+}
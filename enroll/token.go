@@ -0,0 +1,297 @@
+package enroll
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"seankhliao.com/go-wg/internal/atomicfile"
+)
+
+// ErrInvalidToken is returned for unknown, used, revoked and expired tokens
+var ErrInvalidToken = errors.New("invalid token")
+
+var now = time.Now
+
+// Token is a one time enrollment token,
+// only a hash of the secret is stored
+type Token struct {
+	ID        string    `json:"id"`
+	Hash      string    `json:"hash"` // hex sha256 of the secret
+	Interface string    `json:"interface"`
+	Pool      []string  `json:"pool"` // ip/mask prefixes to allocate from
+	Note      string    `json:"note,omitempty"`
+	Created   time.Time `json:"created"`
+	Expires   time.Time `json:"expires"`
+
+	Used      time.Time `json:"used,omitzero"`
+	PublicKey string    `json:"public_key,omitempty"` // of the enrolled peer
+	Revoked   bool      `json:"revoked,omitempty"`
+}
+
+// Valid reports whether t can still be redeemed at time at
+func (t Token) Valid(at time.Time) bool {
+	return t.Used.IsZero() && !t.Revoked && at.Before(t.Expires)
+}
+
+// EventType is the kind of audit event
+type EventType string
+
+// Audit event types
+const (
+	Issued   EventType = "issued"
+	Redeemed EventType = "redeemed"
+	Rejected EventType = "rejected"
+	Revoked  EventType = "revoked"
+)
+
+// Event is an audit log entry
+type Event struct {
+	Time      time.Time `json:"time"`
+	Type      EventType `json:"type"`
+	TokenID   string    `json:"token_id,omitempty"`
+	Interface string    `json:"interface,omitempty"`
+	PublicKey string    `json:"public_key,omitempty"`
+	Address   []string  `json:"address,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// Store keeps tokens and an append only audit log in a local directory
+//
+//	dir/tokens.json
+//	dir/audit.log (JSON lines)
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// Open uses dir as a token store, creating it if needed
+func Open(dir string) (*Store, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, fmt.Errorf("open store: %v", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Store) load() ([]Token, error) {
+	b, err := ioutil.ReadFile(filepath.Join(s.dir, "tokens.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var tokens []Token
+	err = json.Unmarshal(b, &tokens)
+	return tokens, err
+}
+
+func (s *Store) save(tokens []Token) error {
+	b, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(filepath.Join(s.dir, "tokens.json"), b)
+}
+
+// audit appends an event to the audit log
+func (s *Store) audit(ev Event) error {
+	ev.Time = now().UTC()
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(s.dir, "audit.log"), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(b, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Issue creates a token for iface allocating from pool, valid for ttl,
+// returns the secret to hand to the device, it is not stored
+func (s *Store) Issue(iface string, pool []string, ttl time.Duration, note string) (secret string, t Token, err error) {
+	if iface == "" || len(pool) == 0 || ttl <= 0 {
+		return "", t, fmt.Errorf("issue: need interface, pool and ttl")
+	}
+	b := make([]byte, 36)
+	_, err = rand.Read(b)
+	if err != nil {
+		return "", t, fmt.Errorf("issue: %v", err)
+	}
+	secret = base64.RawURLEncoding.EncodeToString(b[4:])
+	created := now().UTC()
+	t = Token{
+		ID:        hex.EncodeToString(b[:4]),
+		Hash:      hash(secret),
+		Interface: iface,
+		Pool:      pool,
+		Note:      note,
+		Created:   created,
+		Expires:   created.Add(ttl),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens, err := s.load()
+	if err != nil {
+		return "", t, fmt.Errorf("issue: %v", err)
+	}
+	err = s.save(append(tokens, t))
+	if err != nil {
+		return "", t, fmt.Errorf("issue: %v", err)
+	}
+	err = s.audit(Event{Type: Issued, TokenID: t.ID, Interface: iface, Reason: note})
+	if err != nil {
+		return "", t, fmt.Errorf("issue: audit: %v", err)
+	}
+	return secret, t, nil
+}
+
+// Redeem checks secret is a valid token for iface and calls f with it,
+// f returns the addresses allocated for the audit log.
+// The token is saved as used by pub before calling f
+// so a peer added by f can't outlive a failed save and leave the token reusable,
+// it is marked unused again if f fails
+func (s *Store) Redeem(secret, iface, pub string, f func(Token) ([]string, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens, err := s.load()
+	if err != nil {
+		return fmt.Errorf("redeem: %v", err)
+	}
+	h := hash(secret)
+	idx := -1
+	for i, t := range tokens {
+		if t.Hash == h {
+			idx = i
+		}
+	}
+	at := now()
+	if idx == -1 || tokens[idx].Interface != iface || !tokens[idx].Valid(at) {
+		ev := Event{Type: Rejected, Interface: iface, PublicKey: pub, Reason: "unknown token"}
+		if idx != -1 {
+			ev.TokenID = tokens[idx].ID
+			switch t := tokens[idx]; {
+			case t.Interface != iface:
+				ev.Reason = "wrong interface"
+			case !t.Used.IsZero():
+				ev.Reason = "already used"
+			case t.Revoked:
+				ev.Reason = "revoked"
+			default:
+				ev.Reason = "expired"
+			}
+		}
+		if err := s.audit(ev); err != nil {
+			return fmt.Errorf("redeem: audit: %v", err)
+		}
+		return fmt.Errorf("redeem: %w", ErrInvalidToken)
+	}
+
+	t := tokens[idx]
+	tokens[idx].Used = at.UTC()
+	tokens[idx].PublicKey = pub
+	err = s.save(tokens)
+	if err != nil {
+		return fmt.Errorf("redeem: %v", err)
+	}
+	addrs, err := f(t)
+	if err != nil {
+		tokens[idx] = t
+		if serr := s.save(tokens); serr != nil {
+			return fmt.Errorf("redeem: %w, token left used: %v", err, serr)
+		}
+		if aerr := s.audit(Event{Type: Rejected, TokenID: t.ID, Interface: iface, PublicKey: pub, Reason: err.Error()}); aerr != nil {
+			return fmt.Errorf("redeem: audit: %v", aerr)
+		}
+		return fmt.Errorf("redeem: %w", err)
+	}
+	err = s.audit(Event{Type: Redeemed, TokenID: t.ID, Interface: iface, PublicKey: pub, Address: addrs})
+	if err != nil {
+		return fmt.Errorf("redeem: audit: %v", err)
+	}
+	return nil
+}
+
+// Revoke invalidates a token by ID
+func (s *Store) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens, err := s.load()
+	if err != nil {
+		return fmt.Errorf("revoke: %v", err)
+	}
+	for i, t := range tokens {
+		if t.ID == id {
+			tokens[i].Revoked = true
+			err = s.save(tokens)
+			if err != nil {
+				return fmt.Errorf("revoke: %v", err)
+			}
+			err = s.audit(Event{Type: Revoked, TokenID: id, Interface: t.Interface})
+			if err != nil {
+				return fmt.Errorf("revoke: audit: %v", err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("revoke %v: %w", id, ErrInvalidToken)
+}
+
+// Tokens lists all tokens sorted by creation time
+func (s *Store) Tokens() ([]Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens, err := s.load()
+	if err != nil {
+		return nil, fmt.Errorf("list tokens: %v", err)
+	}
+	sort.SliceStable(tokens, func(i, j int) bool { return tokens[i].Created.Before(tokens[j].Created) })
+	return tokens, nil
+}
+
+// Audit reads the audit log
+func (s *Store) Audit() ([]Event, error) {
+	f, err := os.Open(filepath.Join(s.dir, "audit.log"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("audit: %v", err)
+	}
+	defer f.Close()
+	var events []Event
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var ev Event
+		err = json.Unmarshal(sc.Bytes(), &ev)
+		if err != nil {
+			return nil, fmt.Errorf("audit: %v", err)
+		}
+		events = append(events, ev)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("audit: %v", err)
+	}
+	return events, nil
+}
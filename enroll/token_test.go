@@ -0,0 +1,124 @@
+package enroll
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var (
+	se = "%v #%v errored: %v"
+	sf = "%v #%v \nexp: >%v< \ngot: >%v<"
+)
+
+func TestStore(t *testing.T) {
+	t0 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	now = func() time.Time { return t0 }
+	defer func() { now = time.Now }()
+
+	dir := t.TempDir()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatalf(se, "Open", 0, err)
+	}
+	_, _, err = s.Issue("wg0", nil, time.Hour, "")
+	if err == nil {
+		t.Errorf(se, "Issue", 0, "expected error for missing pool")
+	}
+
+	pool := []string{"10.0.0.0/24"}
+	secretA, tokA, err := s.Issue("wg0", pool, time.Hour, "alice")
+	if err != nil {
+		t.Fatalf(se, "Issue", 1, err)
+	}
+	secretB, tokB, _ := s.Issue("wg0", pool, time.Minute, "bob")
+	secretC, tokC, _ := s.Issue("wg0", pool, time.Hour, "carol")
+	if fi, err := os.Stat(filepath.Join(dir, "tokens.json")); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf(sf, "mode", 0, os.FileMode(0600), fi)
+	}
+	if tokA.Hash == secretA || len(secretA) != 43 {
+		t.Errorf(sf, "secret", 0, 43, secretA)
+	}
+
+	errFail := errors.New("fail")
+	ok := func(Token) ([]string, error) { return []string{"10.0.0.2/32"}, nil }
+	fail := func(Token) ([]string, error) { return nil, errFail }
+	// the token is already saved as used when the peer is added
+	saved := func(t Token) ([]string, error) {
+		tokens, _ := s.load()
+		for _, st := range tokens {
+			if st.ID == t.ID && (st.Used.IsZero() || st.PublicKey != "pub") {
+				return nil, errors.New("token not saved as used")
+			}
+		}
+		return []string{"10.0.0.2/32"}, nil
+	}
+	err = s.Revoke(tokC.ID)
+	if err != nil {
+		t.Errorf(se, "Revoke", 0, err)
+	}
+
+	cases := []struct {
+		Secret, Iface string
+		At            time.Duration
+		F             func(Token) ([]string, error)
+		Err           error
+	}{
+		{"nope", "wg0", 0, ok, ErrInvalidToken},
+		{secretA, "wg1", 0, ok, ErrInvalidToken},
+		{secretA, "wg0", 0, fail, errFail},
+		{secretA, "wg0", 0, saved, nil},
+		{secretA, "wg0", 0, ok, ErrInvalidToken},
+		{secretB, "wg0", 2 * time.Minute, ok, ErrInvalidToken},
+		{secretC, "wg0", 0, ok, ErrInvalidToken},
+	}
+	for i, c := range cases {
+		now = func() time.Time { return t0.Add(c.At) }
+		err = s.Redeem(c.Secret, c.Iface, "pub", c.F)
+		if !errors.Is(err, c.Err) || (c.Err == nil) != (err == nil) {
+			t.Errorf(sf, "Redeem", i, c.Err, err)
+		}
+	}
+
+	tokens, err := s.Tokens()
+	if err != nil {
+		t.Fatalf(se, "Tokens", 0, err)
+	}
+	tokA.Used, tokA.PublicKey = t0, "pub"
+	tokC.Revoked = true
+	exp := []Token{tokA, tokB, tokC}
+	if !reflect.DeepEqual(tokens, exp) {
+		t.Errorf(sf, "Tokens", 0, exp, tokens)
+	}
+
+	events, err := s.Audit()
+	if err != nil {
+		t.Fatalf(se, "Audit", 0, err)
+	}
+	var got []string
+	for _, ev := range events {
+		got = append(got, string(ev.Type)+" "+ev.Reason)
+	}
+	expEvents := []string{
+		"issued alice", "issued bob", "issued carol", "revoked ",
+		"rejected unknown token",
+		"rejected wrong interface",
+		"rejected fail",
+		"redeemed ",
+		"rejected already used",
+		"rejected expired",
+		"rejected revoked",
+	}
+	if !reflect.DeepEqual(got, expEvents) {
+		t.Errorf(sf, "Audit", 0, expEvents, got)
+	}
+	if ev := events[7]; ev.TokenID != tokA.ID || !reflect.DeepEqual(ev.Address, []string{"10.0.0.2/32"}) {
+		t.Errorf(sf, "Audit", 7, tokA.ID, ev)
+	}
+	if err := s.Revoke("missing"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf(sf, "Revoke", 1, ErrInvalidToken, err)
+	}
+}
@@ -0,0 +1,37 @@
+package wg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// Executor runs external commands (nft, iptables, resolvectl, ...)
+// swap it out in tests
+type Executor interface {
+	// Exec runs name with args, feeding stdin if not nil,
+	// and returns stdout
+	Exec(ctx context.Context, stdin []byte, name string, args ...string) ([]byte, error)
+}
+
+// ExecCmd is an Executor using os/exec
+type ExecCmd struct{}
+
+// Exec runs a command through exec.CommandContext
+func (ExecCmd) Exec(ctx context.Context, stdin []byte, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	b, err := cmd.Output()
+	if err != nil {
+		if msg := bytes.TrimSpace(stderr.Bytes()); len(msg) != 0 {
+			err = fmt.Errorf("%v: %s", err, msg)
+		}
+		return b, err
+	}
+	return b, nil
+}
@@ -0,0 +1,243 @@
+// Package firewall derives firewall rules for a wireguard interface from its Conf
+//
+// Generated rules:
+//   - accept udp on the interface ListenPort
+//   - accept forwarded traffic from the tunnel only if its source is in the sending peer's AllowedIPs
+//   - masquerade tunnel traffic leaving through an outbound interface
+package firewall
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	wg "seankhliao.com/go-wg"
+)
+
+// Backend selects the firewall tooling used by Apply and Remove
+type Backend int
+
+const (
+	// Nftables uses nft with a dedicated inet table
+	Nftables Backend = iota
+	// Iptables uses iptables-restore / ip6tables-restore with dedicated chains
+	Iptables
+)
+
+// Firewall generates and applies rules for a single wireguard interface
+// only Interface is mandatory
+type Firewall struct {
+	Interface string // wireguard interface name
+	Outbound  string // masquerade tunnel traffic out of this interface, empty to disable
+	Backend   Backend
+
+	// Exec runs nft / iptables, defaults to wg.ExecCmd
+	Exec wg.Executor
+}
+
+// Table is the name of the nftables table
+// wg_iface
+func (f Firewall) Table() string {
+	return "wg_" + strings.Replace(f.Interface, "-", "_", -1)
+}
+
+// chain is the name of an iptables chain
+// WG-iface-suffix
+func (f Firewall) chain(suffix string) string {
+	return "WG-" + f.Interface + "-" + suffix
+}
+
+// peerPrefixes splits a peer's AllowedIPs by address family
+func peerPrefixes(p wg.Peer) (v4, v6 []string, err error) {
+	for _, ip := range p.AllowedIPs {
+		prefix, err := netip.ParsePrefix(ip)
+		if err != nil {
+			return nil, nil, fmt.Errorf("peer %v: %v", p.PublicKey, err)
+		}
+		if prefix.Addr().Is4() {
+			v4 = append(v4, prefix.Masked().String())
+		} else {
+			v6 = append(v6, prefix.Masked().String())
+		}
+	}
+	return v4, v6, nil
+}
+
+// Nftables encodes the rules as an nftables ruleset
+// suitable for nft -f
+func (f Firewall) Nftables(c wg.Conf) ([]byte, error) {
+	buf := bytes.NewBufferString("table inet " + f.Table() + " {\n")
+
+	buf.WriteString("\tchain input {\n")
+	buf.WriteString("\t\ttype filter hook input priority filter; policy accept;\n")
+	if c.ListenPort != 0 {
+		buf.WriteString("\t\tudp dport " + strconv.Itoa(c.ListenPort) + " accept\n")
+	}
+	buf.WriteString("\t}\n")
+
+	buf.WriteString("\tchain forward {\n")
+	buf.WriteString("\t\ttype filter hook forward priority filter; policy accept;\n")
+	for _, p := range c.Peers {
+		v4, v6, err := peerPrefixes(p)
+		if err != nil {
+			return nil, err
+		}
+		if len(v4) != 0 {
+			buf.WriteString("\t\tiifname \"" + f.Interface + "\" ip saddr { " + strings.Join(v4, ", ") + " } accept comment \"" + p.PublicKey + "\"\n")
+		}
+		if len(v6) != 0 {
+			buf.WriteString("\t\tiifname \"" + f.Interface + "\" ip6 saddr { " + strings.Join(v6, ", ") + " } accept comment \"" + p.PublicKey + "\"\n")
+		}
+	}
+	buf.WriteString("\t\tiifname \"" + f.Interface + "\" drop\n")
+	buf.WriteString("\t}\n")
+
+	if f.Outbound != "" {
+		buf.WriteString("\tchain postrouting {\n")
+		buf.WriteString("\t\ttype nat hook postrouting priority srcnat; policy accept;\n")
+		buf.WriteString("\t\tiifname \"" + f.Interface + "\" oifname \"" + f.Outbound + "\" masquerade\n")
+		buf.WriteString("\t}\n")
+	}
+
+	buf.WriteString("}\n")
+	return buf.Bytes(), nil
+}
+
+// Iptables encodes the rules for iptables-restore (ipv4)
+func (f Firewall) Iptables(c wg.Conf) ([]byte, error) {
+	return f.iptables(c, false)
+}
+
+// Ip6tables encodes the rules for ip6tables-restore (ipv6)
+func (f Firewall) Ip6tables(c wg.Conf) ([]byte, error) {
+	return f.iptables(c, true)
+}
+
+func (f Firewall) iptables(c wg.Conf, ipv6 bool) ([]byte, error) {
+	in, fwd, nat := f.chain("IN"), f.chain("FWD"), f.chain("NAT")
+
+	buf := bytes.NewBufferString("*filter\n")
+	buf.WriteString(":" + in + " - [0:0]\n")
+	buf.WriteString(":" + fwd + " - [0:0]\n")
+	buf.WriteString("-A INPUT -j " + in + "\n")
+	buf.WriteString("-A FORWARD -j " + fwd + "\n")
+	if c.ListenPort != 0 {
+		buf.WriteString("-A " + in + " -p udp --dport " + strconv.Itoa(c.ListenPort) + " -j ACCEPT\n")
+	}
+	for _, p := range c.Peers {
+		v4, v6, err := peerPrefixes(p)
+		if err != nil {
+			return nil, err
+		}
+		prefixes := v4
+		if ipv6 {
+			prefixes = v6
+		}
+		for _, prefix := range prefixes {
+			buf.WriteString("-A " + fwd + " -i " + f.Interface + " -s " + prefix + " -m comment --comment \"" + p.PublicKey + "\" -j ACCEPT\n")
+		}
+	}
+	buf.WriteString("-A " + fwd + " -i " + f.Interface + " -j DROP\n")
+	buf.WriteString("COMMIT\n")
+
+	if f.Outbound != "" {
+		buf.WriteString("*nat\n")
+		buf.WriteString(":" + nat + " - [0:0]\n")
+		buf.WriteString("-A POSTROUTING -j " + nat + "\n")
+		buf.WriteString("-A " + nat + " -i " + f.Interface + " -o " + f.Outbound + " -j MASQUERADE\n")
+		buf.WriteString("COMMIT\n")
+	}
+	return buf.Bytes(), nil
+}
+
+func (f Firewall) exec() wg.Executor {
+	if f.Exec == nil {
+		return wg.ExecCmd{}
+	}
+	return f.Exec
+}
+
+// Apply installs the rules for c,
+// call Remove first if rules are already installed
+// nft -f - / iptables-restore --noflush
+func (f Firewall) Apply(ctx context.Context, c wg.Conf) error {
+	switch f.Backend {
+	case Nftables:
+		b, err := f.Nftables(c)
+		if err != nil {
+			return fmt.Errorf("apply: %v", err)
+		}
+		_, err = f.exec().Exec(ctx, b, "nft", "-f", "-")
+		if err != nil {
+			return fmt.Errorf("apply nft: %v", err)
+		}
+	case Iptables:
+		b, err := f.Iptables(c)
+		if err != nil {
+			return fmt.Errorf("apply: %v", err)
+		}
+		_, err = f.exec().Exec(ctx, b, "iptables-restore", "--noflush")
+		if err != nil {
+			return fmt.Errorf("apply iptables-restore: %v", err)
+		}
+		b, err = f.Ip6tables(c)
+		if err != nil {
+			return fmt.Errorf("apply: %v", err)
+		}
+		_, err = f.exec().Exec(ctx, b, "ip6tables-restore", "--noflush")
+		if err != nil {
+			return fmt.Errorf("apply ip6tables-restore: %v", err)
+		}
+	default:
+		return fmt.Errorf("apply: unknown backend %v", f.Backend)
+	}
+	return nil
+}
+
+// Remove deletes the rules installed by Apply
+// nft delete table / iptables -D, -F, -X
+func (f Firewall) Remove(ctx context.Context) error {
+	switch f.Backend {
+	case Nftables:
+		_, err := f.exec().Exec(ctx, nil, "nft", "delete", "table", "inet", f.Table())
+		if err != nil {
+			return fmt.Errorf("remove nft: %v", err)
+		}
+	case Iptables:
+		for _, bin := range []string{"iptables", "ip6tables"} {
+			for _, args := range f.removeArgs() {
+				_, err := f.exec().Exec(ctx, nil, bin, args...)
+				if err != nil {
+					return fmt.Errorf("remove %v: %v", bin, err)
+				}
+			}
+		}
+	default:
+		return fmt.Errorf("remove: unknown backend %v", f.Backend)
+	}
+	return nil
+}
+
+// removeArgs are the iptables invocations undoing iptables()
+func (f Firewall) removeArgs() [][]string {
+	in, fwd, nat := f.chain("IN"), f.chain("FWD"), f.chain("NAT")
+	args := [][]string{
+		{"-t", "filter", "-D", "INPUT", "-j", in},
+		{"-t", "filter", "-D", "FORWARD", "-j", fwd},
+		{"-t", "filter", "-F", in},
+		{"-t", "filter", "-F", fwd},
+		{"-t", "filter", "-X", in},
+		{"-t", "filter", "-X", fwd},
+	}
+	if f.Outbound != "" {
+		args = append(args,
+			[]string{"-t", "nat", "-D", "POSTROUTING", "-j", nat},
+			[]string{"-t", "nat", "-F", nat},
+			[]string{"-t", "nat", "-X", nat},
+		)
+	}
+	return args
+}
@@ -0,0 +1,182 @@
+package firewall
+
+import (
+	"context"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	wg "seankhliao.com/go-wg"
+)
+
+var (
+	update = flag.Bool("update", false, "update golden files")
+
+	se = "%v #%v errored: %v"
+	sf = "%v #%v \nexp: >%v< \ngot: >%v<"
+)
+
+var gateway = wg.Conf{
+	Interface: wg.Interface{
+		ListenPort: 51820,
+		PrivateKey: "this_is_a_private_key",
+	},
+	Peers: []wg.Peer{
+		{
+			PublicKey:  "pubkey_a",
+			AllowedIPs: []string{"10.0.0.2/32", "fd00::2/128"},
+		}, {
+			PublicKey:  "pubkey_b",
+			AllowedIPs: []string{"10.0.0.3/32", "192.168.1.1/24"},
+		},
+	},
+}
+
+// execRecorder records commands run
+type execRecorder struct {
+	cmds  []string
+	stdin [][]byte
+}
+
+func (e *execRecorder) Exec(ctx context.Context, stdin []byte, name string, args ...string) ([]byte, error) {
+	e.cmds = append(e.cmds, strings.Join(append([]string{name}, args...), " "))
+	e.stdin = append(e.stdin, stdin)
+	return nil, nil
+}
+
+func golden(t *testing.T, name string, b []byte) {
+	fpath := filepath.Join("testdata", name)
+	if *update {
+		err := ioutil.WriteFile(fpath, b, 0644)
+		if err != nil {
+			t.Fatalf("update %v: %v", fpath, err)
+		}
+	}
+	exp, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		t.Fatalf("read %v: %v", fpath, err)
+	}
+	if string(exp) != string(b) {
+		t.Errorf(sf, name, 0, string(exp), string(b))
+	}
+}
+
+// Conf -> golden
+func TestRules(t *testing.T) {
+	cases := []struct {
+		Name string
+		F    Firewall
+		C    wg.Conf
+	}{
+		{
+			"empty",
+			Firewall{Interface: "wg0"},
+			wg.Conf{},
+		}, {
+			"gateway",
+			Firewall{Interface: "wg0", Outbound: "eth0"},
+			gateway,
+		},
+	}
+	for i, c := range cases {
+		nft, err := c.F.Nftables(c.C)
+		if err != nil {
+			t.Errorf(se, "Nftables", i, err)
+			continue
+		}
+		golden(t, c.Name+".nft", nft)
+
+		ipt, err := c.F.Iptables(c.C)
+		if err != nil {
+			t.Errorf(se, "Iptables", i, err)
+			continue
+		}
+		golden(t, c.Name+".iptables", ipt)
+
+		ip6t, err := c.F.Ip6tables(c.C)
+		if err != nil {
+			t.Errorf(se, "Ip6tables", i, err)
+			continue
+		}
+		golden(t, c.Name+".ip6tables", ip6t)
+	}
+}
+
+func TestRulesInvalid(t *testing.T) {
+	f := Firewall{Interface: "wg0"}
+	c := wg.Conf{Peers: []wg.Peer{{PublicKey: "pubkey_a", AllowedIPs: []string{"not_an_ip"}}}}
+	if _, err := f.Nftables(c); err == nil {
+		t.Errorf(se, "Nftables", 0, "expected error")
+	}
+	if _, err := f.Iptables(c); err == nil {
+		t.Errorf(se, "Iptables", 0, "expected error")
+	}
+}
+
+func TestApplyRemove(t *testing.T) {
+	cases := []struct {
+		F      Firewall
+		Apply  []string
+		Remove []string
+	}{
+		{
+			Firewall{Interface: "wg0", Backend: Nftables},
+			[]string{"nft -f -"},
+			[]string{"nft delete table inet wg_wg0"},
+		}, {
+			Firewall{Interface: "wg0", Outbound: "eth0", Backend: Iptables},
+			[]string{"iptables-restore --noflush", "ip6tables-restore --noflush"},
+			[]string{
+				"iptables -t filter -D INPUT -j WG-wg0-IN",
+				"iptables -t filter -D FORWARD -j WG-wg0-FWD",
+				"iptables -t filter -F WG-wg0-IN",
+				"iptables -t filter -F WG-wg0-FWD",
+				"iptables -t filter -X WG-wg0-IN",
+				"iptables -t filter -X WG-wg0-FWD",
+				"iptables -t nat -D POSTROUTING -j WG-wg0-NAT",
+				"iptables -t nat -F WG-wg0-NAT",
+				"iptables -t nat -X WG-wg0-NAT",
+				"ip6tables -t filter -D INPUT -j WG-wg0-IN",
+				"ip6tables -t filter -D FORWARD -j WG-wg0-FWD",
+				"ip6tables -t filter -F WG-wg0-IN",
+				"ip6tables -t filter -F WG-wg0-FWD",
+				"ip6tables -t filter -X WG-wg0-IN",
+				"ip6tables -t filter -X WG-wg0-FWD",
+				"ip6tables -t nat -D POSTROUTING -j WG-wg0-NAT",
+				"ip6tables -t nat -F WG-wg0-NAT",
+				"ip6tables -t nat -X WG-wg0-NAT",
+			},
+		},
+	}
+	for i, c := range cases {
+		e := &execRecorder{}
+		c.F.Exec = e
+		err := c.F.Apply(context.Background(), gateway)
+		if err != nil {
+			t.Errorf(se, "Apply", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(e.cmds, c.Apply) {
+			t.Errorf(sf, "Apply", i, c.Apply, e.cmds)
+		}
+		for j, stdin := range e.stdin {
+			if len(stdin) == 0 {
+				t.Errorf(sf, "Apply stdin", j, "rules", "nothing")
+			}
+		}
+
+		e = &execRecorder{}
+		c.F.Exec = e
+		err = c.F.Remove(context.Background())
+		if err != nil {
+			t.Errorf(se, "Remove", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(e.cmds, c.Remove) {
+			t.Errorf(sf, "Remove", i, c.Remove, e.cmds)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+// Package atomicfile writes files that readers never see partially written
+package atomicfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// WriteFile writes b to fpath with 0600 permissions,
+// through a temporary file in the same directory renamed over fpath
+func WriteFile(fpath string, b []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(fpath), "."+filepath.Base(fpath)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	err = f.Chmod(0600)
+	if err == nil {
+		_, err = f.Write(b)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), fpath)
+}
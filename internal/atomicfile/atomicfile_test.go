@@ -0,0 +1,41 @@
+package atomicfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var (
+	se = "%v #%v errored: %v"
+	sf = "%v #%v \nexp: >%v< \ngot: >%v<"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	fpath := filepath.Join(dir, "state.json")
+	ioutil.WriteFile(fpath, []byte("old contents\n"), 0644)
+
+	for i, s := range []string{"first\n", "second\n"} {
+		err := WriteFile(fpath, []byte(s))
+		if err != nil {
+			t.Fatalf(se, "WriteFile", i, err)
+		}
+		b, _ := ioutil.ReadFile(fpath)
+		if string(b) != s {
+			t.Errorf(sf, "WriteFile", i, s, string(b))
+		}
+		if fi, err := os.Stat(fpath); err != nil || fi.Mode().Perm() != 0600 {
+			t.Errorf(sf, "WriteFile mode", i, os.FileMode(0600), fi)
+		}
+	}
+	if fis, _ := ioutil.ReadDir(dir); len(fis) != 1 {
+		t.Errorf(sf, "WriteFile temporary", 0, 1, len(fis))
+	}
+
+	err := WriteFile(filepath.Join(dir, "missing", "state.json"), nil)
+	if err == nil {
+		t.Errorf(se, "WriteFile", 2, "expected error")
+	}
+}
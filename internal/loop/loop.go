@@ -0,0 +1,26 @@
+// Package loop runs periodic tasks for the long running services
+package loop
+
+import (
+	"context"
+	"time"
+)
+
+// Every calls fn immediately and then every interval until ctx is cancelled,
+// returning ctx.Err(),
+// errors from fn don't stop the loop and are passed to errf if not nil
+func Every(ctx context.Context, interval time.Duration, fn func(context.Context) error, errf func(error)) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		err := fn(ctx)
+		if err != nil && errf != nil {
+			errf(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
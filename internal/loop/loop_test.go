@@ -0,0 +1,49 @@
+package loop
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var (
+	se = "%v #%v errored: %v"
+	sf = "%v #%v \nexp: >%v< \ngot: >%v<"
+)
+
+func TestEvery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errFail := errors.New("fail")
+	var calls int
+	var errs []error
+	fn := func(context.Context) error {
+		calls++
+		if calls == 3 {
+			cancel()
+		}
+		if calls == 2 {
+			return errFail
+		}
+		return nil
+	}
+	err := Every(ctx, time.Millisecond, fn, func(err error) { errs = append(errs, err) })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf(sf, "Every", 0, context.Canceled, err)
+	}
+	if calls != 3 {
+		t.Errorf(sf, "Every calls", 0, 3, calls)
+	}
+	if !reflect.DeepEqual(errs, []error{errFail}) {
+		t.Errorf(sf, "Every errf", 0, []error{errFail}, errs)
+	}
+
+	// nil errf
+	err = Every(ctx, time.Millisecond, func(context.Context) error { return errFail }, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf(se, "Every", 1, err)
+	}
+}
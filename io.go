@@ -0,0 +1,212 @@
+package wg
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"seankhliao.com/go-wg/internal/atomicfile"
+)
+
+// Decoder reads a conf file from an io.Reader,
+// peers can be read one at a time with Next for large configs
+type Decoder struct {
+	sc    *bufio.Scanner
+	iface Interface
+	peer  *Peer // peer section being read
+	done  bool
+}
+
+// NewDecoder creates a Decoder reading from r
+func NewDecoder(r io.Reader) *Decoder {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 4096), 1024*1024)
+	return &Decoder{sc: sc}
+}
+
+// Interface returns the [Interface] keys read so far,
+// complete once Next has returned io.EOF if the Interface section isn't first
+func (d *Decoder) Interface() Interface {
+	return d.iface
+}
+
+// Next reads the next [Peer] section,
+// returns io.EOF when there are no more peers
+func (d *Decoder) Next() (Peer, error) {
+	for !d.done && d.sc.Scan() {
+		line := d.sc.Text()
+		// comments run to the end of the line, as in wg
+		if i := strings.IndexByte(line, '#'); i != -1 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if line == "[Peer]" {
+			prev := d.peer
+			d.peer = &Peer{}
+			if prev != nil {
+				return *prev, nil
+			}
+			continue
+		}
+		err := d.decodeLine(line)
+		if err != nil {
+			return Peer{}, err
+		}
+	}
+	if err := d.sc.Err(); err != nil {
+		return Peer{}, fmt.Errorf("read conf: %v", err)
+	}
+	d.done = true
+	if d.peer != nil {
+		p := *d.peer
+		d.peer = nil
+		return p, nil
+	}
+	return Peer{}, io.EOF
+}
+
+// Decode reads the rest of the conf
+func (d *Decoder) Decode() (Conf, error) {
+	var c Conf
+	for {
+		p, err := d.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return c, err
+		}
+		c.Peers = append(c.Peers, p)
+	}
+	c.Interface = d.iface
+	return c, nil
+}
+
+func (d *Decoder) decodeLine(line string) error {
+	var err error
+	words := strings.SplitN(line, "=", 2)
+	if len(words) == 2 {
+		words[1] = strings.TrimSpace(words[1])
+	}
+	key := strings.TrimSpace(words[0])
+	switch key {
+
+	// Interface
+	case "[Interface]":
+	case "ListenPort":
+		d.iface.ListenPort, err = strconv.Atoi(words[1])
+		if err != nil {
+			return fmt.Errorf("error parsing ListenPort: %v", err)
+		}
+	case "FwMark":
+		d.iface.FwMark = words[1]
+	case "PrivateKey":
+		d.iface.PrivateKey = words[1]
+	case "Address":
+		for _, addr := range strings.Split(words[1], ",") {
+			d.iface.Address = append(d.iface.Address, strings.TrimSpace(addr))
+		}
+	case "DNS":
+		for _, dns := range strings.Split(words[1], ",") {
+			d.iface.DNS = append(d.iface.DNS, strings.TrimSpace(dns))
+		}
+
+	// Peer
+	case "PublicKey", "Endpoint", "AllowedIPs", "PresharedKey", "PersistentKeepalive":
+		if d.peer == nil {
+			return fmt.Errorf("key outside [Peer]: %v", line)
+		}
+		switch key {
+		case "PublicKey":
+			d.peer.PublicKey = words[1]
+		case "Endpoint":
+			d.peer.Endpoint = words[1]
+		case "AllowedIPs":
+			for _, ip := range strings.Split(words[1], ",") {
+				d.peer.AllowedIPs = append(d.peer.AllowedIPs, strings.TrimSpace(ip))
+			}
+		case "PresharedKey":
+			d.peer.PresharedKey = words[1]
+		case "PersistentKeepalive":
+			if words[1] == "off" {
+				d.peer.PersistentKeepalive = 0
+			} else {
+				d.peer.PersistentKeepalive, err = strconv.Atoi(words[1])
+				if err != nil {
+					return fmt.Errorf("error parsing PersistentKeepalive: %v", err)
+				}
+			}
+		}
+
+	// Unknown key
+	default:
+		return fmt.Errorf("unknown key: %v", line)
+	}
+	return nil
+}
+
+// WriteTo encodes a Conf to w one section at a time
+func (c Conf) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(c.Interface.Bytes())
+	total := int64(n)
+	if err != nil {
+		return total, err
+	}
+	for _, p := range c.Peers {
+		n, err = w.Write(p.Bytes())
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// MarshalText encodes a Conf in conf file format, same as Bytes
+func (c Conf) MarshalText() ([]byte, error) {
+	return c.Bytes(), nil
+}
+
+// UnmarshalText decodes a conf file, same as NewConfBytes
+func (c *Conf) UnmarshalText(b []byte) error {
+	conf, err := NewConfBytes(b)
+	if err != nil {
+		return err
+	}
+	*c = conf
+	return nil
+}
+
+// LoadFile reads a conf file
+func LoadFile(fpath string) (Conf, error) {
+	f, err := os.Open(fpath)
+	if err != nil {
+		return Conf{}, fmt.Errorf("load conf: %v", err)
+	}
+	defer f.Close()
+	c, err := NewDecoder(f).Decode()
+	if err != nil {
+		return Conf{}, fmt.Errorf("load conf %v: %v", fpath, err)
+	}
+	return c, nil
+}
+
+// SaveFile writes a conf file with 0600 permissions,
+// the file is replaced atomically so readers never see a partial config
+func (c Conf) SaveFile(fpath string) error {
+	var buf bytes.Buffer
+	_, err := c.WriteTo(&buf)
+	if err == nil {
+		err = atomicfile.WriteFile(fpath, buf.Bytes())
+	}
+	if err != nil {
+		return fmt.Errorf("save conf: %v", err)
+	}
+	return nil
+}
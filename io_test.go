@@ -0,0 +1,133 @@
+package wg
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var (
+	_ encoding.TextMarshaler   = Conf{}
+	_ encoding.TextUnmarshaler = &Conf{}
+	_ io.WriterTo              = Conf{}
+)
+
+// reader -> peers, one at a time
+func TestDecoderNext(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("[Interface]\nListenPort = 51820\n\n")
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&buf, "[Peer]\nPublicKey = pubkey_%d\nAllowedIPs = 10.%d.%d.0/24\n\n", i, i/256, i%256)
+	}
+
+	d := NewDecoder(&buf)
+	var n int
+	for ; ; n++ {
+		p, err := d.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf(se, "Decoder.Next", n, err)
+		}
+		exp := Peer{PublicKey: fmt.Sprintf("pubkey_%d", n), AllowedIPs: []string{fmt.Sprintf("10.%d.%d.0/24", n/256, n%256)}}
+		if !reflect.DeepEqual(p, exp) {
+			t.Fatalf(sf, "Decoder.Next", n, exp, p)
+		}
+	}
+	if n != 5000 {
+		t.Errorf(sf, "Decoder.Next count", 0, 5000, n)
+	}
+	if iface := d.Interface(); iface.ListenPort != 51820 {
+		t.Errorf(sf, "Decoder.Interface", 0, 51820, iface.ListenPort)
+	}
+	if _, err := d.Next(); err != io.EOF {
+		t.Errorf(sf, "Decoder.Next after EOF", 0, io.EOF, err)
+	}
+}
+
+func TestDecoderErrors(t *testing.T) {
+	cases := []string{
+		"PublicKey = outside_peer\n",
+		"[Peer]\nUnknown = key\n",
+		"[Interface]\nListenPort = port\n",
+	}
+	for i, c := range cases {
+		_, err := NewDecoder(strings.NewReader(c)).Decode()
+		if err == nil {
+			t.Errorf(se, "Decoder.Decode", i, "expected error")
+		}
+	}
+}
+
+// Conf -> text -> Conf
+func TestText(t *testing.T) {
+	c := Conf{
+		Interface{ListenPort: 51820, PrivateKey: "private_key"},
+		[]Peer{{PublicKey: "pubkey_a", AllowedIPs: []string{"10.0.0.2/32"}}},
+	}
+	b, err := c.MarshalText()
+	if err != nil {
+		t.Fatalf(se, "MarshalText", 0, err)
+	}
+	var buf bytes.Buffer
+	n, err := c.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf(se, "WriteTo", 0, err)
+	}
+	if n != int64(len(b)) || !bytes.Equal(buf.Bytes(), b) {
+		t.Errorf(sf, "WriteTo", 0, string(b), buf.String())
+	}
+	var conf Conf
+	err = conf.UnmarshalText(b)
+	if err != nil {
+		t.Fatalf(se, "UnmarshalText", 0, err)
+	}
+	if !reflect.DeepEqual(conf, c) {
+		t.Errorf(sf, "UnmarshalText", 0, c, conf)
+	}
+}
+
+func TestSaveLoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wg-conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fpath := filepath.Join(dir, "wg0.conf")
+	ioutil.WriteFile(fpath, []byte("old"), 0644)
+
+	c := Conf{
+		Interface{ListenPort: 51820, PrivateKey: "private_key"},
+		[]Peer{{PublicKey: "pubkey_a", AllowedIPs: []string{"10.0.0.2/32"}}},
+	}
+	err = c.SaveFile(fpath)
+	if err != nil {
+		t.Fatalf(se, "SaveFile", 0, err)
+	}
+	fi, err := os.Stat(fpath)
+	if err != nil {
+		t.Fatalf(se, "SaveFile stat", 0, err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf(sf, "SaveFile mode", 0, os.FileMode(0600), fi.Mode().Perm())
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf(sf, "SaveFile leftover files", 0, 1, len(files))
+	}
+
+	conf, err := LoadFile(fpath)
+	if err != nil {
+		t.Fatalf(se, "LoadFile", 0, err)
+	}
+	if !reflect.DeepEqual(conf, c) {
+		t.Errorf(sf, "LoadFile", 0, c, conf)
+	}
+}
@@ -0,0 +1,319 @@
+// Package ipam allocates tunnel addresses for peers
+// from one or more ipv4 / ipv6 prefixes
+package ipam
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/netip"
+	"sort"
+	"sync"
+
+	wg "seankhliao.com/go-wg"
+	"seankhliao.com/go-wg/internal/atomicfile"
+)
+
+// ErrExhausted is returned when a prefix has no free addresses left
+var ErrExhausted = errors.New("no free addresses")
+
+// IPAM tracks used addresses in a set of tunnel prefixes
+type IPAM struct {
+	mu        sync.Mutex
+	prefixes  []netip.Prefix
+	allocated map[netip.Addr]bool
+	reserved  map[netip.Addr]bool
+	routed    []netip.Prefix // non host AllowedIPs inside a prefix
+}
+
+// New creates an IPAM for the given prefixes (ip/mask)
+func New(prefixes ...string) (*IPAM, error) {
+	p := &IPAM{
+		allocated: make(map[netip.Addr]bool),
+		reserved:  make(map[netip.Addr]bool),
+	}
+	for _, s := range prefixes {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("new ipam: %v", err)
+		}
+		p.prefixes = append(p.prefixes, prefix.Masked())
+	}
+	return p, nil
+}
+
+// Prefixes returns the prefixes addresses are allocated from
+func (p *IPAM) Prefixes() []string {
+	var ss []string
+	for _, prefix := range p.prefixes {
+		ss = append(ss, prefix.String())
+	}
+	return ss
+}
+
+// contains reports whether addr is in one of the prefixes
+func (p *IPAM) contains(addr netip.Addr) bool {
+	for _, prefix := range p.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Load marks all addresses used by peers in c as allocated,
+// loading the same or an updated conf again is safe
+func (p *IPAM) Load(c wg.Conf) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, peer := range c.Peers {
+		for _, ip := range peer.AllowedIPs {
+			prefix, err := netip.ParsePrefix(ip)
+			if err != nil {
+				return fmt.Errorf("load peer %v: %v", peer.PublicKey, err)
+			}
+			if prefix.IsSingleIP() {
+				if p.contains(prefix.Addr()) {
+					p.allocated[prefix.Addr()] = true
+				}
+				continue
+			}
+			p.route(prefix.Masked())
+		}
+	}
+	return nil
+}
+
+// route records prefix as routed if it is inside a pool and isn't already recorded,
+// prefixes covering a whole pool, eg a default route, don't take addresses from it
+func (p *IPAM) route(prefix netip.Prefix) {
+	for _, r := range p.routed {
+		if r == prefix {
+			return
+		}
+	}
+	for _, pool := range p.prefixes {
+		if prefix.Bits() > pool.Bits() && pool.Overlaps(prefix) {
+			p.routed = append(p.routed, prefix)
+			return
+		}
+	}
+}
+
+// Reserve marks addresses (ip) as unavailable for allocation,
+// eg for the server's own tunnel address
+func (p *IPAM) Reserve(addrs ...string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, s := range addrs {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return fmt.Errorf("reserve: %v", err)
+		}
+		if !p.contains(addr) {
+			return fmt.Errorf("reserve: %v not in %v", addr, p.prefixes)
+		}
+		p.reserved[addr] = true
+	}
+	return nil
+}
+
+// Release returns addresses (ip or ip/mask) to the pool
+func (p *IPAM) Release(addrs ...string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, s := range addrs {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			prefix, err := netip.ParsePrefix(s)
+			if err != nil {
+				return fmt.Errorf("release: %v", err)
+			}
+			addr = prefix.Addr()
+		}
+		delete(p.allocated, addr)
+		delete(p.reserved, addr)
+	}
+	return nil
+}
+
+func (p *IPAM) free(prefix netip.Prefix, addr netip.Addr) bool {
+	if p.allocated[addr] || p.reserved[addr] {
+		return false
+	}
+	// network address
+	if addr == prefix.Addr() && prefix.Bits() < addr.BitLen()-1 {
+		return false
+	}
+	// ipv4 broadcast address
+	if addr.Is4() && prefix.Bits() < 31 && !prefix.Contains(addr.Next()) {
+		return false
+	}
+	return true
+}
+
+// routedAt returns the routed prefix containing addr
+func (p *IPAM) routedAt(addr netip.Addr) (netip.Prefix, bool) {
+	for _, r := range p.routed {
+		if r.Contains(addr) {
+			return r, true
+		}
+	}
+	return netip.Prefix{}, false
+}
+
+// last returns the last address in prefix
+func last(prefix netip.Prefix) netip.Addr {
+	b := prefix.Masked().Addr().AsSlice()
+	for i := prefix.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// Allocate allocates the next free address in each prefix
+// returned as host prefixes (ip/32, ip/128) suitable for AllowedIPs
+func (p *IPAM) Allocate() ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var addrs []netip.Addr
+	for _, prefix := range p.prefixes {
+		addr := prefix.Addr()
+		for ; prefix.Contains(addr); addr = addr.Next() {
+			if r, ok := p.routedAt(addr); ok {
+				// skip the rest of the routed prefix
+				addr = last(r)
+				continue
+			}
+			if p.free(prefix, addr) {
+				break
+			}
+		}
+		if !prefix.Contains(addr) {
+			return nil, fmt.Errorf("allocate %v: %w", prefix, ErrExhausted)
+		}
+		addrs = append(addrs, addr)
+	}
+	var ss []string
+	for _, addr := range addrs {
+		p.allocated[addr] = true
+		ss = append(ss, netip.PrefixFrom(addr, addr.BitLen()).String())
+	}
+	return ss, nil
+}
+
+// AddPeer allocates addresses for peer, appends them to its AllowedIPs
+// and adds the peer to c
+func (p *IPAM) AddPeer(c *wg.Conf, peer wg.Peer) (wg.Peer, error) {
+	addrs, err := p.Allocate()
+	if err != nil {
+		return peer, err
+	}
+	peer.AllowedIPs = append(addrs, peer.AllowedIPs...)
+	c.Peers = append(c.Peers, peer)
+	return peer, nil
+}
+
+// state is the persisted form of an IPAM
+type state struct {
+	Prefixes  []string `json:"prefixes"`
+	Allocated []string `json:"allocated"`
+	Reserved  []string `json:"reserved"`
+	Routed    []string `json:"routed,omitempty"`
+}
+
+func addrStrings(m map[netip.Addr]bool) []string {
+	var addrs []netip.Addr
+	for addr := range m {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i].Less(addrs[j]) })
+	ss := []string{}
+	for _, addr := range addrs {
+		ss = append(ss, addr.String())
+	}
+	return ss
+}
+
+// MarshalJSON encodes the IPAM state
+func (p *IPAM) MarshalJSON() ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := state{
+		Prefixes:  p.Prefixes(),
+		Allocated: addrStrings(p.allocated),
+		Reserved:  addrStrings(p.reserved),
+	}
+	for _, r := range p.routed {
+		s.Routed = append(s.Routed, r.String())
+	}
+	return json.Marshal(s)
+}
+
+// UnmarshalJSON decodes the IPAM state
+func (p *IPAM) UnmarshalJSON(b []byte) error {
+	var s state
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+	n, err := New(s.Prefixes...)
+	if err != nil {
+		return err
+	}
+	for _, a := range s.Allocated {
+		addr, err := netip.ParseAddr(a)
+		if err != nil {
+			return err
+		}
+		n.allocated[addr] = true
+	}
+	for _, a := range s.Reserved {
+		addr, err := netip.ParseAddr(a)
+		if err != nil {
+			return err
+		}
+		n.reserved[addr] = true
+	}
+	for _, r := range s.Routed {
+		prefix, err := netip.ParsePrefix(r)
+		if err != nil {
+			return err
+		}
+		// skips covering routes, as Load does
+		n.route(prefix)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prefixes, p.allocated, p.reserved, p.routed = n.prefixes, n.allocated, n.reserved, n.routed
+	return nil
+}
+
+// LoadFile reads IPAM state from a file written by SaveFile
+func LoadFile(fpath string) (*IPAM, error) {
+	b, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return nil, fmt.Errorf("load ipam: %v", err)
+	}
+	p := &IPAM{}
+	err = json.Unmarshal(b, p)
+	if err != nil {
+		return nil, fmt.Errorf("load ipam: %v", err)
+	}
+	return p, nil
+}
+
+// SaveFile writes IPAM state to a file readable only by its owner
+func (p *IPAM) SaveFile(fpath string) error {
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("save ipam: %v", err)
+	}
+	err = atomicfile.WriteFile(fpath, b)
+	if err != nil {
+		return fmt.Errorf("save ipam: %v", err)
+	}
+	return nil
+}
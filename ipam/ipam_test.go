@@ -0,0 +1,200 @@
+package ipam
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	wg "seankhliao.com/go-wg"
+)
+
+var (
+	se = "%v #%v errored: %v"
+	sf = "%v #%v \nexp: >%v< \ngot: >%v<"
+)
+
+func TestAllocate(t *testing.T) {
+	conf := wg.Conf{
+		Peers: []wg.Peer{
+			{PublicKey: "pubkey_a", AllowedIPs: []string{"10.0.0.2/32", "fd00::2/128"}},
+			{PublicKey: "pubkey_b", AllowedIPs: []string{"10.0.0.4/32", "192.168.0.0/24"}},
+			{PublicKey: "pubkey_c", AllowedIPs: []string{"10.0.0.8/30"}},
+		},
+	}
+	cases := []struct {
+		Prefixes []string
+		Reserved []string
+		Released []string
+		A        [][]string
+	}{
+		{
+			[]string{"10.0.0.0/24", "fd00::/64"},
+			[]string{"10.0.0.1", "fd00::1"},
+			nil,
+			[][]string{
+				{"10.0.0.3/32", "fd00::3/128"},
+				{"10.0.0.5/32", "fd00::4/128"},
+				{"10.0.0.6/32", "fd00::5/128"},
+				{"10.0.0.7/32", "fd00::6/128"},
+				{"10.0.0.12/32", "fd00::7/128"},
+			},
+		}, {
+			[]string{"10.0.0.0/24"},
+			nil,
+			[]string{"10.0.0.2/32"},
+			[][]string{
+				{"10.0.0.1/32"},
+				{"10.0.0.2/32"},
+				{"10.0.0.3/32"},
+			},
+		},
+	}
+	for i, c := range cases {
+		p, err := New(c.Prefixes...)
+		if err != nil {
+			t.Errorf(se, "New", i, err)
+			continue
+		}
+		err = p.Load(conf)
+		if err != nil {
+			t.Errorf(se, "Load", i, err)
+			continue
+		}
+		err = p.Reserve(c.Reserved...)
+		if err != nil {
+			t.Errorf(se, "Reserve", i, err)
+			continue
+		}
+		err = p.Release(c.Released...)
+		if err != nil {
+			t.Errorf(se, "Release", i, err)
+			continue
+		}
+		for j, exp := range c.A {
+			addrs, err := p.Allocate()
+			if err != nil {
+				t.Errorf(se, "Allocate", i, err)
+				break
+			}
+			if !reflect.DeepEqual(addrs, exp) {
+				t.Errorf(sf, "Allocate", j, exp, addrs)
+			}
+		}
+	}
+}
+
+func TestExhausted(t *testing.T) {
+	p, _ := New("10.0.0.0/30")
+	for i := 0; i < 2; i++ {
+		_, err := p.Allocate()
+		if err != nil {
+			t.Fatalf(se, "Allocate", i, err)
+		}
+	}
+	_, err := p.Allocate()
+	if !errors.Is(err, ErrExhausted) {
+		t.Errorf(sf, "Allocate", 2, ErrExhausted, err)
+	}
+}
+
+func TestRouted(t *testing.T) {
+	cases := []struct {
+		Prefix string
+		Routed []string
+		Kept   int // routed prefixes inside the pool
+		Exp    []string
+		Err    error
+	}{
+		{"10.0.0.0/24", []string{"10.0.0.0/25"}, 1, []string{"10.0.0.128/32"}, nil},
+		{"10.0.0.0/24", []string{"10.0.0.0/25", "10.0.0.128/26"}, 2, []string{"10.0.0.192/32"}, nil},
+		{"10.0.0.0/24", []string{"10.0.0.0/25", "10.0.0.128/25"}, 2, nil, ErrExhausted},
+		// covering routes, eg a peer routing everything, don't use up the pool
+		{"10.0.0.0/24", []string{"10.0.0.0/24"}, 0, []string{"10.0.0.1/32"}, nil},
+		{"10.0.0.0/24", []string{"10.0.0.0/16"}, 0, []string{"10.0.0.1/32"}, nil},
+		{"10.0.0.0/24", []string{"0.0.0.0/0"}, 0, []string{"10.0.0.1/32"}, nil},
+		{"10.0.0.0/24", []string{"0.0.0.0/0", "10.0.0.0/25"}, 1, []string{"10.0.0.128/32"}, nil},
+		{"fd00::/64", []string{"fd00::/65"}, 1, []string{"fd00::8000:0:0:0/128"}, nil},
+		{"fd00::/64", []string{"::/0"}, 0, []string{"fd00::1/128"}, nil},
+	}
+	for i, c := range cases {
+		p, _ := New(c.Prefix)
+		conf := wg.Conf{Peers: []wg.Peer{{PublicKey: "pubkey_a", AllowedIPs: c.Routed}}}
+		// loading again doesn't duplicate routed prefixes
+		for j := 0; j < 2; j++ {
+			err := p.Load(conf)
+			if err != nil {
+				t.Fatalf(se, "Load", i, err)
+			}
+		}
+		if len(p.routed) != c.Kept {
+			t.Errorf(sf, "Load routed", i, c.Kept, p.routed)
+		}
+		addrs, err := p.Allocate()
+		if !errors.Is(err, c.Err) {
+			t.Errorf(sf, "Allocate", i, c.Err, err)
+		}
+		if !reflect.DeepEqual(addrs, c.Exp) {
+			t.Errorf(sf, "Allocate", i, c.Exp, addrs)
+		}
+	}
+}
+
+func TestRoutedState(t *testing.T) {
+	p := &IPAM{}
+	err := p.UnmarshalJSON([]byte(`{"prefixes":["10.0.0.0/24"],"allocated":[],"reserved":[],"routed":["0.0.0.0/0","10.0.0.0/25"]}`))
+	if err != nil {
+		t.Fatalf(se, "UnmarshalJSON", 0, err)
+	}
+	addrs, err := p.Allocate()
+	if err != nil || !reflect.DeepEqual(addrs, []string{"10.0.0.128/32"}) {
+		t.Errorf(sf, "Allocate", 0, []string{"10.0.0.128/32"}, addrs)
+	}
+}
+
+func TestAddPeer(t *testing.T) {
+	p, _ := New("10.0.0.0/24")
+	p.Reserve("10.0.0.1")
+	c := wg.Conf{}
+	peer, err := p.AddPeer(&c, wg.Peer{PublicKey: "pubkey_a", AllowedIPs: []string{"192.168.0.0/24"}})
+	if err != nil {
+		t.Fatalf(se, "AddPeer", 0, err)
+	}
+	exp := wg.Peer{PublicKey: "pubkey_a", AllowedIPs: []string{"10.0.0.2/32", "192.168.0.0/24"}}
+	if !reflect.DeepEqual(peer, exp) {
+		t.Errorf(sf, "AddPeer", 0, exp, peer)
+	}
+	if !reflect.DeepEqual(c.Peers, []wg.Peer{exp}) {
+		t.Errorf(sf, "AddPeer conf", 0, []wg.Peer{exp}, c.Peers)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wg-ipam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fpath := filepath.Join(dir, "ipam.json")
+
+	p, _ := New("10.0.0.0/24", "fd00::/64")
+	p.Reserve("10.0.0.1")
+	p.Load(wg.Conf{Peers: []wg.Peer{{AllowedIPs: []string{"10.0.0.8/30"}}}})
+	p.Allocate()
+	err = p.SaveFile(fpath)
+	if err != nil {
+		t.Fatalf(se, "SaveFile", 0, err)
+	}
+
+	l, err := LoadFile(fpath)
+	if err != nil {
+		t.Fatalf(se, "LoadFile", 0, err)
+	}
+	exp, _ := p.Allocate()
+	got, _ := l.Allocate()
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf(sf, "LoadFile", 0, exp, got)
+	}
+}
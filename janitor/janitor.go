@@ -0,0 +1,180 @@
+// Package janitor removes managed peers that should no longer have access:
+// peers past their registry expiry and optionally peers idle for too long
+package janitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	wg "seankhliao.com/go-wg"
+	"seankhliao.com/go-wg/internal/loop"
+	"seankhliao.com/go-wg/registry"
+)
+
+// DefaultInterval is the time between sweeps
+const DefaultInterval = time.Minute
+
+var now = time.Now
+
+// Reason is why a peer was removed
+type Reason string
+
+// Removal reasons
+const (
+	Expired Reason = "expired"
+	Idle    Reason = "idle"
+)
+
+// Event is an audit event for a removed peer
+type Event struct {
+	Time      time.Time `json:"time"`
+	Interface string    `json:"interface"`
+	Name      string    `json:"name"`
+	PublicKey string    `json:"public_key"`
+	Owner     string    `json:"owner,omitempty"`
+	Reason    Reason    `json:"reason"`
+	Expires   time.Time `json:"expires,omitzero"`
+	// LatestHandshake is seconds ago, 0 for never
+	LatestHandshake int64 `json:"latest_handshake,omitempty"`
+	DryRun          bool  `json:"dry_run,omitempty"`
+}
+
+// Janitor removes expired and idle registry peers of an interface
+// from the live interface and the registry
+type Janitor struct {
+	Interface string
+	Registry  *registry.Registry
+	Client    wg.Client // defaults to wg.Cli
+
+	// Idle removes peers without a handshake for this long,
+	// peers that never had a handshake count from their creation
+	// or from when this Janitor first saw them, whichever is later,
+	// so a restarted Janitor doesn't remove peers that were waiting
+	// on a reboot, 0 disables
+	Idle time.Duration
+	// DryRun only reports what would be removed
+	DryRun bool
+	// Audit receives an Event per removal as JSON lines if not nil,
+	// not written to for dry runs
+	Audit    io.Writer
+	Interval time.Duration // time between sweeps, defaults to DefaultInterval
+
+	mu sync.Mutex
+	// never is when peers were first seen without a handshake
+	never map[string]time.Time
+}
+
+// Run sweeps every Interval until ctx is cancelled,
+// failed sweeps are reported to errf if not nil
+func (j *Janitor) Run(ctx context.Context, errf func(error)) error {
+	interval := j.Interval
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+	return loop.Every(ctx, interval, func(ctx context.Context) error {
+		_, err := j.Sweep(ctx)
+		return err
+	}, errf)
+}
+
+// Sweep checks peers once,
+// removing expired and idle peers from the interface in a single Set
+// and then from the registry,
+// returns the (would be) removals sorted by name
+func (j *Janitor) Sweep(ctx context.Context) ([]Event, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	client := j.Client
+	if client == nil {
+		client = wg.Cli{}
+	}
+
+	peers, err := j.Registry.Peers(j.Interface)
+	if err != nil {
+		return nil, fmt.Errorf("sweep: %v", err)
+	}
+	c, err := client.Show(ctx, j.Interface)
+	if err != nil {
+		return nil, fmt.Errorf("sweep: %v", err)
+	}
+	live := make(map[string]wg.Peer, len(c.Peers))
+	for _, p := range c.Peers {
+		live[p.PublicKey] = p
+	}
+
+	at := now()
+	never := make(map[string]time.Time)
+	for _, p := range c.Peers {
+		if p.LatestHandshake == 0 {
+			never[p.PublicKey] = at
+			if t, ok := j.never[p.PublicKey]; ok {
+				never[p.PublicKey] = t
+			}
+		}
+	}
+	j.never = never
+
+	var events []Event
+	var remove []wg.OptPeer
+	for _, p := range peers {
+		lp, ok := live[p.PublicKey]
+		ev := Event{
+			Time:            at.UTC(),
+			Interface:       j.Interface,
+			Name:            p.Name,
+			PublicKey:       p.PublicKey,
+			Owner:           p.Owner,
+			Expires:         p.Expires,
+			LatestHandshake: lp.LatestHandshake,
+			DryRun:          j.DryRun,
+		}
+		switch {
+		case p.Expired(at):
+			ev.Reason = Expired
+		case j.Idle > 0 && ok && idle(p, lp, never[p.PublicKey], at) >= j.Idle:
+			ev.Reason = Idle
+		default:
+			continue
+		}
+		events = append(events, ev)
+		if ok {
+			remove = append(remove, wg.OptPeer{PublicKey: p.PublicKey, Remove: true})
+		}
+	}
+	if j.DryRun || len(events) == 0 {
+		return events, nil
+	}
+
+	if len(remove) > 0 {
+		err = client.Set(ctx, wg.Opt{Interface: j.Interface, Peers: remove})
+		if err != nil {
+			return nil, fmt.Errorf("sweep: %v", err)
+		}
+	}
+	for i, ev := range events {
+		err = j.Registry.Delete(j.Interface, ev.Name)
+		if err == nil && j.Audit != nil {
+			err = json.NewEncoder(j.Audit).Encode(ev)
+		}
+		if err != nil {
+			return events[:i], fmt.Errorf("sweep: %v", err)
+		}
+	}
+	return events, nil
+}
+
+// idle is how long p has been without a handshake,
+// seen is when a peer without one was first seen
+func idle(p registry.Peer, lp wg.Peer, seen, at time.Time) time.Duration {
+	if lp.LatestHandshake != 0 {
+		return time.Duration(lp.LatestHandshake) * time.Second
+	}
+	if p.Created.After(seen) {
+		seen = p.Created
+	}
+	return at.Sub(seen)
+}
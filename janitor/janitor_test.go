@@ -0,0 +1,175 @@
+package janitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	wg "seankhliao.com/go-wg"
+	"seankhliao.com/go-wg/registry"
+	"seankhliao.com/go-wg/wgtest"
+)
+
+var (
+	se = "%v #%v errored: %v"
+	sf = "%v #%v \nexp: >%v< \ngot: >%v<"
+
+	keyA = "AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA="
+	keyB = "B6N8vBQgk8i3VdwbEOhstCY3StFqqFPtC9/AsrhtHHw="
+	keyC = "ICEiIyQlJicoKSorLC0uLzAxMjM0NTY3ODk6Ozw9Pj8="
+	keyD = "QEFCQ0RFRkdISUpLTE1OT1BRUlNUVVZXWFlaW1xdXl8="
+	keyE = "YGFiY2RlZmdoaWprbG1ub3BxcnN0dXZ3eHl6e3x9fn8="
+)
+
+func TestSweep(t *testing.T) {
+	t0 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	now = func() time.Time { return t0 }
+	defer func() { now = time.Now }()
+
+	reg, _ := registry.Open(t.TempDir())
+	reg.PutInterface("wg0", wg.Interface{})
+	day := 24 * time.Hour
+	peers := []registry.Peer{
+		{Name: "contractor", Owner: "bob", PublicKey: keyA, Expires: t0.Add(-time.Hour), Created: t0.Add(-10 * day)},
+		{Name: "laptop", PublicKey: keyB, Expires: t0.Add(day), Created: t0.Add(-10 * day)},
+		{Name: "old", PublicKey: keyC, Created: t0.Add(-10 * day)},
+		{Name: "new", PublicKey: keyD, Created: t0.Add(-day)},
+		{Name: "offline", PublicKey: keyE, Expires: t0, Created: t0.Add(-10 * day)},
+	}
+	for i, p := range peers {
+		p.Interface = "wg0"
+		if err := reg.Put(p); err != nil {
+			t.Fatalf(se, "Put", i, err)
+		}
+	}
+	fake := wgtest.NewClient(map[string]wg.Conf{
+		"wg0": {Peers: []wg.Peer{
+			{PublicKey: keyA, LatestHandshake: 10},
+			{PublicKey: keyB, LatestHandshake: int64(8 * day / time.Second)},
+			{PublicKey: keyC},
+			{PublicKey: keyD},
+		}},
+	})
+
+	var audit bytes.Buffer
+	j := &Janitor{Interface: "wg0", Registry: reg, Client: fake, Idle: 7 * day, Audit: &audit, DryRun: true}
+	exp := []Event{
+		{Time: t0, Interface: "wg0", Name: "contractor", PublicKey: keyA, Owner: "bob", Reason: Expired, Expires: t0.Add(-time.Hour), LatestHandshake: 10, DryRun: true},
+		{Time: t0, Interface: "wg0", Name: "laptop", PublicKey: keyB, Reason: Idle, Expires: t0.Add(day), LatestHandshake: int64(8 * day / time.Second), DryRun: true},
+		{Time: t0, Interface: "wg0", Name: "offline", PublicKey: keyE, Reason: Expired, Expires: t0, DryRun: true},
+	}
+
+	events, err := j.Sweep(context.Background())
+	if err != nil {
+		t.Fatalf(se, "Sweep", 0, err)
+	}
+	if !reflect.DeepEqual(events, exp) {
+		t.Errorf(sf, "Sweep", 0, exp, events)
+	}
+	if len(fake.Sets()) != 0 || audit.Len() != 0 {
+		t.Errorf(sf, "DryRun", 0, 0, len(fake.Sets()))
+	}
+
+	j.DryRun = false
+	for i := range exp {
+		exp[i].DryRun = false
+	}
+	events, err = j.Sweep(context.Background())
+	if err != nil {
+		t.Fatalf(se, "Sweep", 1, err)
+	}
+	if !reflect.DeepEqual(events, exp) {
+		t.Errorf(sf, "Sweep", 1, exp, events)
+	}
+	sets := fake.Sets()
+	expSet := []wg.OptPeer{{PublicKey: keyA, Remove: true}, {PublicKey: keyB, Remove: true}}
+	if len(sets) != 1 || !reflect.DeepEqual(sets[0].Peers, expSet) {
+		t.Errorf(sf, "Set", 1, expSet, sets)
+	}
+	c, _ := fake.Show(context.Background(), "wg0")
+	if len(c.Peers) != 2 || c.Peers[0].PublicKey != keyC || c.Peers[1].PublicKey != keyD {
+		t.Errorf(sf, "Show", 1, []string{keyC, keyD}, c.Peers)
+	}
+	left, _ := reg.Peers("wg0")
+	if len(left) != 2 || left[0].Name != "new" || left[1].Name != "old" {
+		t.Errorf(sf, "Peers", 1, []string{"new", "old"}, left)
+	}
+
+	dec := json.NewDecoder(&audit)
+	var logged []Event
+	for dec.More() {
+		var ev Event
+		if err := dec.Decode(&ev); err != nil {
+			t.Fatalf(se, "Audit", 1, err)
+		}
+		logged = append(logged, ev)
+	}
+	if !reflect.DeepEqual(logged, exp) {
+		t.Errorf(sf, "Audit", 1, exp, logged)
+	}
+
+	events, err = j.Sweep(context.Background())
+	if err != nil || len(events) != 0 || len(fake.Sets()) != 1 {
+		t.Errorf(sf, "Sweep", 2, 0, events)
+	}
+}
+
+func TestSweepNever(t *testing.T) {
+	t0 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	at := t0
+	now = func() time.Time { return at }
+	defer func() { now = time.Now }()
+
+	reg, _ := registry.Open(t.TempDir())
+	reg.PutInterface("wg0", wg.Interface{})
+	day := 24 * time.Hour
+	peers := []registry.Peer{
+		{Name: "old", PublicKey: keyA, Created: t0.Add(-10 * day)},
+		{Name: "new", PublicKey: keyB, Created: t0.Add(9 * day)},
+	}
+	for i, p := range peers {
+		p.Interface = "wg0"
+		if err := reg.Put(p); err != nil {
+			t.Fatalf(se, "Put", i, err)
+		}
+	}
+	fake := wgtest.NewClient(map[string]wg.Conf{
+		"wg0": {Peers: []wg.Peer{{PublicKey: keyA}, {PublicKey: keyB}}},
+	})
+
+	cases := []struct {
+		At      time.Time
+		Restart bool
+		Removed []string
+	}{
+		// after a reboot no peer has a handshake yet
+		{t0, false, nil},
+		{t0.Add(6 * day), false, nil},
+		// a new Janitor starts counting again
+		{t0.Add(8 * day), true, nil},
+		{t0.Add(14 * day), false, nil},
+		{t0.Add(15 * day), false, []string{"old"}},
+		{t0.Add(16 * day), false, []string{"new"}},
+	}
+	j := &Janitor{Interface: "wg0", Registry: reg, Client: fake, Idle: 7 * day}
+	for i, c := range cases {
+		at = c.At
+		if c.Restart {
+			j = &Janitor{Interface: "wg0", Registry: reg, Client: fake, Idle: 7 * day}
+		}
+		events, err := j.Sweep(context.Background())
+		if err != nil {
+			t.Fatalf(se, "Sweep", i, err)
+		}
+		var removed []string
+		for _, ev := range events {
+			removed = append(removed, ev.Name)
+		}
+		if !reflect.DeepEqual(removed, c.Removed) {
+			t.Errorf(sf, "Sweep", i, c.Removed, removed)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package wg
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// KeyRefPrefix marks a PrivateKey or PresharedKey as a reference to a key in a key store,
+// eg PrivateKey = keystore:wg0 (see package keystore)
+const KeyRefPrefix = "keystore:"
+
+// KeyRef returns the key store name referenced by key
+func KeyRef(key string) (name string, ok bool) {
+	if !strings.HasPrefix(key, KeyRefPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(key, KeyRefPrefix), true
+}
+
+// KeyRefs returns the key store names referenced by the PrivateKey and PresharedKeys of c,
+// they must be resolved (see package keystore) before c is set on an interface
+func (c Conf) KeyRefs() []string {
+	var names []string
+	if name, ok := KeyRef(c.PrivateKey); ok {
+		names = append(names, name)
+	}
+	for _, p := range c.Peers {
+		if name, ok := KeyRef(p.PresharedKey); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// NewPrivateKey generates a private key without calling wg
+// same as wg genkey
+func NewPrivateKey() (string, error) {
+	k, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", fmt.Errorf("new private key: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(k.Bytes()), nil
+}
+
+// NewPresharedKey generates a preshared key without calling wg
+// same as wg genpsk
+func NewPresharedKey() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("new preshared key: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// PublicKeyFor derives the public key of a private key without calling wg
+// same as echo $privkey | wg pubkey
+func PublicKeyFor(privKey string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(privKey)
+	if err != nil {
+		return "", fmt.Errorf("public key: %v", err)
+	}
+	k, err := ecdh.X25519().NewPrivateKey(b)
+	if err != nil {
+		return "", fmt.Errorf("public key: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(k.PublicKey().Bytes()), nil
+}
@@ -0,0 +1,84 @@
+package wg
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+)
+
+func TestPublicKeyFor(t *testing.T) {
+	cases := []struct {
+		PrivKey, PubKey string
+	}{
+		{
+			"AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA=",
+			"B6N8vBQgk8i3VdwbEOhstCY3StFqqFPtC9/AsrhtHHw=",
+		},
+	}
+	for i, c := range cases {
+		pubkey, err := PublicKeyFor(c.PrivKey)
+		if err != nil {
+			t.Errorf(se, "PublicKeyFor", i, err)
+			continue
+		}
+		if pubkey != c.PubKey {
+			t.Errorf(sf, "PublicKeyFor", i, c.PubKey, pubkey)
+		}
+	}
+	if _, err := PublicKeyFor("not_a_key"); err == nil {
+		t.Errorf(se, "PublicKeyFor", "invalid", "expected error")
+	}
+}
+
+func TestNewKeys(t *testing.T) {
+	for i, gen := range []func() (string, error){NewPrivateKey, NewPresharedKey} {
+		a, err := gen()
+		if err != nil {
+			t.Errorf(se, "NewKey", i, err)
+			continue
+		}
+		b, _ := gen()
+		if a == b {
+			t.Errorf(sf, "NewKey unique", i, "different keys", a)
+		}
+		if raw, err := base64.StdEncoding.DecodeString(a); err != nil || len(raw) != 32 {
+			t.Errorf(sf, "NewKey", i, "32 bytes base64", a)
+		}
+		if _, err := PublicKeyFor(a); err != nil {
+			t.Errorf(se, "NewKey PublicKeyFor", i, err)
+		}
+	}
+}
+
+func TestKeyRef(t *testing.T) {
+	cases := []struct {
+		Key  string
+		Name string
+		Ok   bool
+	}{
+		{"keystore:wg0", "wg0", true},
+		{"keystore:", "", true},
+		{"AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA=", "", false},
+		{"", "", false},
+	}
+	for i, c := range cases {
+		name, ok := KeyRef(c.Key)
+		if name != c.Name || ok != c.Ok {
+			t.Errorf(sf, "KeyRef", i, c.Name, name)
+		}
+	}
+
+	c := Conf{
+		Interface: Interface{PrivateKey: "keystore:wg0"},
+		Peers: []Peer{
+			{PublicKey: "pubkey_a", PresharedKey: "keystore:wg0-a"},
+			{PublicKey: "pubkey_b", PresharedKey: "AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA="},
+		},
+	}
+	if refs := c.KeyRefs(); !reflect.DeepEqual(refs, []string{"wg0", "wg0-a"}) {
+		t.Errorf(sf, "KeyRefs", 0, []string{"wg0", "wg0-a"}, refs)
+	}
+	if refs := (Conf{}).KeyRefs(); refs != nil {
+		t.Errorf(sf, "KeyRefs", 1, nil, refs)
+	}
+}
@@ -0,0 +1,24 @@
+package wg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// KeyFiles returns a function writing keys to 0600 files in a temporary directory
+// for use as Opt.PrivKeyFpath and OptPeer.PskFpath, call cleanup to remove them
+func KeyFiles() (keyFile func(key string) (string, error), cleanup func(), err error) {
+	dir, err := ioutil.TempDir("", "wg-keys")
+	if err != nil {
+		return nil, nil, err
+	}
+	var n int
+	keyFile = func(key string) (string, error) {
+		n++
+		fpath := filepath.Join(dir, strconv.Itoa(n))
+		return fpath, ioutil.WriteFile(fpath, []byte(key), 0600)
+	}
+	return keyFile, func() { os.RemoveAll(dir) }, nil
+}
@@ -0,0 +1,36 @@
+package wg
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestKeyFiles(t *testing.T) {
+	keyFile, cleanup, err := KeyFiles()
+	if err != nil {
+		t.Fatalf(se, "KeyFiles", 0, err)
+	}
+	var fpaths []string
+	for i, key := range []string{"key1", "key2"} {
+		fpath, err := keyFile(key)
+		if err != nil {
+			t.Fatalf(se, "keyFile", i, err)
+		}
+		b, _ := ioutil.ReadFile(fpath)
+		if string(b) != key {
+			t.Errorf(sf, "keyFile", i, key, string(b))
+		}
+		if fi, _ := os.Stat(fpath); fi.Mode().Perm() != 0600 {
+			t.Errorf(sf, "keyFile mode", i, os.FileMode(0600), fi.Mode().Perm())
+		}
+		fpaths = append(fpaths, fpath)
+	}
+	if fpaths[0] == fpaths[1] {
+		t.Errorf(sf, "keyFile paths", 0, "different", fpaths)
+	}
+	cleanup()
+	if _, err := os.Stat(fpaths[0]); !os.IsNotExist(err) {
+		t.Errorf(sf, "cleanup", 0, "removed", err)
+	}
+}
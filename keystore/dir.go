@@ -0,0 +1,86 @@
+package keystore
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"seankhliao.com/go-wg/internal/atomicfile"
+)
+
+// Dir is a KeyStore of one 0600 file per key in a 0700 directory,
+// compatible with wg's key file arguments
+type Dir struct {
+	path string
+}
+
+// OpenDir uses path as a Dir, creating it if needed
+func OpenDir(path string) (*Dir, error) {
+	err := os.MkdirAll(path, 0700)
+	if err != nil {
+		return nil, fmt.Errorf("open dir: %v", err)
+	}
+	return &Dir{path}, nil
+}
+
+// Path is the file holding the key name
+func (d *Dir) Path(name string) string {
+	return filepath.Join(d.path, name)
+}
+
+// Get reads a key
+func (d *Dir) Get(name string) (string, error) {
+	if err := checkName(name); err != nil {
+		return "", fmt.Errorf("get: %v", err)
+	}
+	b, err := ioutil.ReadFile(d.Path(name))
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("get %v: %w", name, ErrNotFound)
+	} else if err != nil {
+		return "", fmt.Errorf("get %v: %v", name, err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// Put writes a key, replacing any previous one
+func (d *Dir) Put(name, key string) error {
+	if err := checkName(name); err != nil {
+		return fmt.Errorf("put: %v", err)
+	}
+	err := atomicfile.WriteFile(d.Path(name), []byte(key+"\n"))
+	if err != nil {
+		return fmt.Errorf("put %v: %v", name, err)
+	}
+	return nil
+}
+
+// Delete removes a key
+func (d *Dir) Delete(name string) error {
+	if err := checkName(name); err != nil {
+		return fmt.Errorf("delete: %v", err)
+	}
+	err := os.Remove(d.Path(name))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("delete %v: %w", name, ErrNotFound)
+	} else if err != nil {
+		return fmt.Errorf("delete %v: %v", name, err)
+	}
+	return nil
+}
+
+// Names lists the keys sorted by name
+func (d *Dir) Names() ([]string, error) {
+	fis, err := ioutil.ReadDir(d.path)
+	if err != nil {
+		return nil, fmt.Errorf("list: %v", err)
+	}
+	var names []string
+	for _, fi := range fis {
+		if fi.Mode().IsRegular() && validName.MatchString(fi.Name()) && !strings.HasPrefix(fi.Name(), ".") {
+			names = append(names, fi.Name())
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,23 @@
+package keystore
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestDir(t *testing.T) {
+	d, err := OpenDir(t.TempDir())
+	if err != nil {
+		t.Fatalf(se, "OpenDir", 0, err)
+	}
+	testStore(t, "Dir", d)
+	fi, err := os.Stat(d.Path("wg0"))
+	if err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf(sf, "mode", 0, os.FileMode(0600), fi)
+	}
+	names, err := d.Names()
+	if err != nil || !reflect.DeepEqual(names, []string{"wg0"}) {
+		t.Errorf(sf, "Names", 0, []string{"wg0"}, names)
+	}
+}
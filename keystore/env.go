@@ -0,0 +1,69 @@
+package keystore
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Env is a read only KeyStore of environment variables,
+// the key name is upper cased with . and - replaced by _,
+// eg Prefix WG_KEY_ and name wg0-psk reads $WG_KEY_WG0_PSK
+type Env struct {
+	Prefix string
+}
+
+// Var is the environment variable holding the key name
+func (e Env) Var(name string) string {
+	return e.Prefix + strings.NewReplacer(".", "_", "-", "_").Replace(strings.ToUpper(name))
+}
+
+// Get reads a key
+func (e Env) Get(name string) (string, error) {
+	if err := checkName(name); err != nil {
+		return "", fmt.Errorf("get: %v", err)
+	}
+	k, ok := os.LookupEnv(e.Var(name))
+	if !ok {
+		return "", fmt.Errorf("get %v: %w", name, ErrNotFound)
+	}
+	return strings.TrimSpace(k), nil
+}
+
+// Put returns ErrReadOnly
+func (e Env) Put(name, key string) error {
+	return fmt.Errorf("put %v: %w", name, ErrReadOnly)
+}
+
+// Delete returns ErrReadOnly
+func (e Env) Delete(name string) error {
+	return fmt.Errorf("delete %v: %w", name, ErrReadOnly)
+}
+
+// Read reads "name key" lines into a Map,
+// eg from a pipe or file descriptor passed by a secret manager,
+// # starts a comment
+func Read(r io.Reader) (Map, error) {
+	m := Map{}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("read keys: invalid line")
+		}
+		err := m.Put(fields[0], fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("read keys: %v", err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read keys: %v", err)
+	}
+	return m, nil
+}
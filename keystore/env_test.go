@@ -0,0 +1,48 @@
+package keystore
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnv(t *testing.T) {
+	e := Env{Prefix: "WG_TEST_"}
+	os.Setenv("WG_TEST_WG0_PEER_1", keyA+"\n")
+	defer os.Unsetenv("WG_TEST_WG0_PEER_1")
+
+	if v := e.Var("wg0.peer-1"); v != "WG_TEST_WG0_PEER_1" {
+		t.Errorf(sf, "Var", 0, "WG_TEST_WG0_PEER_1", v)
+	}
+	if k, err := e.Get("wg0.peer-1"); err != nil || k != keyA {
+		t.Errorf(sf, "Get", 0, keyA, k)
+	}
+	if _, err := e.Get("wg1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf(sf, "Get", 1, ErrNotFound, err)
+	}
+	if err := e.Put("wg0", keyA); !errors.Is(err, ErrReadOnly) {
+		t.Errorf(sf, "Put", 0, ErrReadOnly, err)
+	}
+	if err := e.Delete("wg0"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf(sf, "Delete", 0, ErrReadOnly, err)
+	}
+}
+
+func TestRead(t *testing.T) {
+	cases := []struct {
+		In  string
+		Exp Map
+	}{
+		{"# keys\nwg0 " + keyA + "\n\n  wg0-b   " + keyB + "\n", Map{"wg0": keyA, "wg0-b": keyB}},
+		{"wg0\n", nil},
+		{"../wg0 " + keyA + "\n", nil},
+	}
+	for i, c := range cases {
+		m, err := Read(strings.NewReader(c.In))
+		if (err != nil) != (c.Exp == nil) || !reflect.DeepEqual(m, c.Exp) {
+			t.Errorf(sf, "Read", i, c.Exp, m)
+		}
+	}
+}
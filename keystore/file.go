@@ -0,0 +1,185 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+	"seankhliao.com/go-wg/internal/atomicfile"
+)
+
+// file format: magic, scrypt salt, secretbox nonce, sealed JSON object of name: key
+const (
+	magic     = "wg-keystore-v1\n"
+	saltSize  = 16
+	nonceSize = 24
+)
+
+// scrypt parameters, N is lowered in tests
+var scryptN = 1 << 15
+
+// File is a KeyStore in a single file encrypted with NaCl secretbox,
+// using a key derived from a passphrase with scrypt
+type File struct {
+	path string
+	salt []byte
+	key  [32]byte
+	mu   sync.Mutex
+}
+
+// OpenFile opens the encrypted file at path,
+// creating an empty one if it doesn't exist
+func OpenFile(path, passphrase string) (*File, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("open file: empty passphrase")
+	}
+	f := &File{path: path}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		f.salt = make([]byte, saltSize)
+		_, err = rand.Read(f.salt)
+		if err == nil {
+			err = f.derive(passphrase)
+		}
+		if err == nil {
+			err = f.save(Map{})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("open file: %v", err)
+		}
+		return f, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("open file: %v", err)
+	}
+
+	if len(b) < len(magic)+saltSize || string(b[:len(magic)]) != magic {
+		return nil, fmt.Errorf("open file: %v is not a keystore file", path)
+	}
+	f.salt = b[len(magic) : len(magic)+saltSize]
+	err = f.derive(passphrase)
+	if err == nil {
+		_, err = f.open(b)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open file: %v", err)
+	}
+	return f, nil
+}
+
+func (f *File) derive(passphrase string) error {
+	k, err := scrypt.Key([]byte(passphrase), f.salt, scryptN, 8, 1, 32)
+	if err != nil {
+		return err
+	}
+	copy(f.key[:], k)
+	return nil
+}
+
+// open decrypts the contents of a keystore file
+func (f *File) open(b []byte) (Map, error) {
+	header := len(magic) + saltSize
+	if len(b) < header+nonceSize || !bytes.Equal(b[len(magic):header], f.salt) {
+		return nil, errors.New("corrupt file")
+	}
+	var nonce [nonceSize]byte
+	copy(nonce[:], b[header:])
+	plain, ok := secretbox.Open(nil, b[header+nonceSize:], &nonce, &f.key)
+	if !ok {
+		return nil, errors.New("wrong passphrase or corrupt file")
+	}
+	m := Map{}
+	err := json.Unmarshal(plain, &m)
+	return m, err
+}
+
+func (f *File) load() (Map, error) {
+	b, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+	return f.open(b)
+}
+
+// save encrypts m with a new nonce and writes it out
+func (f *File) save(m Map) error {
+	plain, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	var nonce [nonceSize]byte
+	_, err = rand.Read(nonce[:])
+	if err != nil {
+		return err
+	}
+	b := append([]byte(magic), f.salt...)
+	b = append(b, nonce[:]...)
+	b = secretbox.Seal(b, plain, &nonce, &f.key)
+	return atomicfile.WriteFile(f.path, b)
+}
+
+// Get decrypts a key
+func (f *File) Get(name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m, err := f.load()
+	if err != nil {
+		return "", fmt.Errorf("get %v: %v", name, err)
+	}
+	return m.Get(name)
+}
+
+// Put encrypts a key
+func (f *File) Put(name, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m, err := f.load()
+	if err != nil {
+		return fmt.Errorf("put %v: %v", name, err)
+	}
+	err = m.Put(name, key)
+	if err != nil {
+		return err
+	}
+	err = f.save(m)
+	if err != nil {
+		return fmt.Errorf("put %v: %v", name, err)
+	}
+	return nil
+}
+
+// Delete removes a key
+func (f *File) Delete(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m, err := f.load()
+	if err != nil {
+		return fmt.Errorf("delete %v: %v", name, err)
+	}
+	err = m.Delete(name)
+	if err != nil {
+		return err
+	}
+	err = f.save(m)
+	if err != nil {
+		return fmt.Errorf("delete %v: %v", name, err)
+	}
+	return nil
+}
+
+// Names lists the keys sorted by name
+func (f *File) Names() ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m, err := f.load()
+	if err != nil {
+		return nil, fmt.Errorf("list: %v", err)
+	}
+	return m.Names(), nil
+}
@@ -0,0 +1,55 @@
+package keystore
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFile(t *testing.T) {
+	scryptN = 1 << 10
+	fpath := filepath.Join(t.TempDir(), "keys")
+	f, err := OpenFile(fpath, "secret")
+	if err != nil {
+		t.Fatalf(se, "OpenFile", 0, err)
+	}
+	testStore(t, "File", f)
+
+	b, _ := ioutil.ReadFile(fpath)
+	if bytes.Contains(b, []byte(keyB)) || bytes.Contains(b, []byte("wg0")) {
+		t.Errorf(sf, "encrypted", 0, "no plaintext", string(b))
+	}
+	if fi, err := os.Stat(fpath); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf(sf, "mode", 0, os.FileMode(0600), fi)
+	}
+
+	f, err = OpenFile(fpath, "secret")
+	if err != nil {
+		t.Fatalf(se, "OpenFile", 1, err)
+	}
+	if k, err := f.Get("wg0"); err != nil || k != keyB {
+		t.Errorf(sf, "Get", 1, keyB, k)
+	}
+	if names, err := f.Names(); err != nil || !reflect.DeepEqual(names, []string{"wg0"}) {
+		t.Errorf(sf, "Names", 1, []string{"wg0"}, names)
+	}
+
+	if _, err = OpenFile(fpath, "wrong"); err == nil {
+		t.Errorf(se, "OpenFile", 2, "expected error for wrong passphrase")
+	}
+	if _, err = OpenFile(fpath, ""); err == nil {
+		t.Errorf(se, "OpenFile", 3, "expected error for empty passphrase")
+	}
+	b[len(b)-1] ^= 1
+	ioutil.WriteFile(fpath, b, 0600)
+	if _, err = OpenFile(fpath, "secret"); err == nil {
+		t.Errorf(se, "OpenFile", 4, "expected error for corrupt file")
+	}
+	ioutil.WriteFile(fpath, []byte("wg0 "+keyA), 0600)
+	if _, err = OpenFile(fpath, "secret"); err == nil {
+		t.Errorf(se, "OpenFile", 5, "expected error for not a keystore")
+	}
+}
@@ -0,0 +1,149 @@
+// Package keystore keeps private and preshared keys out of config files:
+// configs reference keys by name (PrivateKey = keystore:wg0)
+// and Resolve fills them in from a KeyStore when applying
+package keystore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	wg "seankhliao.com/go-wg"
+)
+
+var (
+	// ErrNotFound is returned for missing keys
+	ErrNotFound = errors.New("not found")
+	// ErrReadOnly is returned when modifying a read only KeyStore
+	ErrReadOnly = errors.New("read only")
+)
+
+// validName is the allowed form of key names,
+// they are used as file names
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
+func checkName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid name %q", name)
+	}
+	return nil
+}
+
+// KeyStore stores keys by name
+type KeyStore interface {
+	Get(name string) (string, error)
+	Put(name, key string) error
+	Delete(name string) error
+}
+
+// Map is an in memory KeyStore
+type Map map[string]string
+
+// Get gets a key
+func (m Map) Get(name string) (string, error) {
+	k, ok := m[name]
+	if !ok {
+		return "", fmt.Errorf("get %v: %w", name, ErrNotFound)
+	}
+	return k, nil
+}
+
+// Put sets a key
+func (m Map) Put(name, key string) error {
+	if err := checkName(name); err != nil {
+		return fmt.Errorf("put: %v", err)
+	}
+	m[name] = key
+	return nil
+}
+
+// Delete removes a key
+func (m Map) Delete(name string) error {
+	if _, ok := m[name]; !ok {
+		return fmt.Errorf("delete %v: %w", name, ErrNotFound)
+	}
+	delete(m, name)
+	return nil
+}
+
+// Names lists the keys sorted by name
+func (m Map) Names() []string {
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PassphraseEnv is the environment variable Open reads the passphrase of encrypted files from
+const PassphraseEnv = "WG_KEYSTORE_PASSPHRASE"
+
+// Open opens a KeyStore from a spec:
+//
+//	dir:path    directory of 0600 files, see Dir
+//	file:path   encrypted file with the passphrase from $WG_KEYSTORE_PASSPHRASE, see File
+//	env:PREFIX  environment variables, see Env
+//	fd:N        "name key" lines read from an inherited file descriptor, see Read
+func Open(spec string) (KeyStore, error) {
+	kind, arg, ok := strings.Cut(spec, ":")
+	if !ok || arg == "" {
+		return nil, fmt.Errorf("open keystore: invalid spec %q", spec)
+	}
+	switch kind {
+	case "dir":
+		return OpenDir(arg)
+	case "file":
+		pass, ok := os.LookupEnv(PassphraseEnv)
+		if !ok {
+			return nil, fmt.Errorf("open keystore: %v not set", PassphraseEnv)
+		}
+		return OpenFile(arg, pass)
+	case "env":
+		return Env{Prefix: arg}, nil
+	case "fd":
+		fd, err := strconv.ParseUint(arg, 10, 0)
+		if err != nil {
+			return nil, fmt.Errorf("open keystore: %v", err)
+		}
+		f := os.NewFile(uintptr(fd), "fd"+arg)
+		if f == nil {
+			return nil, fmt.Errorf("open keystore: invalid fd %v", arg)
+		}
+		defer f.Close()
+		return Read(f)
+	}
+	return nil, fmt.Errorf("open keystore: unknown kind %v", kind)
+}
+
+// Resolve returns a copy of c with key references in
+// Interface.PrivateKey and Peer.PresharedKey replaced by keys from ks
+func Resolve(c wg.Conf, ks KeyStore) (wg.Conf, error) {
+	get := func(key string) (string, error) {
+		name, ok := wg.KeyRef(key)
+		if !ok {
+			return key, nil
+		}
+		if err := checkName(name); err != nil {
+			return "", err
+		}
+		return ks.Get(name)
+	}
+	var err error
+	c.PrivateKey, err = get(c.PrivateKey)
+	if err != nil {
+		return c, fmt.Errorf("resolve private key: %v", err)
+	}
+	c.Peers = append([]wg.Peer(nil), c.Peers...)
+	for i, p := range c.Peers {
+		c.Peers[i].PresharedKey, err = get(p.PresharedKey)
+		if err != nil {
+			return c, fmt.Errorf("resolve preshared key of %v: %v", p.PublicKey, err)
+		}
+	}
+	return c, nil
+}
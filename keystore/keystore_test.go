@@ -0,0 +1,152 @@
+package keystore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"syscall"
+	"testing"
+
+	wg "seankhliao.com/go-wg"
+)
+
+var (
+	se = "%v #%v errored: %v"
+	sf = "%v #%v \nexp: >%v< \ngot: >%v<"
+
+	keyA = "AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA="
+	keyB = "B6N8vBQgk8i3VdwbEOhstCY3StFqqFPtC9/AsrhtHHw="
+	keyC = "ICEiIyQlJicoKSorLC0uLzAxMjM0NTY3ODk6Ozw9Pj8="
+)
+
+// testStore runs put, get and delete against a writable KeyStore
+func testStore(t *testing.T, name string, ks KeyStore) {
+	t.Helper()
+	if _, err := ks.Get("wg0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf(sf, name+" Get", 0, ErrNotFound, err)
+	}
+	for i, n := range []string{"wg0", "wg0.peer-1"} {
+		if err := ks.Put(n, keyA); err != nil {
+			t.Errorf(se, name+" Put", i, err)
+		}
+	}
+	if err := ks.Put("wg0", keyB); err != nil {
+		t.Errorf(se, name+" Put", 2, err)
+	}
+	for i, n := range []string{"", "../wg0", ".hidden"} {
+		if err := ks.Put(n, keyA); err == nil {
+			t.Errorf(se, name+" Put", 3+i, "expected error for invalid name")
+		}
+	}
+	if k, err := ks.Get("wg0"); err != nil || k != keyB {
+		t.Errorf(sf, name+" Get", 1, keyB, k)
+	}
+	if err := ks.Delete("wg0.peer-1"); err != nil {
+		t.Errorf(se, name+" Delete", 0, err)
+	}
+	if err := ks.Delete("wg0.peer-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf(sf, name+" Delete", 1, ErrNotFound, err)
+	}
+}
+
+func TestMap(t *testing.T) {
+	m := Map{}
+	testStore(t, "Map", m)
+	if names := m.Names(); !reflect.DeepEqual(names, []string{"wg0"}) {
+		t.Errorf(sf, "Names", 0, []string{"wg0"}, names)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	ks := Map{"wg0": keyA, "wg0-b": keyC}
+	c := wg.Conf{
+		Interface: wg.Interface{PrivateKey: "keystore:wg0"},
+		Peers: []wg.Peer{
+			{PublicKey: keyB, PresharedKey: "keystore:wg0-b"},
+			{PublicKey: keyC, PresharedKey: keyA},
+		},
+	}
+	got, err := Resolve(c, ks)
+	if err != nil {
+		t.Fatalf(se, "Resolve", 0, err)
+	}
+	exp := wg.Conf{
+		Interface: wg.Interface{PrivateKey: keyA},
+		Peers: []wg.Peer{
+			{PublicKey: keyB, PresharedKey: keyC},
+			{PublicKey: keyC, PresharedKey: keyA},
+		},
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf(sf, "Resolve", 0, exp, got)
+	}
+	if c.Peers[0].PresharedKey != "keystore:wg0-b" {
+		t.Errorf(sf, "Resolve", 0, "input unchanged", c.Peers[0].PresharedKey)
+	}
+
+	for i, c := range []wg.Conf{
+		{Interface: wg.Interface{PrivateKey: "keystore:wg1"}},
+		{Interface: wg.Interface{PrivateKey: "keystore:../wg0"}},
+		{Peers: []wg.Peer{{PublicKey: keyB, PresharedKey: "keystore:"}}},
+	} {
+		if _, err := Resolve(c, ks); err == nil {
+			t.Errorf(se, "Resolve", 1+i, "expected error")
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	scryptN = 1 << 10
+	dir := t.TempDir()
+	os.Setenv("WG_TEST_WG0", keyA)
+	defer os.Unsetenv("WG_TEST_WG0")
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString("wg0 " + keyA + "\n")
+	w.Close()
+	// Open takes ownership of the fd
+	fd, err := syscall.Dup(int(r.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+
+	cases := []struct {
+		Spec string
+		Pass string
+		Put  bool
+	}{
+		{"dir:" + filepath.Join(dir, "keys"), "", true},
+		{"file:" + filepath.Join(dir, "keys.enc"), "secret", true},
+		{"env:WG_TEST_", "", false},
+		{"fd:" + strconv.Itoa(fd), "", false},
+	}
+	for i, c := range cases {
+		if c.Pass != "" {
+			os.Setenv(PassphraseEnv, c.Pass)
+		}
+		ks, err := Open(c.Spec)
+		os.Unsetenv(PassphraseEnv)
+		if err != nil {
+			t.Errorf(se, "Open", i, err)
+			continue
+		}
+		if c.Put {
+			ks.Put("wg0", keyA)
+		}
+		if k, err := ks.Get("wg0"); err != nil || k != keyA {
+			t.Errorf(sf, "Open Get", i, keyA, k)
+		}
+	}
+
+	for i, spec := range []string{"", "dir:", "vault:x", "fd:x", "file:" + filepath.Join(dir, "keys.enc")} {
+		if _, err := Open(spec); err == nil {
+			t.Errorf(se, "Open", len(cases)+i, "expected error")
+		}
+	}
+}
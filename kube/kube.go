@@ -0,0 +1,175 @@
+// Package kube renders Conf into Kubernetes Secret and ConfigMap manifests and parses them back,
+// the ConfigMap holds the config without keys, the Secret holds the private and preshared keys
+package kube
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	wg "seankhliao.com/go-wg"
+)
+
+const (
+	// ConfKey is the ConfigMap data key holding the config
+	ConfKey = "wg.conf"
+	// PrivateKeyKey is the Secret data key holding the interface PrivateKey
+	PrivateKeyKey = "private-key"
+	// PresharedKeyPrefix prefixes the Secret data keys holding peer PresharedKeys,
+	// suffixed by the peer PublicKey in unpadded base64url so reordering peers keeps the match
+	PresharedKeyPrefix = "preshared-key-"
+)
+
+type metadata struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
+
+type manifest struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   metadata          `yaml:"metadata"`
+	Type       string            `yaml:"type,omitempty"`
+	Data       map[string]string `yaml:"data,omitempty"`
+	StringData map[string]string `yaml:"stringData,omitempty"`
+}
+
+// Render converts a Conf into a Secret and ConfigMap manifest named name in namespace,
+// namespace may be empty
+func Render(name, namespace string, c wg.Conf) (secret, configMap []byte, err error) {
+	meta := metadata{
+		Name:      name,
+		Namespace: namespace,
+		Labels:    map[string]string{"app.kubernetes.io/managed-by": "go-wg"},
+	}
+
+	data := make(map[string]string)
+	if c.PrivateKey != "" {
+		data[PrivateKeyKey] = base64.StdEncoding.EncodeToString([]byte(c.PrivateKey))
+	}
+	c.PrivateKey = ""
+	peers := make([]wg.Peer, len(c.Peers))
+	for i, p := range c.Peers {
+		if p.PresharedKey != "" {
+			k, err := PresharedKeyKey(p.PublicKey)
+			if err != nil {
+				return nil, nil, fmt.Errorf("render secret: %v", err)
+			}
+			data[k] = base64.StdEncoding.EncodeToString([]byte(p.PresharedKey))
+		}
+		p.PresharedKey = ""
+		peers[i] = p
+	}
+	c.Peers = peers
+
+	secret, err = marshal(manifest{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Metadata:   meta,
+		Type:       "Opaque",
+		Data:       data,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("render secret: %v", err)
+	}
+	configMap, err = marshal(manifest{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Metadata:   meta,
+		Data:       map[string]string{ConfKey: string(c.Bytes())},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("render configmap: %v", err)
+	}
+	return secret, configMap, nil
+}
+
+// PresharedKeyKey is the Secret data key holding the PresharedKey of the peer with publicKey
+func PresharedKeyKey(publicKey string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(b) != 32 {
+		return "", fmt.Errorf("invalid public key %q", publicKey)
+	}
+	return PresharedKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// marshal encodes a manifest with the 2 space indent of kubectl
+func marshal(m manifest) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	err := enc.Encode(m)
+	if err != nil {
+		return nil, err
+	}
+	err = enc.Close()
+	return buf.Bytes(), err
+}
+
+// decode unmarshals a manifest and checks its kind
+func decode(b []byte, kind string) (manifest, error) {
+	var m manifest
+	err := yaml.Unmarshal(b, &m)
+	if err != nil {
+		return m, err
+	}
+	if m.Kind != kind {
+		return m, fmt.Errorf("kind %v is not %v", m.Kind, kind)
+	}
+	return m, nil
+}
+
+// Parse converts a Secret and ConfigMap manifest back into a Conf,
+// secret may be nil, Secret stringData is also read
+func Parse(secret, configMap []byte) (wg.Conf, error) {
+	cm, err := decode(configMap, "ConfigMap")
+	if err != nil {
+		return wg.Conf{}, fmt.Errorf("parse configmap: %v", err)
+	}
+	c, err := wg.NewConfBytes([]byte(cm.Data[ConfKey]))
+	if err != nil {
+		return wg.Conf{}, fmt.Errorf("parse configmap %v: %v", ConfKey, err)
+	}
+	if secret == nil {
+		return c, nil
+	}
+
+	s, err := decode(secret, "Secret")
+	if err != nil {
+		return wg.Conf{}, fmt.Errorf("parse secret: %v", err)
+	}
+	keys := make(map[string]string)
+	for k, v := range s.Data {
+		b, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return wg.Conf{}, fmt.Errorf("parse secret %v: %v", k, err)
+		}
+		keys[k] = string(b)
+	}
+	for k, v := range s.StringData {
+		keys[k] = v
+	}
+	peers := make(map[string]int, len(c.Peers))
+	for i, p := range c.Peers {
+		k, err := PresharedKeyKey(p.PublicKey)
+		if err == nil {
+			peers[k] = i
+		}
+	}
+	for k, v := range keys {
+		switch {
+		case k == PrivateKeyKey:
+			c.PrivateKey = strings.TrimSpace(v)
+		case strings.HasPrefix(k, PresharedKeyPrefix):
+			i, ok := peers[k]
+			if !ok {
+				return wg.Conf{}, fmt.Errorf("parse secret: %v has no matching peer", k)
+			}
+			c.Peers[i].PresharedKey = strings.TrimSpace(v)
+		}
+	}
+	return c, nil
+}
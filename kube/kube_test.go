@@ -0,0 +1,140 @@
+package kube
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	wg "seankhliao.com/go-wg"
+)
+
+var (
+	update = flag.Bool("update", false, "update golden files")
+
+	se = "%v #%v errored: %v"
+	sf = "%v #%v \nexp: >%v< \ngot: >%v<"
+
+	pubA = "B6N8vBQgk8i3VdwbEOhstCY3StFqqFPtC9/AsrhtHHw="
+	keyC = "ICEiIyQlJicoKSorLC0uLzAxMjM0NTY3ODk6Ozw9Pj8="
+)
+
+func golden(t *testing.T, name string, b []byte) {
+	fpath := filepath.Join("testdata", name)
+	if *update {
+		err := ioutil.WriteFile(fpath, b, 0644)
+		if err != nil {
+			t.Fatalf("update %v: %v", fpath, err)
+		}
+	}
+	exp, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		t.Fatalf("read %v: %v", fpath, err)
+	}
+	if string(exp) != string(b) {
+		t.Errorf(sf, name, 0, string(exp), string(b))
+	}
+}
+
+var conf = wg.Conf{
+	Interface: wg.Interface{
+		ListenPort: 51820,
+		PrivateKey: "AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA=",
+		Address:    []string{"10.0.0.1/24"},
+	},
+	Peers: []wg.Peer{
+		{PublicKey: keyC, AllowedIPs: []string{"10.0.0.2/32"}},
+		{PublicKey: pubA, PresharedKey: "psk_b", AllowedIPs: []string{"10.0.0.3/32"}, Endpoint: "1.2.3.4:51820", PersistentKeepalive: 25},
+	},
+}
+
+func TestRenderParse(t *testing.T) {
+	secret, cm, err := Render("wg0", "vpn", conf)
+	if err != nil {
+		t.Fatalf(se, "Render", 0, err)
+	}
+	golden(t, "secret.yaml", secret)
+	golden(t, "configmap.yaml", cm)
+
+	c, err := Parse(secret, cm)
+	if err != nil {
+		t.Fatalf(se, "Parse", 0, err)
+	}
+	if !reflect.DeepEqual(c, conf) {
+		t.Errorf(sf, "Parse", 0, conf, c)
+	}
+	if conf.Peers[1].PresharedKey != "psk_b" {
+		t.Errorf(sf, "Render modified input", 0, "psk_b", conf.Peers[1].PresharedKey)
+	}
+
+	c, err = Parse(nil, cm)
+	if err != nil {
+		t.Fatalf(se, "Parse", 1, err)
+	}
+	if c.PrivateKey != "" || c.Peers[1].PresharedKey != "" {
+		t.Errorf(sf, "Parse without secret", 1, "no keys", c)
+	}
+
+	// preshared keys follow their peer when peers are reordered
+	reordered := conf
+	reordered.Peers = []wg.Peer{conf.Peers[1], conf.Peers[0]}
+	_, cm, err = Render("wg0", "vpn", reordered)
+	if err != nil {
+		t.Fatalf(se, "Render", 2, err)
+	}
+	c, err = Parse(secret, cm)
+	if err != nil {
+		t.Fatalf(se, "Parse", 2, err)
+	}
+	if !reflect.DeepEqual(c, reordered) {
+		t.Errorf(sf, "Parse reordered", 2, reordered, c)
+	}
+
+	bad := conf
+	bad.Peers = []wg.Peer{{PublicKey: "invalid", PresharedKey: "psk"}}
+	if _, _, err = Render("wg0", "vpn", bad); err == nil {
+		t.Errorf(se, "Render", 3, "expected error for invalid public key")
+	}
+}
+
+func TestParse(t *testing.T) {
+	cm := []byte("kind: ConfigMap\ndata:\n  wg.conf: |\n    [Interface]\n    ListenPort = 1\n\n    [Peer]\n    PublicKey = " + keyC + "\n")
+	cases := []struct {
+		Secret string
+		Exp    wg.Conf
+		Err    bool
+	}{
+		{
+			"kind: Secret\nstringData:\n  private-key: priv\n  preshared-key-ICEiIyQlJicoKSorLC0uLzAxMjM0NTY3ODk6Ozw9Pj8: psk\n",
+			wg.Conf{Interface: wg.Interface{ListenPort: 1, PrivateKey: "priv"}, Peers: []wg.Peer{{PublicKey: keyC, PresharedKey: "psk"}}},
+			false,
+		}, {
+			"kind: ConfigMap\n",
+			wg.Conf{},
+			true,
+		}, {
+			"kind: Secret\ndata:\n  private-key: '!!notbase64'\n",
+			wg.Conf{},
+			true,
+		}, {
+			"kind: Secret\nstringData:\n  preshared-key-0: psk\n",
+			wg.Conf{},
+			true,
+		}, {
+			"kind: Secret\nstringData:\n  preshared-key-B6N8vBQgk8i3VdwbEOhstCY3StFqqFPtC9_AsrhtHHw: psk\n",
+			wg.Conf{},
+			true,
+		},
+	}
+	for i, c := range cases {
+		got, err := Parse([]byte(c.Secret), cm)
+		if (err != nil) != c.Err {
+			t.Errorf(se, "Parse", i, err)
+			continue
+		}
+		if !c.Err && !reflect.DeepEqual(got, c.Exp) {
+			t.Errorf(sf, "Parse", i, c.Exp, got)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+// Package lint checks wireguard configs for security and operational problems
+// beyond what NewConfBytes rejects
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+
+	wg "seankhliao.com/go-wg"
+)
+
+// Severity of an issue, same as SARIF levels
+type Severity string
+
+// Severities
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+	Note    Severity = "note"
+)
+
+// Input is what rules check
+type Input struct {
+	Conf wg.Conf
+	Path string      // config file path, empty if not from a file
+	Mode os.FileMode // config file permissions, 0 if not from a file
+}
+
+// Rule is a single check,
+// add your own to a Linter's Rules
+type Rule struct {
+	ID          string // stable identifier, eg WG001
+	Name        string // short kebab-case name
+	Severity    Severity
+	Description string
+	// Check returns issues found, Issue.Rule and Issue.Severity are filled in by the Linter
+	Check func(Input) []Issue
+}
+
+// Issue is a problem found by a Rule
+type Issue struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	Peer     string   `json:"peer,omitempty"` // public key, empty for interface level issues
+	Path     string   `json:"path,omitempty"`
+}
+
+// Linter runs a set of rules
+type Linter struct {
+	Rules []Rule
+}
+
+// Default is a Linter with DefaultRules
+var Default = Linter{Rules: DefaultRules}
+
+// Lint checks c with the default rules
+func Lint(c wg.Conf) []Issue {
+	return Default.Lint(Input{Conf: c})
+}
+
+// LintFile checks a config file with the default rules
+func LintFile(fpath string) ([]Issue, error) {
+	return Default.LintFile(fpath)
+}
+
+// Lint runs all rules against in
+func (l Linter) Lint(in Input) []Issue {
+	var issues []Issue
+	for _, r := range l.Rules {
+		for _, i := range r.Check(in) {
+			i.Rule = r.ID
+			if i.Severity == "" {
+				i.Severity = r.Severity
+			}
+			i.Path = in.Path
+			issues = append(issues, i)
+		}
+	}
+	return issues
+}
+
+// LintFile reads and decodes a config file and runs all rules against it
+func (l Linter) LintFile(fpath string) ([]Issue, error) {
+	fi, err := os.Stat(fpath)
+	if err != nil {
+		return nil, fmt.Errorf("lint: %v", err)
+	}
+	b, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return nil, fmt.Errorf("lint: %v", err)
+	}
+	c, err := wg.NewConfBytes(b)
+	if err != nil {
+		return nil, fmt.Errorf("lint: %v", err)
+	}
+	return l.Lint(Input{Conf: c, Path: fpath, Mode: fi.Mode().Perm()}), nil
+}
+
+// DefaultRules are the built in rules
+var DefaultRules = []Rule{
+	{
+		ID: "WG001", Name: "missing-private-key", Severity: Error,
+		Description: "Interface has no PrivateKey",
+		Check: func(in Input) []Issue {
+			if in.Conf.PrivateKey == "" {
+				return []Issue{{Message: "interface has no PrivateKey"}}
+			}
+			return nil
+		},
+	}, {
+		ID: "WG002", Name: "duplicate-public-key", Severity: Error,
+		Description: "Multiple peers share a PublicKey, only the last one is used",
+		Check: func(in Input) []Issue {
+			var issues []Issue
+			seen := make(map[string]bool)
+			for _, p := range in.Conf.Peers {
+				if seen[p.PublicKey] {
+					issues = append(issues, Issue{Message: "duplicate peer PublicKey", Peer: p.PublicKey})
+				}
+				seen[p.PublicKey] = true
+			}
+			return issues
+		},
+	}, {
+		ID: "WG003", Name: "self-peer", Severity: Error,
+		Description: "A peer has the interface's own PublicKey",
+		Check: func(in Input) []Issue {
+			self := in.Conf.PublicKey
+			if self == "" {
+				self, _ = wg.PublicKeyFor(in.Conf.PrivateKey)
+			}
+			if self == "" {
+				return nil
+			}
+			var issues []Issue
+			for _, p := range in.Conf.Peers {
+				if p.PublicKey == self {
+					issues = append(issues, Issue{Message: "peer PublicKey is the interface's own", Peer: p.PublicKey})
+				}
+			}
+			return issues
+		},
+	}, {
+		ID: "WG004", Name: "missing-keepalive", Severity: Warning,
+		Description: "Interface has no ListenPort (likely behind NAT) but a peer with an Endpoint has no PersistentKeepalive",
+		Check: func(in Input) []Issue {
+			if in.Conf.ListenPort != 0 {
+				return nil
+			}
+			var issues []Issue
+			for _, p := range in.Conf.Peers {
+				if p.Endpoint != "" && p.PersistentKeepalive == 0 {
+					issues = append(issues, Issue{Message: "peer has no PersistentKeepalive, NAT mappings may expire", Peer: p.PublicKey})
+				}
+			}
+			return issues
+		},
+	}, {
+		ID: "WG005", Name: "inconsistent-preshared-key", Severity: Warning,
+		Description: "Some peers have a PresharedKey and others don't",
+		Check: func(in Input) []Issue {
+			var with int
+			for _, p := range in.Conf.Peers {
+				if p.PresharedKey != "" {
+					with++
+				}
+			}
+			if with == 0 || with == len(in.Conf.Peers) {
+				return nil
+			}
+			var issues []Issue
+			for _, p := range in.Conf.Peers {
+				if p.PresharedKey == "" {
+					issues = append(issues, Issue{Message: "peer has no PresharedKey while others do", Peer: p.PublicKey})
+				}
+			}
+			return issues
+		},
+	}, {
+		ID: "WG006", Name: "privileged-listen-port", Severity: Warning,
+		Description: "ListenPort is in the privileged range (< 1024)",
+		Check: func(in Input) []Issue {
+			if in.Conf.ListenPort > 0 && in.Conf.ListenPort < 1024 {
+				return []Issue{{Message: "ListenPort " + strconv.Itoa(in.Conf.ListenPort) + " is privileged"}}
+			}
+			return nil
+		},
+	}, {
+		ID: "WG007", Name: "endpoint-missing-port", Severity: Error,
+		Description: "Peer Endpoint is not host:port",
+		Check: func(in Input) []Issue {
+			var issues []Issue
+			for _, p := range in.Conf.Peers {
+				if p.Endpoint == "" {
+					continue
+				}
+				if _, port, err := net.SplitHostPort(p.Endpoint); err != nil || port == "" {
+					issues = append(issues, Issue{Message: "Endpoint " + p.Endpoint + " has no port", Peer: p.PublicKey})
+				}
+			}
+			return issues
+		},
+	}, {
+		ID: "WG008", Name: "config-permissions", Severity: Error,
+		Description: "Config file is accessible by other users",
+		Check: func(in Input) []Issue {
+			if in.Mode&0007 != 0 {
+				return []Issue{{Message: fmt.Sprintf("config file mode %#o is world accessible", in.Mode)}}
+			}
+			return nil
+		},
+	}, {
+		ID: "WG009", Name: "allowed-ips", Severity: Warning,
+		Description: "AllowedIPs are invalid, not canonical, duplicated or overlapping (see Conf.Validate)",
+		Check: func(in Input) []Issue {
+			var issues []Issue
+			for _, f := range in.Conf.Validate() {
+				i := Issue{Message: f.String(), Peer: f.Peer}
+				switch f.Kind {
+				case wg.Invalid, wg.Duplicate:
+					i.Severity = Error
+				case wg.NonCanonical:
+					i.Severity = Note
+				}
+				issues = append(issues, i)
+			}
+			return issues
+		},
+	},
+}
+
+// JSON encodes issues as a JSON array
+func JSON(issues []Issue) ([]byte, error) {
+	if issues == nil {
+		issues = []Issue{}
+	}
+	return json.MarshalIndent(issues, "", "  ")
+}
+
+// SARIF encodes issues as a SARIF 2.1.0 log,
+// rules are listed in the tool driver
+func (l Linter) SARIF(issues []Issue) ([]byte, error) {
+	type text struct {
+		Text string `json:"text"`
+	}
+	type rule struct {
+		ID               string `json:"id"`
+		Name             string `json:"name"`
+		ShortDescription text   `json:"shortDescription"`
+		DefaultConfig    struct {
+			Level Severity `json:"level"`
+		} `json:"defaultConfiguration"`
+	}
+	type location struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI string `json:"uri"`
+			} `json:"artifactLocation"`
+		} `json:"physicalLocation"`
+	}
+	type result struct {
+		RuleID    string     `json:"ruleId"`
+		Level     Severity   `json:"level"`
+		Message   text       `json:"message"`
+		Locations []location `json:"locations,omitempty"`
+	}
+	type run struct {
+		Tool struct {
+			Driver struct {
+				Name  string `json:"name"`
+				Rules []rule `json:"rules"`
+			} `json:"driver"`
+		} `json:"tool"`
+		Results []result `json:"results"`
+	}
+	var r run
+	r.Tool.Driver.Name = "wg-lint"
+	r.Tool.Driver.Rules = []rule{}
+	for _, lr := range l.Rules {
+		sr := rule{ID: lr.ID, Name: lr.Name, ShortDescription: text{lr.Description}}
+		sr.DefaultConfig.Level = lr.Severity
+		r.Tool.Driver.Rules = append(r.Tool.Driver.Rules, sr)
+	}
+	r.Results = []result{}
+	for _, i := range issues {
+		res := result{RuleID: i.Rule, Level: i.Severity, Message: text{i.Message}}
+		if i.Path != "" {
+			var loc location
+			loc.PhysicalLocation.ArtifactLocation.URI = i.Path
+			res.Locations = []location{loc}
+		}
+		r.Results = append(r.Results, res)
+	}
+	return json.MarshalIndent(struct {
+		Schema  string `json:"$schema"`
+		Version string `json:"version"`
+		Runs    []run  `json:"runs"`
+	}{
+		"https://json.schemastore.org/sarif-2.1.0.json",
+		"2.1.0",
+		[]run{r},
+	}, "", "  ")
+}
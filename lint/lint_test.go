@@ -0,0 +1,181 @@
+package lint
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	wg "seankhliao.com/go-wg"
+)
+
+var (
+	se = "%v #%v errored: %v"
+	sf = "%v #%v \nexp: >%v< \ngot: >%v<"
+
+	privKey = "AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA="
+	pubKey  = "B6N8vBQgk8i3VdwbEOhstCY3StFqqFPtC9/AsrhtHHw="
+)
+
+func rules(issues []Issue) []string {
+	var ids []string
+	for _, i := range issues {
+		ids = append(ids, i.Rule)
+	}
+	return ids
+}
+
+// Conf -> rule ids
+func TestLint(t *testing.T) {
+	cases := []struct {
+		C     wg.Conf
+		Rules []string
+	}{
+		{
+			wg.Conf{
+				Interface: wg.Interface{ListenPort: 51820, PrivateKey: privKey},
+				Peers: []wg.Peer{
+					{PublicKey: "pubkey_a", AllowedIPs: []string{"10.0.0.2/32"}},
+				},
+			},
+			nil,
+		}, {
+			wg.Conf{},
+			[]string{"WG001"},
+		}, {
+			wg.Conf{
+				Interface: wg.Interface{ListenPort: 500, PrivateKey: privKey},
+				Peers: []wg.Peer{
+					{PublicKey: "pubkey_a", PresharedKey: "psk", AllowedIPs: []string{"10.0.0.2/32"}},
+					{PublicKey: "pubkey_a", AllowedIPs: []string{"10.0.0.2/32"}, Endpoint: "vpn.example.com"},
+					{PublicKey: pubKey, PresharedKey: "psk"},
+				},
+			},
+			[]string{"WG002", "WG003", "WG005", "WG006", "WG007", "WG009"},
+		}, {
+			wg.Conf{
+				Interface: wg.Interface{PrivateKey: privKey},
+				Peers: []wg.Peer{
+					{PublicKey: "pubkey_a", Endpoint: "vpn.example.com:51820"},
+					{PublicKey: "pubkey_b", Endpoint: "vpn.example.com:51820", PersistentKeepalive: 25},
+				},
+			},
+			[]string{"WG004"},
+		},
+	}
+	for i, c := range cases {
+		issues := Lint(c.C)
+		if ids := rules(issues); !reflect.DeepEqual(ids, c.Rules) {
+			t.Errorf(sf, "Lint", i, c.Rules, issues)
+		}
+	}
+}
+
+func TestLintFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wg-lint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		Mode  os.FileMode
+		Rules []string
+	}{
+		{0600, nil},
+		{0644, []string{"WG008"}},
+	}
+	for i, c := range cases {
+		fpath := filepath.Join(dir, "wg0.conf")
+		err := ioutil.WriteFile(fpath, wg.Conf{Interface: wg.Interface{PrivateKey: privKey}}.Bytes(), c.Mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.Chmod(fpath, c.Mode)
+
+		issues, err := LintFile(fpath)
+		if err != nil {
+			t.Errorf(se, "LintFile", i, err)
+			continue
+		}
+		if ids := rules(issues); !reflect.DeepEqual(ids, c.Rules) {
+			t.Errorf(sf, "LintFile", i, c.Rules, issues)
+		}
+		for _, issue := range issues {
+			if issue.Path != fpath {
+				t.Errorf(sf, "LintFile path", i, fpath, issue.Path)
+			}
+		}
+	}
+}
+
+func TestCustomRule(t *testing.T) {
+	l := Linter{Rules: append([]Rule{{
+		ID: "X001", Name: "no-fwmark", Severity: Note,
+		Check: func(in Input) []Issue {
+			if in.Conf.FwMark == "" {
+				return []Issue{{Message: "no fwmark"}}
+			}
+			return nil
+		},
+	}}, DefaultRules...)}
+	issues := l.Lint(Input{Conf: wg.Conf{}})
+	exp := []Issue{
+		{Rule: "X001", Severity: Note, Message: "no fwmark"},
+		{Rule: "WG001", Severity: Error, Message: "interface has no PrivateKey"},
+	}
+	if !reflect.DeepEqual(issues, exp) {
+		t.Errorf(sf, "Linter.Lint", 0, exp, issues)
+	}
+}
+
+func TestOutput(t *testing.T) {
+	issues := []Issue{{Rule: "WG001", Severity: Error, Message: "interface has no PrivateKey", Path: "wg0.conf"}}
+
+	b, err := JSON(issues)
+	if err != nil {
+		t.Fatalf(se, "JSON", 0, err)
+	}
+	var decoded []Issue
+	json.Unmarshal(b, &decoded)
+	if !reflect.DeepEqual(decoded, issues) {
+		t.Errorf(sf, "JSON", 0, issues, decoded)
+	}
+
+	b, err = Default.SARIF(issues)
+	if err != nil {
+		t.Fatalf(se, "SARIF", 0, err)
+	}
+	var sarif struct {
+		Version string
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct{ ID string }
+				}
+			}
+			Results []struct {
+				RuleID    string
+				Level     string
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct{ URI string }
+					}
+				}
+			}
+		}
+	}
+	err = json.Unmarshal(b, &sarif)
+	if err != nil {
+		t.Fatalf(se, "SARIF decode", 0, err)
+	}
+	if sarif.Version != "2.1.0" || len(sarif.Runs) != 1 || len(sarif.Runs[0].Tool.Driver.Rules) != len(DefaultRules) {
+		t.Errorf(sf, "SARIF", 0, "2.1.0 log with 1 run", string(b))
+	}
+	res := sarif.Runs[0].Results
+	if len(res) != 1 || res[0].RuleID != "WG001" || res[0].Level != "error" || res[0].Locations[0].PhysicalLocation.ArtifactLocation.URI != "wg0.conf" {
+		t.Errorf(sf, "SARIF results", 0, issues, string(b))
+	}
+}
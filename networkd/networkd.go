@@ -0,0 +1,206 @@
+// Package networkd converts between Conf and systemd-networkd .netdev / .network units
+// see systemd.netdev(5) and systemd.network(5)
+package networkd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	wg "seankhliao.com/go-wg"
+)
+
+// Export converts a Conf into .netdev and .network units for interface name,
+// Address and DNS become [Network] settings and routes (ip/mask) become [Route] sections
+func Export(name string, c wg.Conf, routes []string) (netdev, network []byte) {
+	buf := bytes.NewBufferString("[NetDev]\n")
+	buf.WriteString("Name=" + name + "\n")
+	buf.WriteString("Kind=wireguard\n")
+	buf.WriteString("\n[WireGuard]\n")
+	if c.PrivateKey != "" {
+		buf.WriteString("PrivateKey=" + c.PrivateKey + "\n")
+	}
+	if c.ListenPort != 0 {
+		buf.WriteString("ListenPort=" + strconv.Itoa(c.ListenPort) + "\n")
+	}
+	if c.FwMark != "" {
+		buf.WriteString("FirewallMark=" + c.FwMark + "\n")
+	}
+	for _, p := range c.Peers {
+		buf.WriteString("\n[WireGuardPeer]\n")
+		if p.PublicKey != "" {
+			buf.WriteString("PublicKey=" + p.PublicKey + "\n")
+		}
+		if p.PresharedKey != "" {
+			buf.WriteString("PresharedKey=" + p.PresharedKey + "\n")
+		}
+		if len(p.AllowedIPs) != 0 {
+			buf.WriteString("AllowedIPs=" + strings.Join(p.AllowedIPs, ",") + "\n")
+		}
+		if p.Endpoint != "" {
+			buf.WriteString("Endpoint=" + p.Endpoint + "\n")
+		}
+		if p.PersistentKeepalive != 0 {
+			buf.WriteString("PersistentKeepalive=" + strconv.Itoa(p.PersistentKeepalive) + "\n")
+		}
+	}
+	netdev = buf.Bytes()
+
+	buf = bytes.NewBufferString("[Match]\n")
+	buf.WriteString("Name=" + name + "\n")
+	buf.WriteString("\n[Network]\n")
+	for _, a := range c.Address {
+		buf.WriteString("Address=" + a + "\n")
+	}
+	var domains []string
+	for _, d := range c.DNS {
+		if _, err := netip.ParseAddr(d); err == nil {
+			buf.WriteString("DNS=" + d + "\n")
+		} else {
+			domains = append(domains, d)
+		}
+	}
+	if len(domains) != 0 {
+		buf.WriteString("Domains=" + strings.Join(domains, " ") + "\n")
+	}
+	for _, r := range routes {
+		buf.WriteString("\n[Route]\n")
+		buf.WriteString("Destination=" + r + "\n")
+	}
+	network = buf.Bytes()
+	return netdev, network
+}
+
+// section is a [Section] of a unit file
+type section struct {
+	name string
+	keys [][2]string
+}
+
+// parseUnit splits a unit file into sections,
+// # and ; start comments
+func parseUnit(b []byte) ([]section, error) {
+	var sections []section
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == "", strings.HasPrefix(line, "#"), strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			sections = append(sections, section{name: line[1 : len(line)-1]})
+		default:
+			words := strings.SplitN(line, "=", 2)
+			if len(words) != 2 {
+				return nil, fmt.Errorf("invalid line: %v", line)
+			}
+			if len(sections) == 0 {
+				return nil, fmt.Errorf("key outside section: %v", line)
+			}
+			s := &sections[len(sections)-1]
+			s.keys = append(s.keys, [2]string{strings.TrimSpace(words[0]), strings.TrimSpace(words[1])})
+		}
+	}
+	return sections, sc.Err()
+}
+
+// splitList splits a comma or space separated list
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+}
+
+// Import converts .netdev and .network units back into a Conf,
+// network may be nil,
+// returns the interface name and [Route] destinations
+func Import(netdev, network []byte) (name string, c wg.Conf, routes []string, err error) {
+	sections, err := parseUnit(netdev)
+	if err != nil {
+		return "", c, nil, fmt.Errorf("import netdev: %v", err)
+	}
+	for _, s := range sections {
+		switch s.name {
+		case "NetDev":
+			for _, kv := range s.keys {
+				switch kv[0] {
+				case "Name":
+					name = kv[1]
+				case "Kind":
+					if kv[1] != "wireguard" {
+						return "", c, nil, fmt.Errorf("import netdev: Kind %v is not wireguard", kv[1])
+					}
+				}
+			}
+		case "WireGuard":
+			for _, kv := range s.keys {
+				switch kv[0] {
+				case "PrivateKey":
+					c.PrivateKey = kv[1]
+				case "ListenPort":
+					if kv[1] == "auto" {
+						continue
+					}
+					c.ListenPort, err = strconv.Atoi(kv[1])
+					if err != nil {
+						return "", c, nil, fmt.Errorf("import netdev: ListenPort: %v", err)
+					}
+				case "FirewallMark", "FwMark":
+					c.FwMark = kv[1]
+				default:
+					return "", c, nil, fmt.Errorf("import netdev: unsupported key %v", kv[0])
+				}
+			}
+		case "WireGuardPeer":
+			var p wg.Peer
+			for _, kv := range s.keys {
+				switch kv[0] {
+				case "PublicKey":
+					p.PublicKey = kv[1]
+				case "PresharedKey":
+					p.PresharedKey = kv[1]
+				case "AllowedIPs":
+					p.AllowedIPs = append(p.AllowedIPs, splitList(kv[1])...)
+				case "Endpoint":
+					p.Endpoint = kv[1]
+				case "PersistentKeepalive":
+					if kv[1] == "off" {
+						continue
+					}
+					p.PersistentKeepalive, err = strconv.Atoi(kv[1])
+					if err != nil {
+						return "", c, nil, fmt.Errorf("import netdev: PersistentKeepalive: %v", err)
+					}
+				default:
+					return "", c, nil, fmt.Errorf("import netdev: unsupported key %v", kv[0])
+				}
+			}
+			c.Peers = append(c.Peers, p)
+		}
+	}
+
+	sections, err = parseUnit(network)
+	if err != nil {
+		return "", c, nil, fmt.Errorf("import network: %v", err)
+	}
+	for _, s := range sections {
+		switch s.name {
+		case "Network":
+			for _, kv := range s.keys {
+				switch kv[0] {
+				case "Address":
+					c.Address = append(c.Address, splitList(kv[1])...)
+				case "DNS", "Domains":
+					c.DNS = append(c.DNS, splitList(kv[1])...)
+				}
+			}
+		case "Route":
+			for _, kv := range s.keys {
+				if kv[0] == "Destination" {
+					routes = append(routes, kv[1])
+				}
+			}
+		}
+	}
+	return name, c, routes, nil
+}
@@ -0,0 +1,109 @@
+package networkd
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	wg "seankhliao.com/go-wg"
+)
+
+var (
+	update = flag.Bool("update", false, "update golden files")
+
+	se = "%v #%v errored: %v"
+	sf = "%v #%v \nexp: >%v< \ngot: >%v<"
+)
+
+func golden(t *testing.T, name string, b []byte) {
+	fpath := filepath.Join("testdata", name)
+	if *update {
+		err := ioutil.WriteFile(fpath, b, 0644)
+		if err != nil {
+			t.Fatalf("update %v: %v", fpath, err)
+		}
+	}
+	exp, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		t.Fatalf("read %v: %v", fpath, err)
+	}
+	if string(exp) != string(b) {
+		t.Errorf(sf, name, 0, string(exp), string(b))
+	}
+}
+
+// Conf -> units -> Conf
+func TestExportImport(t *testing.T) {
+	cases := []struct {
+		Name   string
+		C      wg.Conf
+		Routes []string
+	}{
+		{
+			"server",
+			wg.Conf{
+				Interface: wg.Interface{
+					ListenPort: 51820,
+					FwMark:     "0xca6c",
+					PrivateKey: "this_is_a_private_key",
+					Address:    []string{"10.0.0.1/24", "fd00::1/64"},
+				},
+				Peers: []wg.Peer{
+					{PublicKey: "pubkey_a", PresharedKey: "psk_a", AllowedIPs: []string{"10.0.0.2/32", "fd00::2/128"}},
+					{PublicKey: "pubkey_b", AllowedIPs: []string{"10.0.0.3/32", "192.168.1.0/24"}, Endpoint: "1.2.3.4:51820", PersistentKeepalive: 25},
+				},
+			},
+			[]string{"192.168.1.0/24"},
+		}, {
+			"client",
+			wg.Conf{
+				Interface: wg.Interface{
+					PrivateKey: "this_is_a_private_key",
+					Address:    []string{"10.0.0.2/32"},
+					DNS:        []string{"10.0.0.1", "example.com", "corp.example.com"},
+				},
+				Peers: []wg.Peer{
+					{PublicKey: "pubkey_server", AllowedIPs: []string{"0.0.0.0/0", "::/0"}, Endpoint: "vpn.example.com:51820", PersistentKeepalive: 25},
+				},
+			},
+			nil,
+		},
+	}
+	for i, c := range cases {
+		netdev, network := Export("wg0", c.C, c.Routes)
+		golden(t, c.Name+".netdev", netdev)
+		golden(t, c.Name+".network", network)
+
+		name, conf, routes, err := Import(netdev, network)
+		if err != nil {
+			t.Errorf(se, "Import", i, err)
+			continue
+		}
+		if name != "wg0" {
+			t.Errorf(sf, "Import name", i, "wg0", name)
+		}
+		if !reflect.DeepEqual(conf, c.C) {
+			t.Errorf(sf, "Import conf", i, c.C, conf)
+		}
+		if !reflect.DeepEqual(routes, c.Routes) {
+			t.Errorf(sf, "Import routes", i, c.Routes, routes)
+		}
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	cases := []string{
+		"[NetDev]\nName=wg0\nKind=bridge\n",
+		"Name=wg0\n",
+		"[WireGuard]\nPrivateKeyFile=/etc/wg.key\n",
+		"[WireGuard]\nListenPort=port\n",
+	}
+	for i, c := range cases {
+		_, _, _, err := Import([]byte(c), nil)
+		if err == nil {
+			t.Errorf(se, "Import", i, "expected error")
+		}
+	}
+}
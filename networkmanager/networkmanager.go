@@ -0,0 +1,202 @@
+// Package networkmanager converts between Conf and NetworkManager keyfiles (.nmconnection)
+// see nm-settings-keyfile(5)
+package networkmanager
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	wg "seankhliao.com/go-wg"
+)
+
+// Export converts a Conf into a keyfile for connection / interface name,
+// Address and DNS become ipv4 / ipv6 settings
+func Export(name string, c wg.Conf) ([]byte, error) {
+	buf := bytes.NewBufferString("[connection]\n")
+	buf.WriteString("id=" + name + "\n")
+	buf.WriteString("type=wireguard\n")
+	buf.WriteString("interface-name=" + name + "\n")
+
+	buf.WriteString("\n[wireguard]\n")
+	if c.FwMark != "" {
+		// keyfiles only take decimal
+		mark, err := strconv.ParseUint(c.FwMark, 0, 32)
+		if err != nil {
+			return nil, fmt.Errorf("export: FwMark: %v", err)
+		}
+		buf.WriteString("fwmark=" + strconv.FormatUint(mark, 10) + "\n")
+	}
+	if c.ListenPort != 0 {
+		buf.WriteString("listen-port=" + strconv.Itoa(c.ListenPort) + "\n")
+	}
+	if c.PrivateKey != "" {
+		buf.WriteString("private-key=" + c.PrivateKey + "\n")
+	}
+
+	for _, p := range c.Peers {
+		buf.WriteString("\n[wireguard-peer." + p.PublicKey + "]\n")
+		if p.Endpoint != "" {
+			buf.WriteString("endpoint=" + p.Endpoint + "\n")
+		}
+		if p.PresharedKey != "" {
+			buf.WriteString("preshared-key=" + p.PresharedKey + "\n")
+			buf.WriteString("preshared-key-flags=0\n")
+		}
+		if p.PersistentKeepalive != 0 {
+			buf.WriteString("persistent-keepalive=" + strconv.Itoa(p.PersistentKeepalive) + "\n")
+		}
+		if len(p.AllowedIPs) != 0 {
+			buf.WriteString("allowed-ips=" + strings.Join(p.AllowedIPs, ";") + ";\n")
+		}
+	}
+
+	var addr4, addr6, dns4, dns6, search []string
+	for _, a := range c.Address {
+		prefix, err := netip.ParsePrefix(a)
+		if err != nil {
+			return nil, fmt.Errorf("export: Address: %v", err)
+		}
+		if prefix.Addr().Is4() {
+			addr4 = append(addr4, a)
+		} else {
+			addr6 = append(addr6, a)
+		}
+	}
+	for _, d := range c.DNS {
+		addr, err := netip.ParseAddr(d)
+		switch {
+		case err != nil:
+			search = append(search, d)
+		case addr.Is4():
+			dns4 = append(dns4, d)
+		default:
+			dns6 = append(dns6, d)
+		}
+	}
+	writeIP(buf, "ipv4", addr4, dns4, search)
+	writeIP(buf, "ipv6", addr6, dns6, nil)
+	return buf.Bytes(), nil
+}
+
+func writeIP(buf *bytes.Buffer, section string, addrs, dns, search []string) {
+	buf.WriteString("\n[" + section + "]\n")
+	for i, a := range addrs {
+		buf.WriteString("address" + strconv.Itoa(i+1) + "=" + a + "\n")
+	}
+	if len(dns) != 0 {
+		buf.WriteString("dns=" + strings.Join(dns, ";") + ";\n")
+	}
+	if len(search) != 0 {
+		buf.WriteString("dns-search=" + strings.Join(search, ";") + ";\n")
+	}
+	if len(addrs) != 0 {
+		buf.WriteString("method=manual\n")
+	} else {
+		buf.WriteString("method=disabled\n")
+	}
+}
+
+// splitList splits a ; separated list
+func splitList(s string) []string {
+	var l []string
+	for _, v := range strings.Split(s, ";") {
+		if v = strings.TrimSpace(v); v != "" {
+			l = append(l, v)
+		}
+	}
+	return l
+}
+
+// Import converts a keyfile back into a Conf,
+// returns the interface name
+func Import(b []byte) (name string, c wg.Conf, err error) {
+	var section string
+	var peer *wg.Peer
+	var dns4, dns6, search []string
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = line[1 : len(line)-1]
+			peer = nil
+			if strings.HasPrefix(section, "wireguard-peer.") {
+				c.Peers = append(c.Peers, wg.Peer{PublicKey: strings.TrimPrefix(section, "wireguard-peer.")})
+				peer = &c.Peers[len(c.Peers)-1]
+			}
+			continue
+		}
+		words := strings.SplitN(line, "=", 2)
+		if len(words) != 2 {
+			return "", c, fmt.Errorf("import: invalid line %v", line)
+		}
+		key, value := strings.TrimSpace(words[0]), strings.TrimSpace(words[1])
+
+		switch {
+		case section == "connection":
+			switch key {
+			case "interface-name":
+				name = value
+			case "type":
+				if value != "wireguard" {
+					return "", c, fmt.Errorf("import: type %v is not wireguard", value)
+				}
+			}
+		case section == "wireguard":
+			switch key {
+			case "fwmark":
+				mark, err := strconv.ParseUint(value, 10, 32)
+				if err != nil {
+					return "", c, fmt.Errorf("import: fwmark: %v", err)
+				}
+				if mark != 0 {
+					c.FwMark = "0x" + strconv.FormatUint(mark, 16)
+				}
+			case "listen-port":
+				c.ListenPort, err = strconv.Atoi(value)
+				if err != nil {
+					return "", c, fmt.Errorf("import: listen-port: %v", err)
+				}
+			case "private-key":
+				c.PrivateKey = value
+			}
+		case peer != nil:
+			switch key {
+			case "endpoint":
+				peer.Endpoint = value
+			case "preshared-key":
+				peer.PresharedKey = value
+			case "persistent-keepalive":
+				peer.PersistentKeepalive, err = strconv.Atoi(value)
+				if err != nil {
+					return "", c, fmt.Errorf("import: persistent-keepalive: %v", err)
+				}
+			case "allowed-ips":
+				peer.AllowedIPs = append(peer.AllowedIPs, splitList(value)...)
+			}
+		case section == "ipv4" || section == "ipv6":
+			switch {
+			case strings.HasPrefix(key, "address"):
+				// address1=ip/mask,gateway
+				c.Address = append(c.Address, strings.SplitN(value, ",", 2)[0])
+			case key == "dns" && section == "ipv4":
+				dns4 = append(dns4, splitList(value)...)
+			case key == "dns":
+				dns6 = append(dns6, splitList(value)...)
+			case key == "dns-search":
+				search = append(search, splitList(value)...)
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return "", c, fmt.Errorf("import: %v", err)
+	}
+	c.DNS = append(append(append(c.DNS, dns4...), dns6...), search...)
+	return name, c, nil
+}
@@ -0,0 +1,113 @@
+package networkmanager
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	wg "seankhliao.com/go-wg"
+)
+
+var (
+	update = flag.Bool("update", false, "update golden files")
+
+	se = "%v #%v errored: %v"
+	sf = "%v #%v \nexp: >%v< \ngot: >%v<"
+)
+
+func golden(t *testing.T, name string, b []byte) {
+	fpath := filepath.Join("testdata", name)
+	if *update {
+		err := ioutil.WriteFile(fpath, b, 0644)
+		if err != nil {
+			t.Fatalf("update %v: %v", fpath, err)
+		}
+	}
+	exp, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		t.Fatalf("read %v: %v", fpath, err)
+	}
+	if string(exp) != string(b) {
+		t.Errorf(sf, name, 0, string(exp), string(b))
+	}
+}
+
+// Conf -> keyfile -> Conf
+func TestExportImport(t *testing.T) {
+	cases := []struct {
+		Name string
+		C    wg.Conf
+	}{
+		{
+			"laptop",
+			wg.Conf{
+				Interface: wg.Interface{
+					PrivateKey: "this_is_a_private_key",
+					Address:    []string{"10.0.0.2/32", "fd00::2/128"},
+					DNS:        []string{"10.0.0.1", "fd00::1", "corp.example.com"},
+				},
+				Peers: []wg.Peer{
+					{
+						PublicKey:           "pubkey_server",
+						PresharedKey:        "psk",
+						AllowedIPs:          []string{"10.0.0.0/24", "fd00::/64", "192.168.0.0/24"},
+						Endpoint:            "vpn.example.com:51820",
+						PersistentKeepalive: 25,
+					},
+				},
+			},
+		}, {
+			"server",
+			wg.Conf{
+				Interface: wg.Interface{
+					ListenPort: 51820,
+					FwMark:     "0xca6c",
+					PrivateKey: "this_is_a_private_key",
+					Address:    []string{"10.0.0.1/24"},
+				},
+				Peers: []wg.Peer{
+					{PublicKey: "pubkey_a", AllowedIPs: []string{"10.0.0.2/32"}},
+					{PublicKey: "pubkey_b", AllowedIPs: []string{"10.0.0.3/32"}},
+				},
+			},
+		},
+	}
+	for i, c := range cases {
+		b, err := Export("wg0", c.C)
+		if err != nil {
+			t.Errorf(se, "Export", i, err)
+			continue
+		}
+		golden(t, c.Name+".nmconnection", b)
+
+		name, conf, err := Import(b)
+		if err != nil {
+			t.Errorf(se, "Import", i, err)
+			continue
+		}
+		if name != "wg0" {
+			t.Errorf(sf, "Import name", i, "wg0", name)
+		}
+		if !reflect.DeepEqual(conf, c.C) {
+			t.Errorf(sf, "Import conf", i, c.C, conf)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	if _, err := Export("wg0", wg.Conf{Interface: wg.Interface{FwMark: "mark"}}); err == nil {
+		t.Errorf(se, "Export", 0, "expected error")
+	}
+	cases := []string{
+		"[connection]\ntype=vpn\n",
+		"[wireguard]\nlisten-port=port\n",
+		"[wireguard]\nnot a key value\n",
+	}
+	for i, c := range cases {
+		if _, _, err := Import([]byte(c)); err == nil {
+			t.Errorf(se, "Import", i, "expected error")
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Package provision generates configs for new (road warrior) clients of a server
+package provision
+
+import (
+	"fmt"
+	"net/netip"
+
+	wg "seankhliao.com/go-wg"
+	"seankhliao.com/go-wg/ipam"
+)
+
+// DefaultKeepalive is the PersistentKeepalive for clients, which are assumed to be behind NAT
+const DefaultKeepalive = 25
+
+// Params are options for GenerateClientConf
+type Params struct {
+	// IPAM allocates the client's tunnel addresses,
+	// the server's own Address is reserved in it,
+	// ignored if Address is set
+	IPAM    *ipam.IPAM
+	Address []string // ip/mask, use instead of allocating
+
+	DNS []string // client DNS servers and search domains
+
+	// FullTunnel routes all client traffic through the server,
+	// otherwise only the IPAM prefixes, the prefixes of the server Address
+	// and AllowedIPs are routed (split tunnel)
+	FullTunnel bool
+	AllowedIPs []string // extra routes for split tunnel, eg networks behind the server
+
+	PersistentKeepalive int  // defaults to DefaultKeepalive, -1 to disable
+	PresharedKey        bool // generate a preshared key
+}
+
+// GenerateClientConf creates a keypair and addresses for a new client,
+// adds the client as a peer to server,
+// and returns the client's wg-quick config pointing at serverEndpoint (host:port)
+func GenerateClientConf(server *wg.Conf, serverEndpoint string, params Params) (wg.Conf, error) {
+	serverPub := server.PublicKey
+	if serverPub == "" {
+		var err error
+		serverPub, err = wg.PublicKeyFor(server.PrivateKey)
+		if err != nil {
+			return wg.Conf{}, fmt.Errorf("generate client: server key: %v", err)
+		}
+	}
+
+	priv, err := wg.NewPrivateKey()
+	if err != nil {
+		return wg.Conf{}, fmt.Errorf("generate client: %v", err)
+	}
+	pub, err := wg.PublicKeyFor(priv)
+	if err != nil {
+		return wg.Conf{}, fmt.Errorf("generate client: %v", err)
+	}
+	var psk string
+	if params.PresharedKey {
+		psk, err = wg.NewPresharedKey()
+		if err != nil {
+			return wg.Conf{}, fmt.Errorf("generate client: %v", err)
+		}
+	}
+
+	addrs := params.Address
+	if len(addrs) == 0 {
+		if params.IPAM == nil {
+			return wg.Conf{}, fmt.Errorf("generate client: no Address or IPAM")
+		}
+		err = params.IPAM.Load(*server)
+		if err != nil {
+			return wg.Conf{}, fmt.Errorf("generate client: %v", err)
+		}
+		err = reserve(params.IPAM, server.Address)
+		if err != nil {
+			return wg.Conf{}, fmt.Errorf("generate client: server Address: %v", err)
+		}
+		addrs, err = params.IPAM.Allocate()
+		if err != nil {
+			return wg.Conf{}, fmt.Errorf("generate client: %w", err)
+		}
+	}
+
+	// server side routes to the client's host addresses
+	var hosts []string
+	for _, a := range addrs {
+		prefix, err := netip.ParsePrefix(a)
+		if err != nil {
+			return wg.Conf{}, fmt.Errorf("generate client: %v", err)
+		}
+		hosts = append(hosts, netip.PrefixFrom(prefix.Addr(), prefix.Addr().BitLen()).String())
+	}
+
+	var routes []string
+	if params.FullTunnel {
+		routes = []string{"0.0.0.0/0", "::/0"}
+	} else {
+		if params.IPAM != nil {
+			routes = params.IPAM.Prefixes()
+		}
+		for _, a := range server.Address {
+			prefix, err := netip.ParsePrefix(a)
+			if err != nil {
+				return wg.Conf{}, fmt.Errorf("generate client: server Address: %v", err)
+			}
+			routes = appendNew(routes, prefix.Masked().String())
+		}
+		for _, r := range params.AllowedIPs {
+			routes = appendNew(routes, r)
+		}
+	}
+
+	keepalive := params.PersistentKeepalive
+	switch keepalive {
+	case 0:
+		keepalive = DefaultKeepalive
+	case -1:
+		keepalive = 0
+	}
+
+	server.Peers = append(server.Peers, wg.Peer{
+		PublicKey:    pub,
+		PresharedKey: psk,
+		AllowedIPs:   hosts,
+	})
+
+	return wg.Conf{
+		Interface: wg.Interface{
+			PrivateKey: priv,
+			Address:    addrs,
+			DNS:        params.DNS,
+		},
+		Peers: []wg.Peer{
+			{
+				PublicKey:           serverPub,
+				PresharedKey:        psk,
+				AllowedIPs:          routes,
+				Endpoint:            serverEndpoint,
+				PersistentKeepalive: keepalive,
+			},
+		},
+	}, nil
+}
+
+// reserve reserves the addresses (ip/mask) that fall within the prefixes of p
+func reserve(p *ipam.IPAM, addrs []string) error {
+	var prefixes []netip.Prefix
+	for _, s := range p.Prefixes() {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return err
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	for _, a := range addrs {
+		prefix, err := netip.ParsePrefix(a)
+		if err != nil {
+			return err
+		}
+		for _, pool := range prefixes {
+			if pool.Contains(prefix.Addr()) {
+				err = p.Reserve(prefix.Addr().String())
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// appendNew appends s to ss if it isn't already in it
+func appendNew(ss []string, s string) []string {
+	for _, o := range ss {
+		if o == s {
+			return ss
+		}
+	}
+	return append(ss, s)
+}
@@ -0,0 +1,139 @@
+package provision
+
+import (
+	"reflect"
+	"testing"
+
+	wg "seankhliao.com/go-wg"
+	"seankhliao.com/go-wg/ipam"
+)
+
+var (
+	se = "%v #%v errored: %v"
+	sf = "%v #%v \nexp: >%v< \ngot: >%v<"
+
+	serverPriv = "AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA="
+	serverPub  = "B6N8vBQgk8i3VdwbEOhstCY3StFqqFPtC9/AsrhtHHw="
+)
+
+func TestGenerateClientConf(t *testing.T) {
+	cases := []struct {
+		P       func() Params
+		Server  []string // server Address
+		Address []string
+		Routes  []string
+		Hosts   []string
+		PKA     int
+		PSK     bool
+	}{
+		{
+			func() Params {
+				p, _ := ipam.New("10.0.0.0/24", "fd00::/64")
+				p.Reserve("10.0.0.1", "fd00::1")
+				return Params{IPAM: p, DNS: []string{"10.0.0.1"}, AllowedIPs: []string{"192.168.0.0/24"}}
+			},
+			nil,
+			[]string{"10.0.0.3/32", "fd00::2/128"},
+			[]string{"10.0.0.0/24", "fd00::/64", "192.168.0.0/24"},
+			[]string{"10.0.0.3/32", "fd00::2/128"},
+			DefaultKeepalive,
+			false,
+		}, {
+			func() Params {
+				return Params{Address: []string{"10.1.0.5/24"}, FullTunnel: true, PersistentKeepalive: -1, PresharedKey: true}
+			},
+			nil,
+			[]string{"10.1.0.5/24"},
+			[]string{"0.0.0.0/0", "::/0"},
+			[]string{"10.1.0.5/32"},
+			0,
+			true,
+		}, {
+			// server Address is reserved and routed
+			func() Params {
+				p, _ := ipam.New("10.0.0.0/24")
+				return Params{IPAM: p}
+			},
+			[]string{"10.0.0.1/24", "fd00::1/64"},
+			[]string{"10.0.0.3/32"},
+			[]string{"10.0.0.0/24", "fd00::/64"},
+			[]string{"10.0.0.3/32"},
+			DefaultKeepalive,
+			false,
+		}, {
+			// explicit Address still routes the server prefixes
+			func() Params {
+				return Params{Address: []string{"10.0.0.9/24"}, AllowedIPs: []string{"192.168.0.0/24"}}
+			},
+			[]string{"10.0.0.1/24"},
+			[]string{"10.0.0.9/24"},
+			[]string{"10.0.0.0/24", "192.168.0.0/24"},
+			[]string{"10.0.0.9/32"},
+			DefaultKeepalive,
+			false,
+		},
+	}
+	for i, c := range cases {
+		server := wg.Conf{
+			Interface: wg.Interface{ListenPort: 51820, PrivateKey: serverPriv, Address: c.Server},
+			Peers:     []wg.Peer{{PublicKey: "existing", AllowedIPs: []string{"10.0.0.2/32"}}},
+		}
+		params := c.P()
+		client, err := GenerateClientConf(&server, "vpn.example.com:51820", params)
+		if err != nil {
+			t.Errorf(se, "GenerateClientConf", i, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(client.Address, c.Address) {
+			t.Errorf(sf, "client Address", i, c.Address, client.Address)
+		}
+		if !reflect.DeepEqual(client.DNS, params.DNS) {
+			t.Errorf(sf, "client DNS", i, params.DNS, client.DNS)
+		}
+		if len(client.Peers) != 1 {
+			t.Errorf(sf, "client Peers", i, 1, len(client.Peers))
+			continue
+		}
+		sp := client.Peers[0]
+		exp := wg.Peer{
+			PublicKey:           serverPub,
+			PresharedKey:        sp.PresharedKey,
+			AllowedIPs:          c.Routes,
+			Endpoint:            "vpn.example.com:51820",
+			PersistentKeepalive: c.PKA,
+		}
+		if !reflect.DeepEqual(sp, exp) {
+			t.Errorf(sf, "client Peer", i, exp, sp)
+		}
+		if (sp.PresharedKey != "") != c.PSK {
+			t.Errorf(sf, "client PresharedKey", i, c.PSK, sp.PresharedKey)
+		}
+
+		if len(server.Peers) != 2 {
+			t.Errorf(sf, "server Peers", i, 2, len(server.Peers))
+			continue
+		}
+		clientPub, _ := wg.PublicKeyFor(client.PrivateKey)
+		expPeer := wg.Peer{PublicKey: clientPub, PresharedKey: sp.PresharedKey, AllowedIPs: c.Hosts}
+		if !reflect.DeepEqual(server.Peers[1], expPeer) {
+			t.Errorf(sf, "server Peer", i, expPeer, server.Peers[1])
+		}
+	}
+}
+
+func TestGenerateClientConfErrors(t *testing.T) {
+	server := wg.Conf{}
+	_, err := GenerateClientConf(&server, "vpn.example.com:51820", Params{Address: []string{"10.0.0.2/32"}})
+	if err == nil {
+		t.Errorf(se, "GenerateClientConf no server key", 0, "expected error")
+	}
+	server.PrivateKey = serverPriv
+	_, err = GenerateClientConf(&server, "vpn.example.com:51820", Params{})
+	if err == nil {
+		t.Errorf(se, "GenerateClientConf no address", 0, "expected error")
+	}
+	if len(server.Peers) != 0 {
+		t.Errorf(sf, "GenerateClientConf server unchanged", 0, 0, len(server.Peers))
+	}
+}
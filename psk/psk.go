@@ -0,0 +1,121 @@
+// Package psk rolls out preshared keys to the peers of an interface:
+// unique keys are generated per peer, the server side is applied in a single Set
+// and each client gets a snippet to merge into its config
+package psk
+
+import (
+	"context"
+	"fmt"
+
+	wg "seankhliao.com/go-wg"
+)
+
+// Key is a new preshared key for a peer
+type Key struct {
+	Peer         string // public key
+	PresharedKey string
+}
+
+// Rollout is a set of new preshared keys for peers of an interface
+type Rollout struct {
+	Interface string
+	PublicKey string // of the interface, for client snippets
+	Keys      []Key  // in the order of the interface peers
+}
+
+// Plan generates unique keys for the peers of c,
+// peers that already have a preshared key are skipped unless replace is set
+func Plan(iface string, c wg.Conf, replace bool) (Rollout, error) {
+	r := Rollout{Interface: iface, PublicKey: c.PublicKey}
+	if r.PublicKey == "" {
+		var err error
+		r.PublicKey, err = wg.PublicKeyFor(c.PrivateKey)
+		if err != nil {
+			return r, fmt.Errorf("plan: interface key: %v", err)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, p := range c.Peers {
+		seen[p.PresharedKey] = true
+	}
+	for _, p := range c.Peers {
+		if p.PresharedKey != "" && !replace {
+			continue
+		}
+		var k string
+		for k == "" || seen[k] {
+			var err error
+			k, err = wg.NewPresharedKey()
+			if err != nil {
+				return r, fmt.Errorf("plan: %v", err)
+			}
+		}
+		seen[k] = true
+		r.Keys = append(r.Keys, Key{Peer: p.PublicKey, PresharedKey: k})
+	}
+	return r, nil
+}
+
+// Opt is the server side of r as a single Set,
+// keyFile writes the keys to files (see wg.KeyFiles)
+func (r Rollout) Opt(keyFile func(key string) (string, error)) (wg.Opt, error) {
+	opt := wg.Opt{Interface: r.Interface}
+	for _, k := range r.Keys {
+		fpath, err := keyFile(k.PresharedKey)
+		if err != nil {
+			return opt, fmt.Errorf("opt: %v", err)
+		}
+		opt.Peers = append(opt.Peers, wg.OptPeer{PublicKey: k.Peer, PskFpath: fpath})
+	}
+	return opt, nil
+}
+
+// Apply sets the server side of r with client
+func (r Rollout) Apply(ctx context.Context, client wg.Client) error {
+	if len(r.Keys) == 0 {
+		return nil
+	}
+	keyFile, cleanup, err := wg.KeyFiles()
+	if err != nil {
+		return fmt.Errorf("apply: %v", err)
+	}
+	defer cleanup()
+	opt, err := r.Opt(keyFile)
+	if err != nil {
+		return fmt.Errorf("apply: %v", err)
+	}
+	err = client.Set(ctx, opt)
+	if err != nil {
+		return fmt.Errorf("apply: %v", err)
+	}
+	return nil
+}
+
+// Snippet is the client side change for peer:
+// the [Peer] section for the interface with the PresharedKey to add to it
+func (r Rollout) Snippet(peer string) ([]byte, error) {
+	for _, k := range r.Keys {
+		if k.Peer == peer {
+			return []byte(fmt.Sprintf("[Peer]\nPublicKey = %v\nPresharedKey = %v\n", r.PublicKey, k.PresharedKey)), nil
+		}
+	}
+	return nil, fmt.Errorf("snippet: no key for peer %v", peer)
+}
+
+// Enable plans and applies preshared keys for the peers of iface,
+// returning the rollout for distributing client snippets
+func Enable(ctx context.Context, client wg.Client, iface string, replace bool) (Rollout, error) {
+	c, err := client.Show(ctx, iface)
+	if err != nil {
+		return Rollout{}, fmt.Errorf("enable psk: %v", err)
+	}
+	r, err := Plan(iface, c, replace)
+	if err != nil {
+		return r, fmt.Errorf("enable psk: %v", err)
+	}
+	err = r.Apply(ctx, client)
+	if err != nil {
+		return r, fmt.Errorf("enable psk: %v", err)
+	}
+	return r, nil
+}
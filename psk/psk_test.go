@@ -0,0 +1,90 @@
+package psk
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	wg "seankhliao.com/go-wg"
+	"seankhliao.com/go-wg/wgtest"
+)
+
+var (
+	se = "%v #%v errored: %v"
+	sf = "%v #%v \nexp: >%v< \ngot: >%v<"
+
+	privA = "AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA="
+	pubA  = "B6N8vBQgk8i3VdwbEOhstCY3StFqqFPtC9/AsrhtHHw="
+	keyC  = "ICEiIyQlJicoKSorLC0uLzAxMjM0NTY3ODk6Ozw9Pj8="
+	keyD  = "QEFCQ0RFRkdISUpLTE1OT1BRUlNUVVZXWFlaW1xdXl8="
+)
+
+func TestEnable(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		Replace bool
+		Peers   []string // peers that get a new key
+	}{
+		{false, []string{keyC, pubA}},
+		{true, []string{keyC, keyD, pubA}},
+	}
+	for i, c := range cases {
+		fake := wgtest.NewClient(map[string]wg.Conf{
+			"wg0": {
+				Interface: wg.Interface{PrivateKey: privA},
+				Peers: []wg.Peer{
+					{PublicKey: keyC},
+					{PublicKey: keyD, PresharedKey: keyC},
+					{PublicKey: pubA},
+				},
+			},
+		})
+		r, err := Enable(ctx, fake, "wg0", c.Replace)
+		if err != nil {
+			t.Errorf(se, "Enable", i, err)
+			continue
+		}
+		var peers []string
+		keys := make(map[string]string)
+		for _, k := range r.Keys {
+			peers = append(peers, k.Peer)
+			keys[k.Peer] = k.PresharedKey
+		}
+		if !reflect.DeepEqual(peers, c.Peers) || len(keys) != len(c.Peers) {
+			t.Errorf(sf, "Enable", i, c.Peers, peers)
+		}
+
+		sets := fake.Sets()
+		if len(sets) != 1 || len(sets[0].Peers) != len(c.Peers) {
+			t.Errorf(sf, "Sets", i, 1, sets)
+		}
+		live, _ := fake.Show(ctx, "wg0")
+		unique := make(map[string]bool)
+		for _, p := range live.Peers {
+			if k, ok := keys[p.PublicKey]; ok && p.PresharedKey != k {
+				t.Errorf(sf, "PresharedKey", i, k, p.PresharedKey)
+			}
+			unique[p.PresharedKey] = true
+		}
+		if len(unique) != len(live.Peers) {
+			t.Errorf(sf, "unique", i, len(live.Peers), len(unique))
+		}
+
+		snippet, err := r.Snippet(keyC)
+		exp := "[Peer]\nPublicKey = " + pubA + "\nPresharedKey = " + keys[keyC] + "\n"
+		if err != nil || string(snippet) != exp {
+			t.Errorf(sf, "Snippet", i, exp, string(snippet))
+		}
+		if _, err = r.Snippet(privA); err == nil {
+			t.Errorf(se, "Snippet", i, "expected error for unknown peer")
+		}
+	}
+
+	fake := wgtest.NewClient(map[string]wg.Conf{"wg0": {Interface: wg.Interface{PrivateKey: privA}}})
+	if r, err := Enable(ctx, fake, "wg0", false); err != nil || len(r.Keys) != 0 || len(fake.Sets()) != 0 {
+		t.Errorf(sf, "Enable", len(cases), 0, fake.Sets())
+	}
+	if _, err := Enable(ctx, fake, "wg1", false); err == nil {
+		t.Errorf(se, "Enable", len(cases)+1, "expected error for unknown interface")
+	}
+}
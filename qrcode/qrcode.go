@@ -0,0 +1,85 @@
+// Package qrcode renders configs as QR codes for mobile clients to scan,
+// same as qrencode -t png|svg|ansiutf8 < wg0.conf
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"rsc.io/qr"
+	wg "seankhliao.com/go-wg"
+)
+
+// quiet is the border around the code, in modules
+const quiet = 4
+
+// Encode encodes a Conf (wg-quick text) as a QR code
+func Encode(c wg.Conf) (*qr.Code, error) {
+	code, err := qr.Encode(string(c.Bytes()), qr.M)
+	if err != nil {
+		return nil, fmt.Errorf("qr encode: %v", err)
+	}
+	return code, nil
+}
+
+// PNG renders a Conf as a PNG image, scale pixels per module
+func PNG(c wg.Conf, scale int) ([]byte, error) {
+	code, err := Encode(c)
+	if err != nil {
+		return nil, err
+	}
+	if scale > 0 {
+		code.Scale = scale
+	}
+	return code.PNG(), nil
+}
+
+// SVG renders a Conf as an SVG image, one unit per module
+func SVG(c wg.Conf) ([]byte, error) {
+	code, err := Encode(c)
+	if err != nil {
+		return nil, err
+	}
+	size := strconv.Itoa(code.Size + 2*quiet)
+	buf := bytes.NewBufferString(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 ` + size + " " + size + `" shape-rendering="crispEdges">` + "\n")
+	buf.WriteString(`<rect width="100%" height="100%" fill="#fff"/>` + "\n")
+	buf.WriteString(`<path fill="#000" d="`)
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; x++ {
+			if code.Black(x, y) {
+				buf.WriteString("M" + strconv.Itoa(x+quiet) + " " + strconv.Itoa(y+quiet) + "h1v1h-1z")
+			}
+		}
+	}
+	buf.WriteString(`"/>` + "\n</svg>\n")
+	return buf.Bytes(), nil
+}
+
+// Terminal renders a Conf for terminals using ANSI colors and half blocks,
+// two rows of modules per line
+func Terminal(c wg.Conf) ([]byte, error) {
+	code, err := Encode(c)
+	if err != nil {
+		return nil, err
+	}
+	const (
+		fgBlack, fgWhite = "30", "97"
+		bgBlack, bgWhite = "40", "107"
+	)
+	buf := &bytes.Buffer{}
+	for y := -quiet; y < code.Size+quiet; y += 2 {
+		for x := -quiet; x < code.Size+quiet; x++ {
+			fg, bg := fgWhite, bgWhite
+			if code.Black(x, y) {
+				fg = fgBlack
+			}
+			if code.Black(x, y+1) {
+				bg = bgBlack
+			}
+			buf.WriteString("\x1b[" + fg + ";" + bg + "m▀")
+		}
+		buf.WriteString("\x1b[0m\n")
+	}
+	return buf.Bytes(), nil
+}
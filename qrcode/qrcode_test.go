@@ -0,0 +1,92 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	wg "seankhliao.com/go-wg"
+)
+
+var (
+	se = "%v #%v errored: %v"
+	sf = "%v #%v \nexp: >%v< \ngot: >%v<"
+)
+
+var client = wg.Conf{
+	Interface: wg.Interface{
+		PrivateKey: "AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA=",
+		Address:    []string{"10.0.0.2/32"},
+		DNS:        []string{"10.0.0.1"},
+	},
+	Peers: []wg.Peer{{
+		PublicKey:           "B6N8vBQgk8i3VdwbEOhstCY3StFqqFPtC9/AsrhtHHw=",
+		AllowedIPs:          []string{"0.0.0.0/0", "::/0"},
+		Endpoint:            "vpn.example.com:51820",
+		PersistentKeepalive: 25,
+	}},
+}
+
+func TestPNG(t *testing.T) {
+	code, err := Encode(client)
+	if err != nil {
+		t.Fatalf(se, "Encode", 0, err)
+	}
+	b, err := PNG(client, 2)
+	if err != nil {
+		t.Fatalf(se, "PNG", 0, err)
+	}
+	img, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf(se, "PNG decode", 0, err)
+	}
+	if size := (code.Size + 2*quiet) * 2; img.Bounds().Dx() != size {
+		t.Fatalf(sf, "PNG size", 0, size, img.Bounds().Dx())
+	}
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; x++ {
+			r, _, _, _ := img.At((x+quiet)*2, (y+quiet)*2).RGBA()
+			if (r == 0) != code.Black(x, y) {
+				t.Fatalf(sf, "PNG pixel", x*1000+y, code.Black(x, y), r)
+			}
+		}
+	}
+}
+
+func TestSVG(t *testing.T) {
+	code, _ := Encode(client)
+	b, err := SVG(client)
+	if err != nil {
+		t.Fatalf(se, "SVG", 0, err)
+	}
+	var black int
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; x++ {
+			if code.Black(x, y) {
+				black++
+			}
+		}
+	}
+	if n := strings.Count(string(b), "h1v1h-1z"); n != black {
+		t.Errorf(sf, "SVG modules", 0, black, n)
+	}
+	if !strings.HasPrefix(string(b), "<svg ") || !strings.HasSuffix(string(b), "</svg>\n") {
+		t.Errorf(sf, "SVG", 0, "<svg>...</svg>", string(b))
+	}
+}
+
+func TestTerminal(t *testing.T) {
+	code, _ := Encode(client)
+	b, err := Terminal(client)
+	if err != nil {
+		t.Fatalf(se, "Terminal", 0, err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	if exp := (code.Size + 2*quiet + 1) / 2; len(lines) != exp {
+		t.Errorf(sf, "Terminal lines", 0, exp, len(lines))
+	}
+	if n := strings.Count(lines[0], "▀"); n != code.Size+2*quiet {
+		t.Errorf(sf, "Terminal columns", 0, code.Size+2*quiet, n)
+	}
+}
@@ -0,0 +1,426 @@
+// Package registry stores managed peers and their metadata in a local directory of JSON files
+// and renders interface configs from them
+//
+// layout:
+//
+//	dir/<iface>/interface.json
+//	dir/<iface>/peers/<name>.json
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	wg "seankhliao.com/go-wg"
+	"seankhliao.com/go-wg/internal/atomicfile"
+)
+
+// ErrNotFound is returned for missing interfaces and peers
+var ErrNotFound = errors.New("not found")
+
+// validName is the allowed form of interface and peer names,
+// they are used as file names
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
+var now = time.Now
+
+// Peer is a managed peer of an interface
+type Peer struct {
+	Name      string    `json:"name"` // unique per interface
+	Interface string    `json:"interface"`
+	Owner     string    `json:"owner,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+	Created   time.Time `json:"created"`
+	Expires   time.Time `json:"expires,omitzero"` // zero never expires
+
+	PublicKey    string `json:"public_key"`
+	PrivateKey   string `json:"private_key,omitempty"` // only if generated for the peer
+	PresharedKey string `json:"preshared_key,omitempty"`
+
+	Address             []string `json:"address,omitempty"`     // ip/mask, assigned tunnel addresses
+	AllowedIPs          []string `json:"allowed_ips,omitempty"` // ip/mask, extra routes to the peer
+	Endpoint            string   `json:"endpoint,omitempty"`
+	PersistentKeepalive int      `json:"persistent_keepalive,omitempty"`
+}
+
+// HasTag reports whether p is tagged with tag
+func (p Peer) HasTag(tag string) bool {
+	for _, t := range p.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired reports whether p has an expiry at or before at
+func (p Peer) Expired(at time.Time) bool {
+	return !p.Expires.IsZero() && !at.Before(p.Expires)
+}
+
+// WgPeer is the interface side Peer entry,
+// AllowedIPs are the host prefixes of Address and AllowedIPs
+func (p Peer) WgPeer() wg.Peer {
+	var ips []string
+	for _, a := range p.Address {
+		if prefix, err := netip.ParsePrefix(a); err == nil {
+			a = netip.PrefixFrom(prefix.Addr(), prefix.Addr().BitLen()).String()
+		}
+		ips = append(ips, a)
+	}
+	return wg.Peer{
+		PublicKey:           p.PublicKey,
+		PresharedKey:        p.PresharedKey,
+		AllowedIPs:          append(ips, p.AllowedIPs...),
+		Endpoint:            p.Endpoint,
+		PersistentKeepalive: p.PersistentKeepalive,
+	}
+}
+
+// Registry is a directory backed store of interfaces and peers,
+// safe for concurrent use within a process
+type Registry struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// Open uses dir as a registry, creating it if needed
+func Open(dir string) (*Registry, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, fmt.Errorf("open registry: %v", err)
+	}
+	return &Registry{dir: dir}, nil
+}
+
+// Dir is the directory backing the registry
+func (r *Registry) Dir() string {
+	return r.dir
+}
+
+func (r *Registry) ifacePath(iface string) string {
+	return filepath.Join(r.dir, iface, "interface.json")
+}
+
+func (r *Registry) peerPath(iface, name string) string {
+	return filepath.Join(r.dir, iface, "peers", name+".json")
+}
+
+// writeJSON writes v to fpath as indented JSON
+func writeJSON(fpath string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(fpath, append(b, '\n'))
+}
+
+// readJSON reads fpath into v, wrapping ErrNotFound if it doesn't exist
+func readJSON(fpath string, v interface{}) error {
+	b, err := ioutil.ReadFile(fpath)
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// PutInterface creates or replaces the interface config of iface,
+// Interface.PublicKey is not stored
+func (r *Registry) PutInterface(iface string, i wg.Interface) error {
+	if !validName.MatchString(iface) {
+		return fmt.Errorf("put interface: invalid name %q", iface)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.putInterface(iface, i)
+}
+
+func (r *Registry) putInterface(iface string, i wg.Interface) error {
+	i.PublicKey = ""
+	err := os.MkdirAll(filepath.Join(r.dir, iface, "peers"), 0700)
+	if err == nil {
+		err = writeJSON(r.ifacePath(iface), i)
+	}
+	if err != nil {
+		return fmt.Errorf("put interface %v: %v", iface, err)
+	}
+	return nil
+}
+
+// Interface gets the interface config of iface
+func (r *Registry) Interface(iface string) (wg.Interface, error) {
+	var i wg.Interface
+	if !validName.MatchString(iface) {
+		return i, fmt.Errorf("get interface %v: %w", iface, ErrNotFound)
+	}
+	err := readJSON(r.ifacePath(iface), &i)
+	if err != nil {
+		return i, fmt.Errorf("get interface %v: %w", iface, err)
+	}
+	return i, nil
+}
+
+// Interfaces lists the names of all interfaces
+func (r *Registry) Interfaces() ([]string, error) {
+	fis, err := ioutil.ReadDir(r.dir)
+	if err != nil {
+		return nil, fmt.Errorf("list interfaces: %v", err)
+	}
+	var ifaces []string
+	for _, fi := range fis {
+		if !fi.IsDir() || !validName.MatchString(fi.Name()) {
+			continue
+		}
+		if _, err := os.Stat(r.ifacePath(fi.Name())); err == nil {
+			ifaces = append(ifaces, fi.Name())
+		}
+	}
+	return ifaces, nil
+}
+
+// DeleteInterface removes iface and all its peers
+func (r *Registry) DeleteInterface(iface string) error {
+	if _, err := r.Interface(iface); err != nil {
+		return fmt.Errorf("delete interface: %w", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	err := os.RemoveAll(filepath.Join(r.dir, iface))
+	if err != nil {
+		return fmt.Errorf("delete interface %v: %v", iface, err)
+	}
+	return nil
+}
+
+// Put creates or replaces a peer,
+// the interface must exist and the PublicKey must be unique within it,
+// Created defaults to now
+func (r *Registry) Put(p Peer) error {
+	if !validName.MatchString(p.Name) {
+		return fmt.Errorf("put peer: invalid name %q", p.Name)
+	}
+	if p.PublicKey == "" {
+		return fmt.Errorf("put peer %v: no PublicKey", p.Name)
+	}
+	if _, err := r.Interface(p.Interface); err != nil {
+		return fmt.Errorf("put peer %v: %w", p.Name, err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.put(p)
+}
+
+func (r *Registry) put(p Peer) error {
+	peers, err := r.peers(p.Interface)
+	if err != nil {
+		return fmt.Errorf("put peer %v: %v", p.Name, err)
+	}
+	for _, o := range peers {
+		if o.PublicKey == p.PublicKey && o.Name != p.Name {
+			return fmt.Errorf("put peer %v: PublicKey used by peer %v", p.Name, o.Name)
+		}
+	}
+	if p.Created.IsZero() {
+		p.Created = now().UTC().Truncate(time.Second)
+	}
+	err = writeJSON(r.peerPath(p.Interface, p.Name), p)
+	if err != nil {
+		return fmt.Errorf("put peer %v: %v", p.Name, err)
+	}
+	return nil
+}
+
+// Get gets a peer by name
+func (r *Registry) Get(iface, name string) (Peer, error) {
+	var p Peer
+	if !validName.MatchString(iface) || !validName.MatchString(name) {
+		return p, fmt.Errorf("get peer %v: %w", name, ErrNotFound)
+	}
+	err := readJSON(r.peerPath(iface, name), &p)
+	if err != nil {
+		return p, fmt.Errorf("get peer %v: %w", name, err)
+	}
+	return p, nil
+}
+
+// ByPublicKey gets a peer by its PublicKey
+func (r *Registry) ByPublicKey(iface, pub string) (Peer, error) {
+	peers, err := r.Peers(iface)
+	if err != nil {
+		return Peer{}, err
+	}
+	for _, p := range peers {
+		if p.PublicKey == pub {
+			return p, nil
+		}
+	}
+	return Peer{}, fmt.Errorf("get peer %v: %w", pub, ErrNotFound)
+}
+
+// Delete removes a peer
+func (r *Registry) Delete(iface, name string) error {
+	if _, err := r.Get(iface, name); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	err := os.Remove(r.peerPath(iface, name))
+	if err != nil {
+		return fmt.Errorf("delete peer %v: %v", name, err)
+	}
+	return nil
+}
+
+// Peers lists the peers of iface sorted by name
+func (r *Registry) Peers(iface string) ([]Peer, error) {
+	if _, err := r.Interface(iface); err != nil {
+		return nil, fmt.Errorf("list peers: %w", err)
+	}
+	peers, err := r.peers(iface)
+	if err != nil {
+		return nil, fmt.Errorf("list peers %v: %v", iface, err)
+	}
+	return peers, nil
+}
+
+func (r *Registry) peers(iface string) ([]Peer, error) {
+	fis, err := ioutil.ReadDir(filepath.Join(r.dir, iface, "peers"))
+	if err != nil {
+		return nil, err
+	}
+	var peers []Peer
+	for _, fi := range fis {
+		name := strings.TrimSuffix(fi.Name(), ".json")
+		if fi.IsDir() || name == fi.Name() || !validName.MatchString(name) {
+			continue
+		}
+		var p Peer
+		err = readJSON(filepath.Join(r.dir, iface, "peers", fi.Name()), &p)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", fi.Name(), err)
+		}
+		peers = append(peers, p)
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Name < peers[j].Name })
+	return peers, nil
+}
+
+// Conf renders the config of iface with all its peers
+func (r *Registry) Conf(iface string) (wg.Conf, error) {
+	i, err := r.Interface(iface)
+	if err != nil {
+		return wg.Conf{}, fmt.Errorf("conf: %w", err)
+	}
+	peers, err := r.Peers(iface)
+	if err != nil {
+		return wg.Conf{}, fmt.Errorf("conf: %w", err)
+	}
+	c := wg.Conf{Interface: i}
+	for _, p := range peers {
+		c.Peers = append(c.Peers, p.WgPeer())
+	}
+	return c, nil
+}
+
+// splitAddress splits AllowedIPs into the host prefixes of the known addrs and the rest,
+// addrs are dropped unless all of them are still routed
+func splitAddress(addrs, allowedIPs []string) (address, rest []string) {
+	hosts := make(map[string]bool)
+	for _, a := range (Peer{Address: addrs}).WgPeer().AllowedIPs {
+		hosts[a] = true
+	}
+	for _, ip := range allowedIPs {
+		if hosts[ip] {
+			delete(hosts, ip)
+			continue
+		}
+		rest = append(rest, ip)
+	}
+	if len(hosts) != 0 {
+		return nil, allowedIPs
+	}
+	return addrs, rest
+}
+
+// Import reads a config (as accepted by NewConfBytes) into iface,
+// replacing the interface config,
+// peers with a known PublicKey are updated keeping their metadata,
+// new peers are named peer-N,
+// returns the imported peers
+func (r *Registry) Import(iface string, b []byte) ([]Peer, error) {
+	if !validName.MatchString(iface) {
+		return nil, fmt.Errorf("import: invalid name %q", iface)
+	}
+	c, err := wg.NewConfBytes(b)
+	if err != nil {
+		return nil, fmt.Errorf("import %v: %v", iface, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	existing, err := r.peers(iface)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("import %v: %v", iface, err)
+	}
+	byKey := make(map[string]Peer)
+	names := make(map[string]bool)
+	for _, p := range existing {
+		byKey[p.PublicKey] = p
+		names[p.Name] = true
+	}
+
+	// validate everything before writing anything
+	var peers []Peer
+	seen := make(map[string]bool)
+	n := 1
+	for _, wp := range c.Peers {
+		if wp.PublicKey == "" {
+			return nil, fmt.Errorf("import %v: peer with no PublicKey", iface)
+		}
+		if seen[wp.PublicKey] {
+			return nil, fmt.Errorf("import %v: duplicate PublicKey %v", iface, wp.PublicKey)
+		}
+		seen[wp.PublicKey] = true
+		p, ok := byKey[wp.PublicKey]
+		if !ok {
+			for names["peer-"+strconv.Itoa(n)] {
+				n++
+			}
+			p = Peer{Name: "peer-" + strconv.Itoa(n), Interface: iface, PublicKey: wp.PublicKey}
+			names[p.Name] = true
+		}
+		p.PresharedKey = wp.PresharedKey
+		p.Address, p.AllowedIPs = splitAddress(p.Address, wp.AllowedIPs)
+		p.Endpoint = wp.Endpoint
+		p.PersistentKeepalive = wp.PersistentKeepalive
+		peers = append(peers, p)
+	}
+
+	err = r.putInterface(iface, c.Interface)
+	if err != nil {
+		return nil, fmt.Errorf("import: %v", err)
+	}
+	var imported []Peer
+	for _, p := range peers {
+		err = r.put(p)
+		if err != nil {
+			return imported, fmt.Errorf("import %v: %v", iface, err)
+		}
+		p, _ = r.Get(iface, p.Name)
+		imported = append(imported, p)
+	}
+	return imported, nil
+}
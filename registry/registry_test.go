@@ -0,0 +1,205 @@
+package registry
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	wg "seankhliao.com/go-wg"
+)
+
+var (
+	se = "%v #%v errored: %v"
+	sf = "%v #%v \nexp: >%v< \ngot: >%v<"
+
+	privA = "AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA="
+
+	t0 = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+)
+
+func newRegistry(t *testing.T) *Registry {
+	now = func() time.Time { return t0 }
+	r, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf(se, "Open", 0, err)
+	}
+	err = r.PutInterface("wg0", wg.Interface{ListenPort: 51820, PrivateKey: privA, Address: []string{"10.0.0.1/24"}, PublicKey: "pub"})
+	if err != nil {
+		t.Fatalf(se, "PutInterface", 0, err)
+	}
+	return r
+}
+
+func TestRegistry(t *testing.T) {
+	r := newRegistry(t)
+
+	i, err := r.Interface("wg0")
+	if err != nil {
+		t.Fatalf(se, "Interface", 0, err)
+	}
+	if exp := (wg.Interface{ListenPort: 51820, PrivateKey: privA, Address: []string{"10.0.0.1/24"}}); !reflect.DeepEqual(i, exp) {
+		t.Errorf(sf, "Interface", 0, exp, i)
+	}
+	ifaces, _ := r.Interfaces()
+	if !reflect.DeepEqual(ifaces, []string{"wg0"}) {
+		t.Errorf(sf, "Interfaces", 0, []string{"wg0"}, ifaces)
+	}
+
+	peers := []Peer{
+		{Name: "phone", Interface: "wg0", Owner: "alice", Tags: []string{"mobile"}, PublicKey: "pub_phone", Address: []string{"10.0.0.3/24"}},
+		{Name: "laptop", Interface: "wg0", Owner: "alice", Expires: t0.Add(time.Hour), PublicKey: "pub_laptop", PresharedKey: "psk", Address: []string{"10.0.0.2/24"}, AllowedIPs: []string{"192.168.0.0/24"}},
+	}
+	for i, p := range peers {
+		if err := r.Put(p); err != nil {
+			t.Errorf(se, "Put", i, err)
+		}
+	}
+
+	p, err := r.Get("wg0", "phone")
+	if err != nil {
+		t.Fatalf(se, "Get", 0, err)
+	}
+	peers[0].Created = t0
+	if !reflect.DeepEqual(p, peers[0]) {
+		t.Errorf(sf, "Get", 0, peers[0], p)
+	}
+	if !p.HasTag("mobile") || p.HasTag("desktop") {
+		t.Errorf(sf, "HasTag", 0, []string{"mobile"}, p.Tags)
+	}
+	expiring := Peer{Expires: t0}
+	if p.Expired(t0.Add(time.Hour)) || !expiring.Expired(t0) || expiring.Expired(t0.Add(-time.Second)) {
+		t.Errorf(sf, "Expired", 0, t0, expiring.Expires)
+	}
+	p, err = r.ByPublicKey("wg0", "pub_laptop")
+	if err != nil || p.Name != "laptop" {
+		t.Errorf(sf, "ByPublicKey", 0, "laptop", p.Name)
+	}
+
+	c, err := r.Conf("wg0")
+	if err != nil {
+		t.Fatalf(se, "Conf", 0, err)
+	}
+	exp := wg.Conf{
+		Interface: i,
+		Peers: []wg.Peer{
+			{PublicKey: "pub_laptop", PresharedKey: "psk", AllowedIPs: []string{"10.0.0.2/32", "192.168.0.0/24"}},
+			{PublicKey: "pub_phone", AllowedIPs: []string{"10.0.0.3/32"}},
+		},
+	}
+	if !reflect.DeepEqual(c, exp) {
+		t.Errorf(sf, "Conf", 0, exp, c)
+	}
+
+	err = r.Delete("wg0", "phone")
+	if err != nil {
+		t.Errorf(se, "Delete", 0, err)
+	}
+	if _, err = r.Get("wg0", "phone"); !errors.Is(err, ErrNotFound) {
+		t.Errorf(sf, "Get deleted", 0, ErrNotFound, err)
+	}
+	if err = r.Delete("wg0", "phone"); !errors.Is(err, ErrNotFound) {
+		t.Errorf(sf, "Delete deleted", 0, ErrNotFound, err)
+	}
+
+	fi, err := os.Stat(filepath.Join(r.Dir(), "wg0", "peers", "laptop.json"))
+	if err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf(sf, "permissions", 0, os.FileMode(0600), fi.Mode().Perm())
+	}
+
+	err = r.DeleteInterface("wg0")
+	if err != nil {
+		t.Errorf(se, "DeleteInterface", 0, err)
+	}
+	if _, err = r.Peers("wg0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf(sf, "Peers deleted", 0, ErrNotFound, err)
+	}
+}
+
+func TestPutErrors(t *testing.T) {
+	r := newRegistry(t)
+	r.Put(Peer{Name: "a", Interface: "wg0", PublicKey: "pub_a"})
+	cases := []Peer{
+		{Name: "../a", Interface: "wg0", PublicKey: "pub"},
+		{Name: ".hidden", Interface: "wg0", PublicKey: "pub"},
+		{Name: "b", Interface: "wg0"},
+		{Name: "b", Interface: "wg1", PublicKey: "pub"},
+		{Name: "b", Interface: "wg0", PublicKey: "pub_a"},
+	}
+	for i, c := range cases {
+		if err := r.Put(c); err == nil {
+			t.Errorf(se, "Put", i, "expected error")
+		}
+	}
+	if err := r.PutInterface("../wg", wg.Interface{}); err == nil {
+		t.Errorf(se, "PutInterface", 0, "expected error")
+	}
+}
+
+func TestImport(t *testing.T) {
+	r := newRegistry(t)
+	r.Put(Peer{Name: "laptop", Interface: "wg0", Owner: "alice", PublicKey: "pub_laptop", Address: []string{"10.0.0.2/24"}})
+	// leftover temp files are ignored
+	ioutil.WriteFile(filepath.Join(r.Dir(), "wg0", "peers", ".x.json.tmp1"), nil, 0600)
+
+	conf := `[Interface]
+ListenPort = 51821
+PrivateKey = ICEiIyQlJicoKSorLC0uLzAxMjM0NTY3ODk6Ozw9Pj8=
+
+[Peer]
+PublicKey = pub_laptop
+AllowedIPs = 10.0.0.2/32, 192.168.0.0/24
+
+[Peer]
+PublicKey = pub_new
+AllowedIPs = 10.0.0.4/32
+Endpoint = 1.2.3.4:51820
+`
+	peers, err := r.Import("wg0", []byte(conf))
+	if err != nil {
+		t.Fatalf(se, "Import", 0, err)
+	}
+	exp := []Peer{
+		{Name: "laptop", Interface: "wg0", Owner: "alice", Created: t0, PublicKey: "pub_laptop", Address: []string{"10.0.0.2/24"}, AllowedIPs: []string{"192.168.0.0/24"}},
+		{Name: "peer-1", Interface: "wg0", Created: t0, PublicKey: "pub_new", AllowedIPs: []string{"10.0.0.4/32"}, Endpoint: "1.2.3.4:51820"},
+	}
+	if !reflect.DeepEqual(peers, exp) {
+		t.Errorf(sf, "Import", 0, exp, peers)
+	}
+
+	c, err := r.Conf("wg0")
+	if err != nil {
+		t.Fatalf(se, "Conf", 0, err)
+	}
+	want, _ := wg.NewConfBytes([]byte(conf))
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf(sf, "Conf", 0, want, c)
+	}
+
+	if _, err := r.Import("wg0", []byte("[Interface]\nListenPort = port\n")); err == nil {
+		t.Errorf(se, "Import", 1, "expected error")
+	}
+
+	// invalid peers leave the registry untouched
+	for i, bad := range []string{
+		"[Interface]\nListenPort = 1\n\n[Peer]\nAllowedIPs = 10.0.0.5/32\n",
+		"[Interface]\nListenPort = 1\n\n[Peer]\nPublicKey = pub_dup\n\n[Peer]\nPublicKey = pub_dup\n",
+	} {
+		if _, err := r.Import("wg0", []byte(bad)); err == nil {
+			t.Errorf(se, "Import", 2+i, "expected error")
+		}
+		c, _ := r.Conf("wg0")
+		if !reflect.DeepEqual(c, want) {
+			t.Errorf(sf, "Import", 2+i, want, c)
+		}
+	}
+	if _, err := r.Import("wg1", []byte("[Interface]\nListenPort = 1\n\n[Peer]\nAllowedIPs = 10.0.0.5/32\n")); err == nil {
+		t.Errorf(se, "Import", 4, "expected error")
+	}
+	if _, err := r.Interface("wg1"); err == nil {
+		t.Errorf(se, "Import", 4, "expected no interface")
+	}
+}
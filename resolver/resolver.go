@@ -0,0 +1,142 @@
+// Package resolver periodically re-resolves peer endpoints given as hostnames,
+// wg only resolves them once when the config is set,
+// so peers behind dynamic DNS break after an ip change
+// (reresolve-dns.sh as a service)
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+
+	wg "seankhliao.com/go-wg"
+	"seankhliao.com/go-wg/internal/loop"
+)
+
+// Defaults, same as reresolve-dns.sh
+const (
+	DefaultInterval = 30 * time.Second
+	DefaultStale    = 135 * time.Second
+)
+
+// Resolver keeps the hostname endpoints of an interface up to date
+type Resolver struct {
+	Interface string
+	// Endpoints maps peer public keys to host:port,
+	// only peers with a hostname (not an ip) are kept
+	Endpoints map[string]string
+
+	Client   wg.Client     // defaults to wg.Cli
+	Resolver *net.Resolver // defaults to net.DefaultResolver
+	Network  string        // "ip", "ip4" or "ip6", defaults to "ip"
+	Interval time.Duration // time between checks, defaults to DefaultInterval
+	Stale    time.Duration // handshakes older than this are re-resolved, defaults to DefaultStale
+}
+
+// New creates a Resolver for iface
+// from the original config (file), before wg resolved the hostnames
+func New(iface string, c wg.Conf) *Resolver {
+	r := &Resolver{
+		Interface: iface,
+		Endpoints: make(map[string]string),
+	}
+	for _, p := range c.Peers {
+		host, _, err := net.SplitHostPort(p.Endpoint)
+		if err != nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(host); err == nil {
+			continue
+		}
+		r.Endpoints[p.PublicKey] = p.Endpoint
+	}
+	return r
+}
+
+// Run resolves every Interval until ctx is cancelled,
+// failed runs are reported to errf if not nil
+func (r *Resolver) Run(ctx context.Context, errf func(error)) error {
+	interval := r.Interval
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+	return loop.Every(ctx, interval, func(ctx context.Context) error {
+		_, err := r.Resolve(ctx)
+		return err
+	}, errf)
+}
+
+// Resolve checks peers once,
+// peers with a stale handshake are re-resolved and
+// peers whose endpoint changed are updated in a single Set,
+// peers that fail to resolve don't hold back the others,
+// returns the updated peers and the joined errors
+func (r *Resolver) Resolve(ctx context.Context) ([]wg.OptPeer, error) {
+	client := r.Client
+	if client == nil {
+		client = wg.Cli{}
+	}
+	res := r.Resolver
+	if res == nil {
+		res = net.DefaultResolver
+	}
+	network := r.Network
+	if network == "" {
+		network = "ip"
+	}
+	stale := r.Stale
+	if stale == 0 {
+		stale = DefaultStale
+	}
+
+	c, err := client.Show(ctx, r.Interface)
+	if err != nil {
+		return nil, fmt.Errorf("resolve: %v", err)
+	}
+
+	var peers []wg.OptPeer
+	var errs []error
+	for _, p := range c.Peers {
+		endpoint, ok := r.Endpoints[p.PublicKey]
+		if !ok {
+			continue
+		}
+		// LatestHandshake is seconds ago, 0 for never
+		if p.LatestHandshake != 0 && time.Duration(p.LatestHandshake)*time.Second < stale {
+			continue
+		}
+		host, port, err := net.SplitHostPort(endpoint)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("resolve %v: %v", endpoint, err))
+			continue
+		}
+		addrs, err := res.LookupNetIP(ctx, network, host)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("resolve %v: %v", host, err))
+			continue
+		}
+		if len(addrs) == 0 {
+			continue
+		}
+		resolved := net.JoinHostPort(addrs[0].Unmap().String(), port)
+		if resolved == p.Endpoint {
+			continue
+		}
+		peers = append(peers, wg.OptPeer{
+			PublicKey: p.PublicKey,
+			Endpoint:  resolved,
+		})
+	}
+	if len(peers) == 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	err = client.Set(ctx, wg.Opt{Interface: r.Interface, Peers: peers})
+	if err != nil {
+		return nil, fmt.Errorf("resolve: %v", err)
+	}
+	return peers, errors.Join(errs...)
+}
@@ -0,0 +1,114 @@
+package resolver
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+
+	wg "seankhliao.com/go-wg"
+	"seankhliao.com/go-wg/wgtest"
+)
+
+var (
+	se = "%v #%v errored: %v"
+	sf = "%v #%v \nexp: >%v< \ngot: >%v<"
+)
+
+func TestNew(t *testing.T) {
+	c := wg.Conf{
+		Peers: []wg.Peer{
+			{PublicKey: "pubkey_a", Endpoint: "vpn.example.com:51820"},
+			{PublicKey: "pubkey_b", Endpoint: "1.2.3.4:51820"},
+			{PublicKey: "pubkey_c", Endpoint: "[fd00::1]:51820"},
+			{PublicKey: "pubkey_d"},
+		},
+	}
+	exp := map[string]string{"pubkey_a": "vpn.example.com:51820"}
+	r := New("wg0", c)
+	if !reflect.DeepEqual(r.Endpoints, exp) {
+		t.Errorf(sf, "New", 0, exp, r.Endpoints)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	cases := []struct {
+		Live  wg.Peer
+		Peers []wg.OptPeer
+	}{
+		{
+			// stale, changed
+			wg.Peer{PublicKey: "pubkey_a", Endpoint: "10.0.0.1:51820", LatestHandshake: 300},
+			[]wg.OptPeer{{PublicKey: "pubkey_a", Endpoint: "127.0.0.1:51820"}},
+		}, {
+			// never, changed
+			wg.Peer{PublicKey: "pubkey_a", Endpoint: "10.0.0.1:51820"},
+			[]wg.OptPeer{{PublicKey: "pubkey_a", Endpoint: "127.0.0.1:51820"}},
+		}, {
+			// fresh
+			wg.Peer{PublicKey: "pubkey_a", Endpoint: "10.0.0.1:51820", LatestHandshake: 5},
+			nil,
+		}, {
+			// stale, unchanged
+			wg.Peer{PublicKey: "pubkey_a", Endpoint: "127.0.0.1:51820", LatestHandshake: 300},
+			nil,
+		}, {
+			// not a hostname peer
+			wg.Peer{PublicKey: "pubkey_b", Endpoint: "10.0.0.1:51820", LatestHandshake: 300},
+			nil,
+		},
+	}
+	for i, c := range cases {
+		client := wgtest.NewClient(map[string]wg.Conf{
+			"wg0": {Peers: []wg.Peer{c.Live}},
+		})
+		r := New("wg0", wg.Conf{Peers: []wg.Peer{{PublicKey: "pubkey_a", Endpoint: "localhost:51820"}}})
+		r.Client = client
+		r.Resolver = &net.Resolver{PreferGo: true}
+		r.Network = "ip4"
+
+		peers, err := r.Resolve(context.Background())
+		if err != nil {
+			t.Errorf(se, "Resolve", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(peers, c.Peers) {
+			t.Errorf(sf, "Resolve", i, c.Peers, peers)
+		}
+		if sets := client.Sets(); len(sets) != 0 && c.Peers == nil {
+			t.Errorf(sf, "Resolve Set", i, nil, sets)
+		}
+		conf, _ := client.Show(context.Background(), "wg0")
+		if c.Peers != nil && conf.Peers[0].Endpoint != c.Peers[0].Endpoint {
+			t.Errorf(sf, "Resolve endpoint", i, c.Peers[0].Endpoint, conf.Peers[0].Endpoint)
+		}
+	}
+}
+
+func TestResolvePartial(t *testing.T) {
+	client := wgtest.NewClient(map[string]wg.Conf{
+		"wg0": {Peers: []wg.Peer{
+			{PublicKey: "pubkey_a", Endpoint: "10.0.0.1:51820"},
+			{PublicKey: "pubkey_b", Endpoint: "10.0.0.2:51820"},
+		}},
+	})
+	r := &Resolver{
+		Interface: "wg0",
+		Endpoints: map[string]string{"pubkey_a": "no-port", "pubkey_b": "localhost:51820"},
+		Client:    client,
+		Resolver:  &net.Resolver{PreferGo: true},
+		Network:   "ip4",
+	}
+	peers, err := r.Resolve(context.Background())
+	if err == nil {
+		t.Errorf(se, "Resolve", 0, "expected error for pubkey_a")
+	}
+	exp := []wg.OptPeer{{PublicKey: "pubkey_b", Endpoint: "127.0.0.1:51820"}}
+	if !reflect.DeepEqual(peers, exp) {
+		t.Errorf(sf, "Resolve", 0, exp, peers)
+	}
+	conf, _ := client.Show(context.Background(), "wg0")
+	if conf.Peers[0].Endpoint != "10.0.0.1:51820" || conf.Peers[1].Endpoint != "127.0.0.1:51820" {
+		t.Errorf(sf, "Resolve endpoints", 0, "127.0.0.1:51820", conf.Peers)
+	}
+}
@@ -0,0 +1,505 @@
+// Package rotate changes the private key of an interface and the preshared keys of its peers
+// without breaking peers: new keys are generated and published ahead of a cutover time,
+// set on the interface at the cutover, and can be rolled back until then,
+// progress is persisted to a state file so a crashed rotation resumes where it stopped
+package rotate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	wg "seankhliao.com/go-wg"
+	"seankhliao.com/go-wg/internal/atomicfile"
+	"seankhliao.com/go-wg/internal/loop"
+)
+
+// Defaults
+const (
+	DefaultDelay    = 24 * time.Hour // between publishing and the cutover
+	DefaultInterval = time.Minute    // between steps in Run
+
+	DefaultMaxAttempts = 5 // publish or apply attempts before a change fails
+)
+
+// ErrInProgress is returned when starting a rotation for a key that already has one pending
+var ErrInProgress = errors.New("rotation in progress")
+
+var now = time.Now
+
+// Status is the progress of a key change
+type Status string
+
+// Key change statuses
+const (
+	Pending  Status = "pending"  // generated, waiting for the cutover
+	Applying Status = "applying" // being set on the interface
+	Applied  Status = "applied"  // set on the interface, secrets cleared
+	Failed   Status = "failed"   // gave up after MaxAttempts, needs a Rollback
+)
+
+// Key is a scheduled change of the interface private key (Peer is empty)
+// or of the preshared key with a peer,
+// Old and New are secret and cleared once applied
+type Key struct {
+	Peer         string    `json:"peer,omitempty"` // public key
+	Old          string    `json:"old,omitempty"`
+	New          string    `json:"new,omitempty"`
+	NewPublicKey string    `json:"new_public_key,omitempty"` // of New, for the interface private key
+	Cutover      time.Time `json:"cutover"`
+	Status       Status    `json:"status"`
+	Published    bool      `json:"published,omitempty"`
+	Applied      time.Time `json:"applied,omitzero"`
+	Attempts     int       `json:"attempts,omitempty"` // failed attempts
+	Error        string    `json:"error,omitempty"`    // of the last failed attempt
+}
+
+// State is the persisted progress of rotations for an interface
+type State struct {
+	Interface string `json:"interface"`
+	// Keys has at most one change per key, the interface first then sorted by peer
+	Keys []Key `json:"keys,omitempty"`
+	// Rotated is the last preshared key change (or when it was first seen) by peer
+	Rotated map[string]time.Time `json:"rotated,omitempty"`
+}
+
+func (s *State) find(peer string) int {
+	for i, k := range s.Keys {
+		if k.Peer == peer {
+			return i
+		}
+	}
+	return -1
+}
+
+// put adds or replaces the change for k.Peer, keeping Keys sorted
+func (s *State) put(k Key) {
+	if i := s.find(k.Peer); i != -1 {
+		s.Keys[i] = k
+		return
+	}
+	i := sort.Search(len(s.Keys), func(i int) bool { return s.Keys[i].Peer >= k.Peer })
+	s.Keys = append(s.Keys, Key{})
+	copy(s.Keys[i+1:], s.Keys[i:])
+	s.Keys[i] = k
+}
+
+// Rotator orchestrates key changes for an interface
+type Rotator struct {
+	Interface string
+	Client    wg.Client // defaults to wg.Cli
+	// StateFile is where progress is kept, written with 0600 permissions
+	StateFile string
+	Delay     time.Duration // defaults to DefaultDelay
+	// PresharedKeyInterval schedules preshared key changes for peers that have one,
+	// 0 disables
+	PresharedKeyInterval time.Duration
+	// Publish announces a pending change so peers can update their configs before the cutover,
+	// eg the interface's new public key, or the new preshared key to its peer,
+	// retried by Step up to MaxAttempts
+	Publish     func(ctx context.Context, k Key) error
+	Interval    time.Duration // defaults to DefaultInterval
+	MaxAttempts int           // defaults to DefaultMaxAttempts
+
+	mu sync.Mutex
+}
+
+func (r *Rotator) client() wg.Client {
+	if r.Client == nil {
+		return wg.Cli{}
+	}
+	return r.Client
+}
+
+// load reads the state, empty if it doesn't exist yet
+func (r *Rotator) load() (State, error) {
+	s := State{Interface: r.Interface}
+	b, err := ioutil.ReadFile(r.StateFile)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return s, err
+	}
+	err = json.Unmarshal(b, &s)
+	if err != nil {
+		return s, err
+	}
+	if s.Interface != r.Interface {
+		return s, fmt.Errorf("%v is for interface %v", r.StateFile, s.Interface)
+	}
+	return s, nil
+}
+
+func (r *Rotator) save(s State) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(r.StateFile, append(b, '\n'))
+}
+
+// State reads the current state
+func (r *Rotator) State() (State, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, err := r.load()
+	if err != nil {
+		return s, fmt.Errorf("state: %v", err)
+	}
+	return s, nil
+}
+
+// RotatePrivateKey starts changing the interface private key,
+// a zero cutover uses Delay from now
+func (r *Rotator) RotatePrivateKey(ctx context.Context, cutover time.Time) (Key, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k, err := r.start(ctx, "", cutover)
+	if err != nil {
+		return k, fmt.Errorf("rotate private key: %w", err)
+	}
+	return k, nil
+}
+
+// RotatePresharedKey starts changing the preshared key with peer,
+// a zero cutover uses Delay from now
+func (r *Rotator) RotatePresharedKey(ctx context.Context, peer string, cutover time.Time) (Key, error) {
+	if peer == "" {
+		return Key{}, fmt.Errorf("rotate preshared key: no peer")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k, err := r.start(ctx, peer, cutover)
+	if err != nil {
+		return k, fmt.Errorf("rotate preshared key %v: %w", peer, err)
+	}
+	return k, nil
+}
+
+// start generates and persists a pending key then publishes it
+func (r *Rotator) start(ctx context.Context, peer string, cutover time.Time) (Key, error) {
+	s, err := r.load()
+	if err != nil {
+		return Key{}, err
+	}
+	c, err := r.client().Show(ctx, r.Interface)
+	if err != nil {
+		return Key{}, err
+	}
+	k, err := r.newKey(s, c, peer, cutover)
+	if err != nil {
+		return k, err
+	}
+	s.put(k)
+	err = r.save(s)
+	if err != nil {
+		return k, err
+	}
+	return r.publish(ctx, &s, k)
+}
+
+// newKey generates a change for peer ("" for the interface),
+// only an applied change may be replaced
+func (r *Rotator) newKey(s State, c wg.Conf, peer string, cutover time.Time) (Key, error) {
+	if i := s.find(peer); i != -1 && s.Keys[i].Status != Applied {
+		return Key{}, ErrInProgress
+	}
+	if cutover.IsZero() {
+		delay := r.Delay
+		if delay == 0 {
+			delay = DefaultDelay
+		}
+		cutover = now().Add(delay)
+	}
+	k := Key{Peer: peer, Cutover: cutover.UTC(), Status: Pending}
+	var err error
+	if peer == "" {
+		k.Old = c.PrivateKey
+		k.New, err = wg.NewPrivateKey()
+		if err == nil {
+			k.NewPublicKey, err = wg.PublicKeyFor(k.New)
+		}
+	} else {
+		found := false
+		for _, p := range c.Peers {
+			if p.PublicKey == peer {
+				k.Old, found = p.PresharedKey, true
+			}
+		}
+		if !found {
+			return k, fmt.Errorf("no peer %v", peer)
+		}
+		k.New, err = wg.NewPresharedKey()
+	}
+	if err != nil {
+		return k, err
+	}
+	if k.Old == "" && peer == "" {
+		return k, fmt.Errorf("no current private key to roll back to")
+	}
+	return k, nil
+}
+
+// publish calls Publish for k and records it
+func (r *Rotator) publish(ctx context.Context, s *State, k Key) (Key, error) {
+	if r.Publish != nil {
+		err := r.Publish(ctx, k)
+		if err != nil {
+			return k, fmt.Errorf("publish: %v", err)
+		}
+	}
+	k.Published = true
+	k.Attempts, k.Error = 0, ""
+	s.put(k)
+	return k, r.save(*s)
+}
+
+// Step moves every rotation forward once, each on its own:
+// scheduled preshared key changes are started,
+// unpublished changes are published,
+// published changes past their cutover and interrupted changes are set on the interface,
+// changes for peers no longer on the interface are dropped,
+// failures are recorded on the change and retried up to MaxAttempts,
+// returns the applied changes and the joined errors
+func (r *Rotator) Step(ctx context.Context) ([]Key, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, err := r.load()
+	if err != nil {
+		return nil, fmt.Errorf("step: %v", err)
+	}
+
+	if r.PresharedKeyInterval > 0 {
+		err = r.schedule(ctx, &s)
+		if err != nil {
+			return nil, fmt.Errorf("step: %v", err)
+		}
+	}
+	c, err := r.client().Show(ctx, r.Interface)
+	if err != nil {
+		return nil, fmt.Errorf("step: %v", err)
+	}
+	peers := make(map[string]bool, len(c.Peers))
+	for _, p := range c.Peers {
+		peers[p.PublicKey] = true
+	}
+
+	at := now()
+	var applied []Key
+	var errs []error
+	for _, k := range append([]Key(nil), s.Keys...) {
+		if k.Peer != "" && !peers[k.Peer] {
+			// wg set would recreate the peer without AllowedIPs
+			i := s.find(k.Peer)
+			s.Keys = append(s.Keys[:i], s.Keys[i+1:]...)
+			delete(s.Rotated, k.Peer)
+			err = r.save(s)
+			if err != nil {
+				return applied, fmt.Errorf("step: %v", err)
+			}
+			if k.Status != Applied {
+				errs = append(errs, fmt.Errorf("%v: peer removed, rotation dropped", name(k)))
+			}
+			continue
+		}
+		switch {
+		case k.Status == Pending && !k.Published:
+			_, err = r.publish(ctx, &s, k)
+		case k.Status == Applying || (k.Status == Pending && !at.Before(k.Cutover)):
+			k, err = r.apply(ctx, &s, k, at)
+			if err == nil {
+				applied = append(applied, k)
+			}
+		default:
+			continue
+		}
+		if err != nil && k.Status == Applied {
+			// set but not saved, don't retry
+			return applied, fmt.Errorf("step: %v: %v", name(k), err)
+		} else if err != nil {
+			errs = append(errs, r.fail(&s, k, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return applied, fmt.Errorf("step: %w", err)
+	}
+	return applied, nil
+}
+
+// name describes the key changed by k
+func name(k Key) string {
+	if k.Peer == "" {
+		return "private key"
+	}
+	return "preshared key " + k.Peer
+}
+
+// apply sets k.New, persisting progress before and after,
+// the secrets are cleared once applied
+func (r *Rotator) apply(ctx context.Context, s *State, k Key, at time.Time) (Key, error) {
+	k.Status = Applying
+	s.put(k)
+	err := r.save(*s)
+	if err != nil {
+		return k, err
+	}
+	err = r.set(ctx, k, k.New)
+	if err != nil {
+		return k, err
+	}
+	k.Status = Applied
+	k.Applied = at.UTC()
+	k.Old, k.New = "", ""
+	k.Attempts, k.Error = 0, ""
+	s.put(k)
+	if k.Peer != "" {
+		if s.Rotated == nil {
+			s.Rotated = make(map[string]time.Time)
+		}
+		s.Rotated[k.Peer] = at.UTC()
+	}
+	return k, r.save(*s)
+}
+
+// fail records err on k, giving up after MaxAttempts
+func (r *Rotator) fail(s *State, k Key, err error) error {
+	max := r.MaxAttempts
+	if max == 0 {
+		max = DefaultMaxAttempts
+	}
+	k.Attempts++
+	k.Error = err.Error()
+	if k.Attempts >= max {
+		k.Status = Failed
+	}
+	s.put(k)
+	if serr := r.save(*s); serr != nil {
+		return fmt.Errorf("%v: %v, save: %v", name(k), err, serr)
+	}
+	return fmt.Errorf("%v: %v", name(k), err)
+}
+
+// schedule starts preshared key changes for peers last rotated PresharedKeyInterval ago,
+// peers seen for the first time start their interval now
+func (r *Rotator) schedule(ctx context.Context, s *State) error {
+	c, err := r.client().Show(ctx, r.Interface)
+	if err != nil {
+		return err
+	}
+	at := now().UTC()
+	changed := false
+	for _, p := range c.Peers {
+		if p.PresharedKey == "" {
+			continue
+		}
+		last, ok := s.Rotated[p.PublicKey]
+		if !ok {
+			if s.Rotated == nil {
+				s.Rotated = make(map[string]time.Time)
+			}
+			s.Rotated[p.PublicKey] = at
+			changed = true
+			continue
+		}
+		if at.Sub(last) < r.PresharedKeyInterval {
+			continue
+		}
+		k, err := r.newKey(*s, c, p.PublicKey, time.Time{})
+		if errors.Is(err, ErrInProgress) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		s.put(k)
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return r.save(*s)
+}
+
+// set sets key as the private key or the preshared key of the change's peer,
+// callers check the peer still exists as wg set would recreate it
+func (r *Rotator) set(ctx context.Context, k Key, key string) error {
+	fpath := "/dev/null" // removes the preshared key
+	if key != "" {
+		keyFile, cleanup, err := wg.KeyFiles()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		fpath, err = keyFile(key)
+		if err != nil {
+			return err
+		}
+	}
+	opt := wg.Opt{Interface: r.Interface}
+	if k.Peer == "" {
+		opt.PrivKeyFpath = fpath
+	} else {
+		opt.Peers = []wg.OptPeer{{PublicKey: k.Peer, PskFpath: fpath}}
+	}
+	return r.client().Set(ctx, opt)
+}
+
+// Rollback abandons an unapplied change for peer ("" for the interface private key),
+// restoring the old key if it may have been set,
+// applied changes have their secrets cleared and can't be rolled back
+func (r *Rotator) Rollback(ctx context.Context, peer string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, err := r.load()
+	if err != nil {
+		return fmt.Errorf("rollback: %v", err)
+	}
+	i := s.find(peer)
+	if i == -1 {
+		return fmt.Errorf("rollback %v: no rotation", peer)
+	}
+	k := s.Keys[i]
+	switch k.Status {
+	case Applied:
+		return fmt.Errorf("rollback %v: already applied", peer)
+	case Applying, Failed:
+		c, err := r.client().Show(ctx, r.Interface)
+		if err != nil {
+			return fmt.Errorf("rollback %v: %v", peer, err)
+		}
+		exists := peer == ""
+		for _, p := range c.Peers {
+			exists = exists || p.PublicKey == peer
+		}
+		if exists {
+			err = r.set(ctx, k, k.Old)
+			if err != nil {
+				return fmt.Errorf("rollback %v: %v", peer, err)
+			}
+		}
+	}
+	s.Keys = append(s.Keys[:i], s.Keys[i+1:]...)
+	err = r.save(s)
+	if err != nil {
+		return fmt.Errorf("rollback %v: %v", peer, err)
+	}
+	return nil
+}
+
+// Run steps the rotation every Interval until ctx is cancelled,
+// failed steps are reported to errf if not nil
+func (r *Rotator) Run(ctx context.Context, errf func(error)) error {
+	interval := r.Interval
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+	return loop.Every(ctx, interval, func(ctx context.Context) error {
+		_, err := r.Step(ctx)
+		return err
+	}, errf)
+}
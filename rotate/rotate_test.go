@@ -0,0 +1,281 @@
+package rotate
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	wg "seankhliao.com/go-wg"
+	"seankhliao.com/go-wg/wgtest"
+)
+
+var (
+	se = "%v #%v errored: %v"
+	sf = "%v #%v \nexp: >%v< \ngot: >%v<"
+
+	privA = "AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA="
+	pubA  = "B6N8vBQgk8i3VdwbEOhstCY3StFqqFPtC9/AsrhtHHw="
+	keyC  = "ICEiIyQlJicoKSorLC0uLzAxMjM0NTY3ODk6Ozw9Pj8="
+	keyD  = "QEFCQ0RFRkdISUpLTE1OT1BRUlNUVVZXWFlaW1xdXl8="
+)
+
+// failSet fails Set while fail is set or when it sets failPeer
+type failSet struct {
+	*wgtest.Client
+	fail     bool
+	failPeer string
+}
+
+func (c *failSet) Set(ctx context.Context, opt wg.Opt) error {
+	if c.fail {
+		return errors.New("set failed")
+	}
+	for _, p := range opt.Peers {
+		if p.PublicKey == c.failPeer {
+			return errors.New("set failed")
+		}
+	}
+	return c.Client.Set(ctx, opt)
+}
+
+func newRotator(t *testing.T) (*failSet, *Rotator, *[]Key) {
+	fake := &failSet{Client: wgtest.NewClient(map[string]wg.Conf{
+		"wg0": {
+			Interface: wg.Interface{PrivateKey: privA, PublicKey: pubA},
+			Peers: []wg.Peer{
+				{PublicKey: keyC, PresharedKey: keyD},
+				{PublicKey: keyD},
+			},
+		},
+	})}
+	var published []Key
+	r := &Rotator{
+		Interface: "wg0",
+		Client:    fake,
+		StateFile: filepath.Join(t.TempDir(), "rotate.json"),
+		Delay:     time.Hour,
+		Publish: func(ctx context.Context, k Key) error {
+			published = append(published, k)
+			return nil
+		},
+	}
+	return fake, r, &published
+}
+
+func setNow(t0 time.Time) {
+	now = func() time.Time { return t0 }
+}
+
+func TestPrivateKey(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	setNow(t0)
+	defer func() { now = time.Now }()
+	fake, r, published := newRotator(t)
+
+	k, err := r.RotatePrivateKey(ctx, time.Time{})
+	if err != nil {
+		t.Fatalf(se, "RotatePrivateKey", 0, err)
+	}
+	if pub, _ := wg.PublicKeyFor(k.New); k.Old != privA || pub != k.NewPublicKey || !k.Cutover.Equal(t0.Add(time.Hour)) {
+		t.Errorf(sf, "RotatePrivateKey", 0, privA, k)
+	}
+	if !reflect.DeepEqual(*published, []Key{{Old: privA, New: k.New, NewPublicKey: k.NewPublicKey, Cutover: k.Cutover, Status: Pending}}) {
+		t.Errorf(sf, "Publish", 0, k, *published)
+	}
+	if fi, err := os.Stat(r.StateFile); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf(sf, "mode", 0, os.FileMode(0600), fi)
+	}
+	if _, err = r.RotatePrivateKey(ctx, time.Time{}); !errors.Is(err, ErrInProgress) {
+		t.Errorf(sf, "RotatePrivateKey", 1, ErrInProgress, err)
+	}
+
+	keys, err := r.Step(ctx)
+	if err != nil || len(keys) != 0 {
+		t.Errorf(sf, "Step", 0, 0, keys)
+	}
+
+	// crash during Set
+	setNow(t0.Add(time.Hour))
+	fake.fail = true
+	if _, err = r.Step(ctx); err == nil {
+		t.Errorf(se, "Step", 1, "expected error")
+	}
+	s, _ := r.State()
+	if s.Keys[0].Status != Applying {
+		t.Errorf(sf, "State", 1, Applying, s.Keys[0].Status)
+	}
+	if s.Keys[0].Attempts != 1 || s.Keys[0].Error == "" {
+		t.Errorf(sf, "State", 1, 1, s.Keys[0].Attempts)
+	}
+
+	// an interrupted change can be rolled back
+	fake.fail = false
+	err = r.Rollback(ctx, "")
+	if err != nil {
+		t.Fatalf(se, "Rollback", 0, err)
+	}
+	c, _ := fake.Show(ctx, "wg0")
+	s, _ = r.State()
+	if c.PrivateKey != privA || len(s.Keys) != 0 {
+		t.Errorf(sf, "Rollback", 0, privA, c.PrivateKey)
+	}
+	if err = r.Rollback(ctx, ""); err == nil {
+		t.Errorf(se, "Rollback", 1, "expected error")
+	}
+
+	// pending rollback doesn't touch the interface
+	r.RotatePrivateKey(ctx, time.Time{})
+	n := len(fake.Sets())
+	if err = r.Rollback(ctx, ""); err != nil || len(fake.Sets()) != n {
+		t.Errorf(sf, "Rollback", 2, n, len(fake.Sets()))
+	}
+
+	// crash during Set, resume with a new Rotator
+	k, _ = r.RotatePrivateKey(ctx, t0)
+	fake.fail = true
+	r.Step(ctx)
+	fake.fail = false
+	r = &Rotator{Interface: "wg0", Client: fake, StateFile: r.StateFile}
+	keys, err = r.Step(ctx)
+	if err != nil || len(keys) != 1 || keys[0].Status != Applied || !keys[0].Applied.Equal(t0.Add(time.Hour)) {
+		t.Errorf(sf, "Step", 2, Applied, keys)
+	}
+	c, _ = fake.Show(ctx, "wg0")
+	if c.PrivateKey != k.New {
+		t.Errorf(sf, "Set", 2, k.New, c.PrivateKey)
+	}
+	s, _ = r.State()
+	if keys[0].Old != "" || keys[0].New != "" || s.Keys[0].Old != "" || s.Keys[0].New != "" || s.Keys[0].Attempts != 0 {
+		t.Errorf(sf, "State", 2, "secrets cleared", s.Keys[0])
+	}
+	if err = r.Rollback(ctx, ""); err == nil {
+		t.Errorf(se, "Rollback", 3, "expected error once applied")
+	}
+
+	// gives up after MaxAttempts
+	r.MaxAttempts = 2
+	r.RotatePrivateKey(ctx, t0)
+	fake.fail = true
+	for i := 0; i < 3; i++ {
+		r.Step(ctx)
+	}
+	fake.fail = false
+	s, _ = r.State()
+	if s.Keys[0].Status != Failed || s.Keys[0].Attempts != 2 {
+		t.Errorf(sf, "State", 3, Failed, s.Keys[0])
+	}
+	n = len(fake.Sets())
+	if keys, _ = r.Step(ctx); len(keys) != 0 || len(fake.Sets()) != n {
+		t.Errorf(sf, "Step", 3, 0, keys)
+	}
+	if err = r.Rollback(ctx, ""); err != nil {
+		t.Errorf(se, "Rollback", 4, err)
+	}
+	c, _ = fake.Show(ctx, "wg0")
+	if c.PrivateKey != k.New {
+		t.Errorf(sf, "Rollback", 4, k.New, c.PrivateKey)
+	}
+
+	r2 := &Rotator{Interface: "wg1", StateFile: r.StateFile}
+	if _, err = r2.State(); err == nil {
+		t.Errorf(se, "State", 3, "expected error for other interface")
+	}
+}
+
+func TestPresharedKey(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	setNow(t0)
+	defer func() { now = time.Now }()
+	fake, r, published := newRotator(t)
+	r.PresharedKeyInterval = 24 * time.Hour
+	failPublish := true
+	publish := r.Publish
+	r.Publish = func(ctx context.Context, k Key) error {
+		if failPublish {
+			return errors.New("publish failed")
+		}
+		return publish(ctx, k)
+	}
+
+	// starts the interval
+	keys, err := r.Step(ctx)
+	s, _ := r.State()
+	if err != nil || len(keys) != 0 || !reflect.DeepEqual(s.Rotated, map[string]time.Time{keyC: t0}) {
+		t.Errorf(sf, "Step", 0, map[string]time.Time{keyC: t0}, s.Rotated)
+	}
+
+	// scheduled, publish fails
+	setNow(t0.Add(24 * time.Hour))
+	if _, err = r.Step(ctx); err == nil {
+		t.Errorf(se, "Step", 1, "expected error")
+	}
+	s, _ = r.State()
+	if len(s.Keys) != 1 || s.Keys[0].Peer != keyC || s.Keys[0].Published || s.Keys[0].Old != keyD {
+		t.Errorf(sf, "State", 1, keyC, s.Keys)
+	}
+
+	// publish retried
+	failPublish = false
+	keys, err = r.Step(ctx)
+	if err != nil || len(keys) != 0 || len(*published) != 1 || (*published)[0].Peer != keyC {
+		t.Errorf(sf, "Step", 2, keyC, *published)
+	}
+
+	// a manual rotation of the other peer adds a preshared key
+	_, err = r.RotatePresharedKey(ctx, keyD, t0)
+	if err != nil {
+		t.Fatalf(se, "RotatePresharedKey", 0, err)
+	}
+	if _, err = r.RotatePresharedKey(ctx, privA, t0); err == nil {
+		t.Errorf(se, "RotatePresharedKey", 1, "expected error for unknown peer")
+	}
+
+	// each change is set on its own, a failure doesn't hold back the others
+	s, _ = r.State()
+	exp := map[string]string{keyC: s.Keys[s.find(keyC)].New}
+	fake.failPeer = keyD
+	setNow(t0.Add(25 * time.Hour))
+	keys, err = r.Step(ctx)
+	if err == nil || len(keys) != 1 || keys[0].Peer != keyC {
+		t.Fatalf(sf, "Step", 3, keyC, keys)
+	}
+	if sets := fake.Sets(); len(sets[len(sets)-1].Peers) != 1 {
+		t.Errorf(sf, "Set", 3, 1, sets[len(sets)-1])
+	}
+	c, _ := fake.Show(ctx, "wg0")
+	s, _ = r.State()
+	if c.Peers[0].PresharedKey != exp[keyC] || !s.Rotated[keyC].Equal(t0.Add(25*time.Hour)) {
+		t.Errorf(sf, "Step", 4, exp[keyC], c.Peers[0].PresharedKey)
+	}
+	if k := s.Keys[s.find(keyD)]; k.Status != Applying || k.Attempts != 1 {
+		t.Errorf(sf, "State", 4, Applying, k)
+	}
+
+	// rolling back removes the preshared key it didn't have before
+	fake.failPeer = ""
+	err = r.Rollback(ctx, keyD)
+	if err != nil {
+		t.Fatalf(se, "Rollback", 0, err)
+	}
+	c, _ = fake.Show(ctx, "wg0")
+	if c.Peers[1].PresharedKey != "" {
+		t.Errorf(sf, "Rollback", 0, "", c.Peers[1].PresharedKey)
+	}
+
+	// removed peers aren't recreated
+	r.RotatePresharedKey(ctx, keyD, t0)
+	fake.Update("wg0", func(c *wg.Conf) { c.Peers = c.Peers[:1] })
+	n := len(fake.Sets())
+	keys, err = r.Step(ctx)
+	c, _ = fake.Show(ctx, "wg0")
+	s, _ = r.State()
+	if err == nil || len(keys) != 0 || len(fake.Sets()) != n || len(c.Peers) != 1 || s.find(keyD) != -1 {
+		t.Errorf(sf, "Step", 5, "dropped", s.Keys)
+	}
+}
@@ -0,0 +1,157 @@
+// Package router exports Conf to router config formats:
+// OpenWrt UCI (/etc/config/network) and MikroTik RouterOS scripts
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	wg "seankhliao.com/go-wg"
+)
+
+// splitEndpoint splits host:port
+func splitEndpoint(endpoint string) (host, port string, err error) {
+	host, port, err = net.SplitHostPort(endpoint)
+	if err != nil {
+		return "", "", fmt.Errorf("endpoint %v: %v", endpoint, err)
+	}
+	return host, port, nil
+}
+
+// uci quotes a value for UCI
+func uci(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// UCI converts a Conf into /etc/config/network stanzas:
+// an interface section for name and a wireguard_<name> section per peer,
+// DNS servers go to dns and search domains to dns_search,
+// peer AllowedIPs are routed
+func UCI(name string, c wg.Conf) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("config interface " + uci(name) + "\n")
+	buf.WriteString("\toption proto 'wireguard'\n")
+	if c.PrivateKey != "" {
+		buf.WriteString("\toption private_key " + uci(c.PrivateKey) + "\n")
+	}
+	if c.ListenPort != 0 {
+		buf.WriteString("\toption listen_port " + uci(strconv.Itoa(c.ListenPort)) + "\n")
+	}
+	if c.FwMark != "" {
+		buf.WriteString("\toption fwmark " + uci(c.FwMark) + "\n")
+	}
+	for _, a := range c.Address {
+		buf.WriteString("\tlist addresses " + uci(a) + "\n")
+	}
+	// wg-quick DNS mixes servers and search domains
+	var search []string
+	for _, d := range c.DNS {
+		if _, err := netip.ParseAddr(d); err != nil {
+			search = append(search, d)
+			continue
+		}
+		buf.WriteString("\tlist dns " + uci(d) + "\n")
+	}
+	for _, d := range search {
+		buf.WriteString("\tlist dns_search " + uci(d) + "\n")
+	}
+
+	for _, p := range c.Peers {
+		buf.WriteString("\nconfig wireguard_" + name + "\n")
+		buf.WriteString("\toption public_key " + uci(p.PublicKey) + "\n")
+		if p.PresharedKey != "" {
+			buf.WriteString("\toption preshared_key " + uci(p.PresharedKey) + "\n")
+		}
+		for _, a := range p.AllowedIPs {
+			buf.WriteString("\tlist allowed_ips " + uci(a) + "\n")
+		}
+		buf.WriteString("\toption route_allowed_ips '1'\n")
+		if p.Endpoint != "" {
+			host, port, err := splitEndpoint(p.Endpoint)
+			if err != nil {
+				return nil, fmt.Errorf("uci: %v", err)
+			}
+			buf.WriteString("\toption endpoint_host " + uci(host) + "\n")
+			buf.WriteString("\toption endpoint_port " + uci(port) + "\n")
+		}
+		if p.PersistentKeepalive != 0 {
+			buf.WriteString("\toption persistent_keepalive " + uci(strconv.Itoa(p.PersistentKeepalive)) + "\n")
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// ros quotes a value for RouterOS
+func ros(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// RouterOS converts a Conf into a RouterOS script
+// adding interface name, its peers and addresses,
+// FwMark and DNS have no per interface equivalent and are ignored
+func RouterOS(name string, c wg.Conf) ([]byte, error) {
+	buf := bytes.NewBufferString("/interface wireguard\n")
+	buf.WriteString("add name=" + ros(name))
+	if c.ListenPort != 0 {
+		buf.WriteString(" listen-port=" + strconv.Itoa(c.ListenPort))
+	}
+	if c.PrivateKey != "" {
+		buf.WriteString(" private-key=" + ros(c.PrivateKey))
+	}
+	buf.WriteString("\n")
+
+	if len(c.Peers) != 0 {
+		buf.WriteString("/interface wireguard peers\n")
+	}
+	for _, p := range c.Peers {
+		buf.WriteString("add interface=" + ros(name) + " public-key=" + ros(p.PublicKey))
+		if p.PresharedKey != "" {
+			buf.WriteString(" preshared-key=" + ros(p.PresharedKey))
+		}
+		if len(p.AllowedIPs) != 0 {
+			buf.WriteString(" allowed-address=" + strings.Join(p.AllowedIPs, ","))
+		}
+		if p.Endpoint != "" {
+			host, port, err := splitEndpoint(p.Endpoint)
+			if err != nil {
+				return nil, fmt.Errorf("routeros: %v", err)
+			}
+			buf.WriteString(" endpoint-address=" + ros(host) + " endpoint-port=" + port)
+		}
+		if p.PersistentKeepalive != 0 {
+			buf.WriteString(" persistent-keepalive=" + strconv.Itoa(p.PersistentKeepalive) + "s")
+		}
+		buf.WriteString("\n")
+	}
+
+	var addr4, addr6 []string
+	for _, a := range c.Address {
+		prefix, err := netip.ParsePrefix(a)
+		if err != nil {
+			return nil, fmt.Errorf("routeros: Address: %v", err)
+		}
+		if prefix.Addr().Is4() {
+			addr4 = append(addr4, a)
+		} else {
+			addr6 = append(addr6, a)
+		}
+	}
+	if len(addr4) != 0 {
+		buf.WriteString("/ip address\n")
+		for _, a := range addr4 {
+			buf.WriteString("add address=" + a + " interface=" + ros(name) + "\n")
+		}
+	}
+	if len(addr6) != 0 {
+		buf.WriteString("/ipv6 address\n")
+		for _, a := range addr6 {
+			buf.WriteString("add address=" + a + " interface=" + ros(name) + " advertise=no\n")
+		}
+	}
+	return buf.Bytes(), nil
+}
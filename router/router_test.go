@@ -0,0 +1,118 @@
+package router
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	wg "seankhliao.com/go-wg"
+)
+
+var (
+	update = flag.Bool("update", false, "update golden files")
+
+	se = "%v #%v errored: %v"
+	sf = "%v #%v \nexp: >%v< \ngot: >%v<"
+)
+
+func golden(t *testing.T, name string, b []byte) {
+	fpath := filepath.Join("testdata", name)
+	if *update {
+		err := ioutil.WriteFile(fpath, b, 0644)
+		if err != nil {
+			t.Fatalf("update %v: %v", fpath, err)
+		}
+	}
+	exp, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		t.Fatalf("read %v: %v", fpath, err)
+	}
+	if string(exp) != string(b) {
+		t.Errorf(sf, name, 0, string(exp), string(b))
+	}
+}
+
+var confs = []struct {
+	Name string
+	C    wg.Conf
+}{
+	{
+		"server",
+		wg.Conf{
+			Interface: wg.Interface{
+				ListenPort: 51820,
+				FwMark:     "0xca6c",
+				PrivateKey: "this_is_a_private_key",
+				Address:    []string{"10.0.0.1/24", "fd00::1/64"},
+			},
+			Peers: []wg.Peer{
+				{PublicKey: "pubkey_a", PresharedKey: "psk_a", AllowedIPs: []string{"10.0.0.2/32", "fd00::2/128"}},
+				{PublicKey: "pubkey_b", AllowedIPs: []string{"10.0.0.3/32", "192.168.1.0/24"}, Endpoint: "[2001:db8::1]:51820", PersistentKeepalive: 25},
+			},
+		},
+	}, {
+		"branch",
+		wg.Conf{
+			Interface: wg.Interface{
+				PrivateKey: "this_is_a_private_key",
+				Address:    []string{"10.0.0.2/32"},
+				DNS:        []string{"10.0.0.1", "corp.example.com"},
+			},
+			Peers: []wg.Peer{
+				{PublicKey: "pubkey_server", AllowedIPs: []string{"10.0.0.0/24"}, Endpoint: "vpn.example.com:51820", PersistentKeepalive: 25},
+			},
+		},
+	},
+}
+
+func TestUCI(t *testing.T) {
+	for i, c := range confs {
+		b, err := UCI("wg0", c.C)
+		if err != nil {
+			t.Errorf(se, "UCI", i, err)
+			continue
+		}
+		golden(t, c.Name+".uci", b)
+	}
+}
+
+func TestRouterOS(t *testing.T) {
+	for i, c := range confs {
+		b, err := RouterOS("wg0", c.C)
+		if err != nil {
+			t.Errorf(se, "RouterOS", i, err)
+			continue
+		}
+		golden(t, c.Name+".rsc", b)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	bad := wg.Conf{Peers: []wg.Peer{{PublicKey: "pubkey", Endpoint: "no-port"}}}
+	if _, err := UCI("wg0", bad); err == nil {
+		t.Errorf(se, "UCI", 0, "expected error")
+	}
+	if _, err := RouterOS("wg0", bad); err == nil {
+		t.Errorf(se, "RouterOS", 0, "expected error")
+	}
+	if _, err := RouterOS("wg0", wg.Conf{Interface: wg.Interface{Address: []string{"10.0.0.1"}}}); err == nil {
+		t.Errorf(se, "RouterOS", 1, "expected error")
+	}
+}
+
+func TestQuote(t *testing.T) {
+	cases := []struct {
+		F   func(string) string
+		In  string
+		Exp string
+	}{
+		{uci, "it's", `'it'\''s'`},
+		{ros, `a"b$c\d`, `"a\"b\$c\\d"`},
+	}
+	for i, c := range cases {
+		if got := c.F(c.In); got != c.Exp {
+			t.Errorf(sf, "quote", i, c.Exp, got)
+		}
+	}
+}
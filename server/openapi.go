@@ -0,0 +1,197 @@
+package server
+
+// OpenAPI is the OpenAPI 3 spec of the API, served at /openapi.yaml
+const OpenAPI = `openapi: 3.0.3
+info:
+  title: go-wg management API
+  version: "1"
+security:
+  - bearer: []
+paths:
+  /v1/interfaces:
+    get:
+      summary: List interfaces
+      operationId: listInterfaces
+      responses:
+        "200":
+          description: interface names
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  type: string
+        default:
+          $ref: "#/components/responses/Error"
+  /v1/interfaces/{iface}:
+    parameters:
+      - $ref: "#/components/parameters/iface"
+    get:
+      summary: Get interface status, secrets are omitted
+      operationId: getInterface
+      responses:
+        "200":
+          description: interface status
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Conf"
+        default:
+          $ref: "#/components/responses/Error"
+  /v1/interfaces/{iface}/peers/{pubkey}:
+    parameters:
+      - $ref: "#/components/parameters/iface"
+      - name: pubkey
+        in: path
+        required: true
+        description: peer public key, base64url encoded
+        schema:
+          type: string
+    put:
+      summary: Add or update a peer, unset fields are unchanged
+      operationId: putPeer
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: "#/components/schemas/PeerRequest"
+      responses:
+        "200":
+          description: peer status
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Peer"
+        default:
+          $ref: "#/components/responses/Error"
+    delete:
+      summary: Remove a peer
+      operationId: deletePeer
+      responses:
+        "204":
+          description: removed
+        default:
+          $ref: "#/components/responses/Error"
+  /v1/interfaces/{iface}/clients:
+    parameters:
+      - $ref: "#/components/parameters/iface"
+    post:
+      summary: Generate a client, add it as a peer and download its config
+      operationId: createClient
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: "#/components/schemas/ClientRequest"
+      responses:
+        "201":
+          description: wg-quick config of the client
+          content:
+            text/plain:
+              schema:
+                type: string
+        default:
+          $ref: "#/components/responses/Error"
+  /v1/interfaces/{iface}/rotate:
+    parameters:
+      - $ref: "#/components/parameters/iface"
+    post:
+      summary: Replace the interface private key
+      operationId: rotateKey
+      responses:
+        "200":
+          description: new public key
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  public_key:
+                    type: string
+        default:
+          $ref: "#/components/responses/Error"
+components:
+  securitySchemes:
+    bearer:
+      type: http
+      scheme: bearer
+  parameters:
+    iface:
+      name: iface
+      in: path
+      required: true
+      schema:
+        type: string
+  responses:
+    Error:
+      description: error
+      content:
+        application/json:
+          schema:
+            type: object
+            properties:
+              error:
+                type: string
+  schemas:
+    Conf:
+      type: object
+      properties:
+        interface:
+          type: object
+          properties:
+            listen_port:
+              type: integer
+            fwmark:
+              type: string
+            public_key:
+              type: string
+        peers:
+          type: array
+          items:
+            $ref: "#/components/schemas/Peer"
+    Peer:
+      type: object
+      properties:
+        public_key:
+          type: string
+        allowed_ips:
+          type: array
+          items:
+            type: string
+        endpoint:
+          type: string
+        persistent_keepalive:
+          type: integer
+        latest_handshake:
+          type: string
+          format: date-time
+        received:
+          type: integer
+        sent:
+          type: integer
+    PeerRequest:
+      type: object
+      properties:
+        preshared_key:
+          type: string
+        endpoint:
+          type: string
+        persistent_keepalive:
+          type: integer
+        allowed_ips:
+          type: array
+          items:
+            type: string
+    ClientRequest:
+      type: object
+      properties:
+        full_tunnel:
+          type: boolean
+        allowed_ips:
+          type: array
+          items:
+            type: string
+        preshared_key:
+          type: boolean
+`
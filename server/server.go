@@ -0,0 +1,379 @@
+// Package server exposes interfaces and peers over an HTTP/JSON API,
+// see OpenAPI for the spec
+//
+//	GET    /v1/interfaces
+//	GET    /v1/interfaces/{iface}
+//	PUT    /v1/interfaces/{iface}/peers/{pubkey}
+//	DELETE /v1/interfaces/{iface}/peers/{pubkey}
+//	POST   /v1/interfaces/{iface}/clients
+//	POST   /v1/interfaces/{iface}/rotate
+//	GET    /openapi.yaml
+//
+// public keys in paths use base64url (RFC 4648 §5),
+// all /v1 requests need an Authorization: Bearer token
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+
+	wg "seankhliao.com/go-wg"
+	"seankhliao.com/go-wg/ipam"
+	"seankhliao.com/go-wg/provision"
+)
+
+// Network are the settings for generating client configs for an interface
+type Network struct {
+	Endpoint string   // host:port clients connect to
+	Prefixes []string // ip/mask client address pool
+	Reserve  []string // ip addresses in Prefixes not to hand out, eg the server's own
+	DNS      []string // client DNS servers and search domains
+}
+
+// Server is an http.Handler for the API
+type Server struct {
+	Client wg.Client
+	// Tokens are the accepted bearer tokens,
+	// no tokens rejects all requests
+	Tokens []string
+	// Networks enables client config generation per interface
+	Networks map[string]Network
+
+	// clientMu serializes client address allocation
+	clientMu sync.Mutex
+}
+
+// errHTTP is an error with a status code
+type errHTTP struct {
+	code int
+	err  error
+}
+
+func (e errHTTP) Error() string {
+	return e.err.Error()
+}
+
+func httpErr(code int, format string, a ...interface{}) error {
+	return errHTTP{code, fmt.Errorf(format, a...)}
+}
+
+func notAllowed(r *http.Request) error {
+	return httpErr(http.StatusMethodNotAllowed, "method %v not allowed", r.Method)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeErr(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	var he errHTTP
+	if errors.As(err, &he) {
+		code = he.code
+	}
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// authorized checks the bearer token in constant time
+func (s *Server) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return false
+	}
+	ok = false
+	for _, t := range s.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			ok = true
+		}
+	}
+	return ok
+}
+
+// ServeHTTP routes requests
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/openapi.yaml" {
+		if r.Method != http.MethodGet {
+			writeErr(w, notAllowed(r))
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write([]byte(OpenAPI))
+		return
+	}
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeErr(w, httpErr(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
+	err := s.route(w, r)
+	if err != nil {
+		writeErr(w, err)
+	}
+}
+
+func (s *Server) route(w http.ResponseWriter, r *http.Request) error {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "v1" || parts[1] != "interfaces" {
+		return httpErr(http.StatusNotFound, "no route for %v", r.URL.Path)
+	}
+	parts = parts[2:]
+
+	ctx := r.Context()
+	if len(parts) == 0 {
+		if r.Method != http.MethodGet {
+			return notAllowed(r)
+		}
+		ifaces, err := s.Client.ShowInterfaces(ctx)
+		if err != nil {
+			return err
+		}
+		writeJSON(w, http.StatusOK, ifaces)
+		return nil
+	}
+
+	iface := parts[0]
+	conf, err := s.show(ctx, iface)
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(parts) == 1:
+		if r.Method != http.MethodGet {
+			return notAllowed(r)
+		}
+		// never expose secrets
+		conf.PrivateKey = ""
+		for i := range conf.Peers {
+			conf.Peers[i].PresharedKey = ""
+		}
+		writeJSON(w, http.StatusOK, conf)
+		return nil
+	case len(parts) == 3 && parts[1] == "peers":
+		pub, err := base64.URLEncoding.DecodeString(parts[2])
+		if err != nil || len(pub) != 32 {
+			return httpErr(http.StatusBadRequest, "invalid public key %v", parts[2])
+		}
+		switch r.Method {
+		case http.MethodPut:
+			return s.putPeer(w, r, iface, base64.StdEncoding.EncodeToString(pub))
+		case http.MethodDelete:
+			return s.deletePeer(w, r, iface, conf, base64.StdEncoding.EncodeToString(pub))
+		}
+		return notAllowed(r)
+	case len(parts) == 2 && parts[1] == "clients":
+		if r.Method != http.MethodPost {
+			return notAllowed(r)
+		}
+		return s.client(w, r, iface)
+	case len(parts) == 2 && parts[1] == "rotate":
+		if r.Method != http.MethodPost {
+			return notAllowed(r)
+		}
+		return s.rotate(w, r, iface)
+	}
+	return httpErr(http.StatusNotFound, "no route for %v", r.URL.Path)
+}
+
+// show gets the live config, 404 for unknown interfaces
+func (s *Server) show(ctx context.Context, iface string) (wg.Conf, error) {
+	ifaces, err := s.Client.ShowInterfaces(ctx)
+	if err != nil {
+		return wg.Conf{}, err
+	}
+	for _, i := range ifaces {
+		if i == iface {
+			return s.Client.Show(ctx, iface)
+		}
+	}
+	return wg.Conf{}, httpErr(http.StatusNotFound, "no interface %v", iface)
+}
+
+// PeerRequest is the body of PUT /v1/interfaces/{iface}/peers/{pubkey},
+// unset fields are left unchanged
+type PeerRequest struct {
+	PresharedKey        string   `json:"preshared_key,omitempty"`
+	Endpoint            string   `json:"endpoint,omitempty"`
+	PersistentKeepalive *int     `json:"persistent_keepalive,omitempty"`
+	AllowedIPs          []string `json:"allowed_ips,omitempty"`
+}
+
+func (s *Server) putPeer(w http.ResponseWriter, r *http.Request, iface, pub string) error {
+	var req PeerRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return httpErr(http.StatusBadRequest, "decode request: %v", err)
+	}
+	op := wg.OptPeer{
+		PublicKey:           pub,
+		Endpoint:            req.Endpoint,
+		PersistentKeepalive: req.PersistentKeepalive,
+		AllowedIPs:          req.AllowedIPs,
+	}
+	if req.PresharedKey != "" {
+		b, err := base64.StdEncoding.DecodeString(req.PresharedKey)
+		if err != nil || len(b) != 32 {
+			return httpErr(http.StatusBadRequest, "invalid preshared_key")
+		}
+		keyFile, cleanup, err := wg.KeyFiles()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		op.PskFpath, err = keyFile(req.PresharedKey)
+		if err != nil {
+			return err
+		}
+	}
+	err = s.Client.Set(r.Context(), wg.Opt{Interface: iface, Peers: []wg.OptPeer{op}})
+	if err != nil {
+		return err
+	}
+	conf, err := s.Client.Show(r.Context(), iface)
+	if err != nil {
+		return err
+	}
+	for _, p := range conf.Peers {
+		if p.PublicKey == pub {
+			p.PresharedKey = ""
+			writeJSON(w, http.StatusOK, p)
+			return nil
+		}
+	}
+	return fmt.Errorf("peer %v not found after set", pub)
+}
+
+func (s *Server) deletePeer(w http.ResponseWriter, r *http.Request, iface string, conf wg.Conf, pub string) error {
+	var found bool
+	for _, p := range conf.Peers {
+		if p.PublicKey == pub {
+			found = true
+		}
+	}
+	if !found {
+		return httpErr(http.StatusNotFound, "no peer %v", pub)
+	}
+	err := s.Client.Set(r.Context(), wg.Opt{Interface: iface, Peers: []wg.OptPeer{{PublicKey: pub, Remove: true}}})
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// ClientRequest is the body of POST /v1/interfaces/{iface}/clients
+type ClientRequest struct {
+	FullTunnel   bool     `json:"full_tunnel,omitempty"`
+	AllowedIPs   []string `json:"allowed_ips,omitempty"`
+	PresharedKey bool     `json:"preshared_key,omitempty"`
+}
+
+// client generates a new client, adds it as a peer and responds with its wg-quick config
+func (s *Server) client(w http.ResponseWriter, r *http.Request, iface string) error {
+	network, ok := s.Networks[iface]
+	if !ok {
+		return httpErr(http.StatusNotFound, "client configs not enabled for %v", iface)
+	}
+	var req ClientRequest
+	if r.ContentLength != 0 {
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			return httpErr(http.StatusBadRequest, "decode request: %v", err)
+		}
+	}
+	for _, ip := range req.AllowedIPs {
+		if _, err := netip.ParsePrefix(ip); err != nil {
+			return httpErr(http.StatusBadRequest, "invalid allowed_ips: %v", err)
+		}
+	}
+
+	// allocation reads the live peers, so show through set must not interleave
+	s.clientMu.Lock()
+	defer s.clientMu.Unlock()
+	conf, err := s.show(r.Context(), iface)
+	if err != nil {
+		return err
+	}
+	pool, err := ipam.New(network.Prefixes...)
+	if err != nil {
+		return err
+	}
+	err = pool.Reserve(network.Reserve...)
+	if err != nil {
+		return err
+	}
+	cc, err := provision.GenerateClientConf(&conf, network.Endpoint, provision.Params{
+		IPAM:         pool,
+		DNS:          network.DNS,
+		FullTunnel:   req.FullTunnel,
+		AllowedIPs:   req.AllowedIPs,
+		PresharedKey: req.PresharedKey,
+	})
+	if errors.Is(err, ipam.ErrExhausted) {
+		return httpErr(http.StatusConflict, "%v", err)
+	} else if err != nil {
+		return err
+	}
+
+	p := conf.Peers[len(conf.Peers)-1]
+	op := wg.OptPeer{PublicKey: p.PublicKey, AllowedIPs: p.AllowedIPs}
+	keyFile, cleanup, err := wg.KeyFiles()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if p.PresharedKey != "" {
+		op.PskFpath, err = keyFile(p.PresharedKey)
+		if err != nil {
+			return err
+		}
+	}
+	err = s.Client.Set(r.Context(), wg.Opt{Interface: iface, Peers: []wg.OptPeer{op}})
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+iface+`.conf"`)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(cc.Bytes())
+	return nil
+}
+
+// rotate replaces the interface private key,
+// peers need the new public key before they can reconnect
+func (s *Server) rotate(w http.ResponseWriter, r *http.Request, iface string) error {
+	priv, err := wg.NewPrivateKey()
+	if err != nil {
+		return err
+	}
+	pub, err := wg.PublicKeyFor(priv)
+	if err != nil {
+		return err
+	}
+	keyFile, cleanup, err := wg.KeyFiles()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	fpath, err := keyFile(priv)
+	if err != nil {
+		return err
+	}
+	err = s.Client.Set(r.Context(), wg.Opt{Interface: iface, PrivKeyFpath: fpath})
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"public_key": pub})
+	return nil
+}
@@ -0,0 +1,260 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+	wg "seankhliao.com/go-wg"
+	"seankhliao.com/go-wg/wgtest"
+)
+
+var (
+	se = "%v #%v errored: %v"
+	sf = "%v #%v \nexp: >%v< \ngot: >%v<"
+
+	privA = "AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA="
+	pubA  = "B6N8vBQgk8i3VdwbEOhstCY3StFqqFPtC9/AsrhtHHw="
+	keyC  = "ICEiIyQlJicoKSorLC0uLzAxMjM0NTY3ODk6Ozw9Pj8=" // same in base64url
+
+	pubAURL = "B6N8vBQgk8i3VdwbEOhstCY3StFqqFPtC9_AsrhtHHw="
+)
+
+func newServer() (*wgtest.Client, *httptest.Server) {
+	fake := wgtest.NewClient(map[string]wg.Conf{
+		"wg0": {
+			Interface: wg.Interface{ListenPort: 51820, PrivateKey: privA, PublicKey: pubA},
+			Peers: []wg.Peer{
+				{PublicKey: keyC, PresharedKey: keyC, AllowedIPs: []string{"10.0.0.2/32"}},
+			},
+		},
+	})
+	ts := httptest.NewServer(&Server{
+		Client: fake,
+		Tokens: []string{"secret"},
+		Networks: map[string]Network{
+			"wg0": {Endpoint: "vpn.example.com:51820", Prefixes: []string{"10.0.0.0/24"}, Reserve: []string{"10.0.0.1"}, DNS: []string{"10.0.0.1"}},
+		},
+	})
+	return fake, ts
+}
+
+func do(t *testing.T, ts *httptest.Server, method, path, token, body string) (int, string) {
+	req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf(se, method+" "+path, 0, err)
+	}
+	defer res.Body.Close()
+	b, _ := ioutil.ReadAll(res.Body)
+	return res.StatusCode, string(b)
+}
+
+func TestAuth(t *testing.T) {
+	_, ts := newServer()
+	defer ts.Close()
+	cases := []struct {
+		Token string
+		Code  int
+	}{
+		{"", http.StatusUnauthorized},
+		{"wrong", http.StatusUnauthorized},
+		{"secret", http.StatusOK},
+	}
+	for i, c := range cases {
+		code, _ := do(t, ts, "GET", "/v1/interfaces", c.Token, "")
+		if code != c.Code {
+			t.Errorf(sf, "auth", i, c.Code, code)
+		}
+	}
+	ts2 := httptest.NewServer(&Server{})
+	defer ts2.Close()
+	if code, _ := do(t, ts2, "GET", "/v1/interfaces", "secret", ""); code != http.StatusUnauthorized {
+		t.Errorf(sf, "no tokens", 0, http.StatusUnauthorized, code)
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	fake, ts := newServer()
+	defer ts.Close()
+	cases := []struct {
+		Method, Path, Body string
+		Code               int
+		Contains           []string
+		Excludes           []string
+	}{
+		{"GET", "/v1/interfaces", "", 200, []string{`["wg0"]`}, nil},
+		{"GET", "/v1/interfaces/wg0", "", 200, []string{pubA, keyC, "10.0.0.2/32"}, []string{privA, "preshared_key"}},
+		{"GET", "/v1/interfaces/wg1", "", 404, nil, nil},
+		{"DELETE", "/v1/interfaces/wg0", "", 405, nil, nil},
+		{"PUT", "/v1/interfaces/wg0/peers/" + pubAURL, `{"endpoint":"1.2.3.4:51820","persistent_keepalive":25,"allowed_ips":["10.0.0.3/32"],"preshared_key":"` + keyC + `"}`, 200, []string{pubA, "1.2.3.4:51820", `"persistent_keepalive":25`}, []string{"preshared_key"}},
+		{"PUT", "/v1/interfaces/wg0/peers/" + pubAURL, `{"endpoint":"5.6.7.8:51820"}`, 200, []string{"5.6.7.8:51820", `"persistent_keepalive":25`, "10.0.0.3/32"}, nil},
+		{"PUT", "/v1/interfaces/wg0/peers/" + pubAURL, `{"preshared_key":"short"}`, 400, nil, nil},
+		{"PUT", "/v1/interfaces/wg0/peers/" + pubAURL, `not json`, 400, nil, nil},
+		{"PUT", "/v1/interfaces/wg0/peers/invalid", `{}`, 400, nil, nil},
+		{"DELETE", "/v1/interfaces/wg0/peers/" + keyC, "", 204, nil, nil},
+		{"DELETE", "/v1/interfaces/wg0/peers/" + keyC, "", 404, nil, nil},
+		{"GET", "/v1/interfaces/wg0/unknown", "", 404, nil, nil},
+		{"GET", "/v2", "", 404, nil, nil},
+	}
+	for i, c := range cases {
+		code, body := do(t, ts, c.Method, c.Path, "secret", c.Body)
+		if code != c.Code {
+			t.Errorf(sf, c.Method+" "+c.Path, i, c.Code, code)
+		}
+		for _, s := range c.Contains {
+			if !strings.Contains(body, s) {
+				t.Errorf(sf, c.Method+" "+c.Path, i, s, body)
+			}
+		}
+		for _, s := range c.Excludes {
+			if strings.Contains(body, s) {
+				t.Errorf(sf, c.Method+" "+c.Path+" excludes", i, s, body)
+			}
+		}
+	}
+
+	conf, _ := fake.Show(context.Background(), "wg0")
+	exp := []wg.Peer{{PublicKey: pubA, PresharedKey: keyC, AllowedIPs: []string{"10.0.0.3/32"}, Endpoint: "5.6.7.8:51820", PersistentKeepalive: 25}}
+	if !reflect.DeepEqual(conf.Peers, exp) {
+		t.Errorf(sf, "peers", 0, exp, conf.Peers)
+	}
+}
+
+func TestClient(t *testing.T) {
+	fake, ts := newServer()
+	defer ts.Close()
+
+	code, body := do(t, ts, "POST", "/v1/interfaces/wg0/clients", "secret", `{"preshared_key":true}`)
+	if code != http.StatusCreated {
+		t.Fatalf(sf, "clients", 0, http.StatusCreated, code)
+	}
+	cc, err := wg.NewConfBytes([]byte(body))
+	if err != nil {
+		t.Fatalf(se, "clients", 0, err)
+	}
+	// 10.0.0.1 reserved, 10.0.0.2 used by keyC
+	if !reflect.DeepEqual(cc.Address, []string{"10.0.0.3/32"}) || cc.Peers[0].PublicKey != pubA || cc.Peers[0].Endpoint != "vpn.example.com:51820" {
+		t.Errorf(sf, "clients", 0, "10.0.0.3/32 to "+pubA, body)
+	}
+
+	pub, _ := wg.PublicKeyFor(cc.PrivateKey)
+	conf, _ := fake.Show(context.Background(), "wg0")
+	last := conf.Peers[len(conf.Peers)-1]
+	if last.PublicKey != pub || last.PresharedKey != cc.Peers[0].PresharedKey || !reflect.DeepEqual(last.AllowedIPs, []string{"10.0.0.3/32"}) {
+		t.Errorf(sf, "clients peer", 0, pub, last)
+	}
+
+	fake2 := wgtest.NewClient(map[string]wg.Conf{"wg1": {}})
+	ts2 := httptest.NewServer(&Server{Client: fake2, Tokens: []string{"secret"}})
+	defer ts2.Close()
+	if code, _ := do(t, ts2, "POST", "/v1/interfaces/wg1/clients", "secret", ""); code != http.StatusNotFound {
+		t.Errorf(sf, "clients disabled", 0, http.StatusNotFound, code)
+	}
+
+	if code, _ := do(t, ts, "POST", "/v1/interfaces/wg0/clients", "secret", `{"allowed_ips":["10.1.0.0"]}`); code != http.StatusBadRequest {
+		t.Errorf(sf, "clients invalid", 0, http.StatusBadRequest, code)
+	}
+	ts3 := httptest.NewServer(&Server{
+		Client:   fake,
+		Tokens:   []string{"secret"},
+		Networks: map[string]Network{"wg0": {Endpoint: "vpn.example.com:51820", Prefixes: []string{"10.0.0.2/32"}}},
+	})
+	defer ts3.Close()
+	if code, _ := do(t, ts3, "POST", "/v1/interfaces/wg0/clients", "secret", ""); code != http.StatusConflict {
+		t.Errorf(sf, "clients exhausted", 0, http.StatusConflict, code)
+	}
+}
+
+func TestClientConcurrent(t *testing.T) {
+	fake, ts := newServer()
+	defer ts.Close()
+
+	n := 10
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			req, _ := http.NewRequest("POST", ts.URL+"/v1/interfaces/wg0/clients", nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			res, err := ts.Client().Do(req)
+			if err == nil {
+				res.Body.Close()
+			}
+			errs <- err
+		}()
+	}
+	for i := 0; i < n; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf(se, "clients", i, err)
+		}
+	}
+
+	conf, _ := fake.Show(context.Background(), "wg0")
+	seen := make(map[string]bool)
+	for _, p := range conf.Peers {
+		for _, ip := range p.AllowedIPs {
+			if seen[ip] {
+				t.Errorf(sf, "clients duplicate", 0, "unique addresses", ip)
+			}
+			seen[ip] = true
+		}
+	}
+	if len(conf.Peers) != n+1 {
+		t.Errorf(sf, "clients", 0, n+1, len(conf.Peers))
+	}
+}
+
+func TestRotate(t *testing.T) {
+	fake, ts := newServer()
+	defer ts.Close()
+	code, body := do(t, ts, "POST", "/v1/interfaces/wg0/rotate", "secret", "")
+	if code != http.StatusOK {
+		t.Fatalf(sf, "rotate", 0, http.StatusOK, code)
+	}
+	var res map[string]string
+	json.Unmarshal([]byte(body), &res)
+	conf, _ := fake.Show(context.Background(), "wg0")
+	pub, _ := wg.PublicKeyFor(conf.PrivateKey)
+	if conf.PrivateKey == privA || res["public_key"] != pub {
+		t.Errorf(sf, "rotate", 0, pub, res["public_key"])
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	_, ts := newServer()
+	defer ts.Close()
+	code, body := do(t, ts, "GET", "/openapi.yaml", "", "")
+	if code != http.StatusOK {
+		t.Fatalf(sf, "openapi", 0, http.StatusOK, code)
+	}
+	var spec struct {
+		Paths map[string]map[string]interface{} `yaml:"paths"`
+	}
+	err := yaml.Unmarshal([]byte(body), &spec)
+	if err != nil {
+		t.Fatalf(se, "openapi", 0, err)
+	}
+	exp := map[string][]string{
+		"/v1/interfaces":                        {"get"},
+		"/v1/interfaces/{iface}":                {"get"},
+		"/v1/interfaces/{iface}/peers/{pubkey}": {"put", "delete"},
+		"/v1/interfaces/{iface}/clients":        {"post"},
+		"/v1/interfaces/{iface}/rotate":         {"post"},
+	}
+	for path, methods := range exp {
+		for _, m := range methods {
+			if _, ok := spec.Paths[path][m]; !ok {
+				t.Errorf(sf, "openapi", 0, m+" "+path, "missing")
+			}
+		}
+	}
+}
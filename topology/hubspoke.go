@@ -0,0 +1,102 @@
+package topology
+
+import (
+	"fmt"
+
+	wg "seankhliao.com/go-wg"
+)
+
+// DefaultKeepalive is the PersistentKeepalive spokes send to hubs,
+// spokes are assumed to be behind NAT
+const DefaultKeepalive = 25
+
+// HubAndSpoke generates a hub and spoke network,
+// returns one Conf per node, hubs first then spokes.
+// Hubs need an Endpoint, they peer with each other and with every spoke.
+// Spokes only peer with hubs and send keepalives to them
+// (PersistentKeepalive defaults to DefaultKeepalive, -1 to disable).
+//
+// wg routes a prefix to a single peer,
+// so with multiple hubs (dual hub) the first hub is the primary
+// and spokes route the rest of the network through it,
+// other hubs only carry their own routes,
+// failing over means moving the primary's AllowedIPs to another hub
+func HubAndSpoke(hubs, spokes []Node, opts Options) ([]wg.Conf, error) {
+	if len(hubs) == 0 {
+		return nil, fmt.Errorf("hub and spoke: no hubs")
+	}
+	spokes = append([]Node{}, spokes...)
+	for i := range spokes {
+		switch spokes[i].PersistentKeepalive {
+		case 0:
+			spokes[i].PersistentKeepalive = DefaultKeepalive
+		case -1:
+			spokes[i].PersistentKeepalive = 0
+		}
+	}
+	nodes, err := prepare(append(append([]Node{}, hubs...), spokes...))
+	if err != nil {
+		return nil, err
+	}
+	hubs, spokes = nodes[:len(hubs)], nodes[len(hubs):]
+	for _, h := range hubs {
+		if h.Endpoint == "" {
+			return nil, fmt.Errorf("hub %v: no Endpoint", h.Name)
+		}
+	}
+	var keys pairKeys
+	if opts.PresharedKeys {
+		keys = make(pairKeys)
+	}
+
+	confs := make([]wg.Conf, len(nodes))
+	for i, h := range hubs {
+		confs[i].Interface = iface(h)
+		for j, o := range nodes {
+			if i == j {
+				continue
+			}
+			p, err := peer(h, o, allowedIPs(o), keys)
+			if err != nil {
+				return nil, err
+			}
+			confs[i].Peers = append(confs[i].Peers, p)
+		}
+	}
+	for i, s := range spokes {
+		c := &confs[len(hubs)+i]
+		c.Interface = iface(s)
+		for j, h := range hubs {
+			ips := allowedIPs(h)
+			if j == 0 {
+				// primary carries everything not routed to another hub
+				ips = nil
+				for _, o := range nodes {
+					if o.Name == s.Name || (o.Name != h.Name && isHub(hubs, o)) {
+						continue
+					}
+					ips = append(ips, allowedIPs(o)...)
+				}
+			}
+			p, err := peer(s, h, ips, keys)
+			if err != nil {
+				return nil, err
+			}
+			c.Peers = append(c.Peers, p)
+		}
+	}
+	err = Check(nodes, confs)
+	if err != nil {
+		return nil, err
+	}
+	return confs, nil
+}
+
+func isHub(hubs []Node, n Node) bool {
+	for _, h := range hubs {
+		if h.Name == n.Name {
+			return true
+		}
+	}
+	return false
+}
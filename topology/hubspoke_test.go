@@ -0,0 +1,120 @@
+package topology
+
+import (
+	"reflect"
+	"testing"
+
+	wg "seankhliao.com/go-wg"
+)
+
+func TestHubAndSpoke(t *testing.T) {
+	hubs := []Node{
+		{Name: "hub1", Endpoint: "hub1.example.com:51820", Address: []string{"10.0.0.1/24"}, Subnets: []string{"192.168.0.0/24"}},
+		{Name: "hub2", Endpoint: "hub2.example.com:51820", Address: []string{"10.0.0.2/24"}},
+	}
+	spokes := []Node{
+		{Name: "s1", Address: []string{"10.0.0.11/24"}, Subnets: []string{"192.168.11.0/24"}},
+		{Name: "s2", Address: []string{"10.0.0.12/24"}, PersistentKeepalive: -1},
+	}
+	confs, err := HubAndSpoke(hubs, spokes, Options{PresharedKeys: true})
+	if err != nil {
+		t.Fatalf(se, "HubAndSpoke", 0, err)
+	}
+	if spokes[1].PersistentKeepalive != -1 {
+		t.Errorf(sf, "HubAndSpoke modified input", 0, -1, spokes[1].PersistentKeepalive)
+	}
+
+	pubs := make(map[string]string)
+	for i, name := range []string{"hub1", "hub2", "s1", "s2"} {
+		pub, _ := wg.PublicKeyFor(confs[i].PrivateKey)
+		pubs[pub] = name
+	}
+
+	type peer struct {
+		Name      string
+		IPs       []string
+		Endpoint  string
+		Keepalive int
+	}
+	exp := [][]peer{
+		{
+			{"hub2", []string{"10.0.0.2/32"}, "hub2.example.com:51820", 0},
+			{"s1", []string{"10.0.0.11/32", "192.168.11.0/24"}, "", 0},
+			{"s2", []string{"10.0.0.12/32"}, "", 0},
+		}, {
+			{"hub1", []string{"10.0.0.1/32", "192.168.0.0/24"}, "hub1.example.com:51820", 0},
+			{"s1", []string{"10.0.0.11/32", "192.168.11.0/24"}, "", 0},
+			{"s2", []string{"10.0.0.12/32"}, "", 0},
+		}, {
+			{"hub1", []string{"10.0.0.1/32", "192.168.0.0/24", "10.0.0.12/32"}, "hub1.example.com:51820", DefaultKeepalive},
+			{"hub2", []string{"10.0.0.2/32"}, "hub2.example.com:51820", DefaultKeepalive},
+		}, {
+			{"hub1", []string{"10.0.0.1/32", "192.168.0.0/24", "10.0.0.11/32", "192.168.11.0/24"}, "hub1.example.com:51820", 0},
+			{"hub2", []string{"10.0.0.2/32"}, "hub2.example.com:51820", 0},
+		},
+	}
+	for i, c := range confs {
+		var got []peer
+		for _, p := range c.Peers {
+			got = append(got, peer{pubs[p.PublicKey], p.AllowedIPs, p.Endpoint, p.PersistentKeepalive})
+			if p.PresharedKey == "" {
+				t.Errorf(sf, "HubAndSpoke PresharedKey", i, "key", p.PresharedKey)
+			}
+		}
+		if !reflect.DeepEqual(got, exp[i]) {
+			t.Errorf(sf, "HubAndSpoke peers", i, exp[i], got)
+		}
+	}
+}
+
+func TestHubAndSpokeErrors(t *testing.T) {
+	cases := []struct {
+		Hubs, Spokes []Node
+	}{
+		{nil, []Node{{Name: "s1"}}},
+		{[]Node{{Name: "hub1"}}, []Node{{Name: "s1"}}},
+		{[]Node{{Name: "hub1", Endpoint: "hub1:1", Address: []string{"10.0.0.1/24"}}}, []Node{{Name: "s1", Address: []string{"10.0.0.1/24"}}}},
+	}
+	for i, c := range cases {
+		if _, err := HubAndSpoke(c.Hubs, c.Spokes, Options{}); err == nil {
+			t.Errorf(se, "HubAndSpoke", i, "expected error")
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	nodes := []Node{
+		{Name: "a", PrivateKey: privA, Endpoint: "a:1", Address: []string{"10.0.0.1/24"}},
+		{Name: "b", Address: []string{"10.0.0.2/24"}, Subnets: []string{"192.168.0.0/24"}},
+	}
+	confs, err := Mesh(nodes, Options{})
+	if err != nil {
+		t.Fatalf(se, "Mesh", 0, err)
+	}
+
+	cases := []struct {
+		Name string
+		F    func(c []wg.Conf)
+	}{
+		{"unreachable", func(c []wg.Conf) { c[0].Peers[0].AllowedIPs = []string{"10.0.0.2/32"} }},
+		{"overlap", func(c []wg.Conf) {
+			c[0].Peers = append(c[0].Peers, wg.Peer{PublicKey: pubA, AllowedIPs: []string{"192.168.0.0/16"}})
+		}},
+		{"unknown peer", func(c []wg.Conf) { c[1].Peers[0].PublicKey = "unknown" }},
+		{"no endpoint", func(c []wg.Conf) { c[1].Peers[0].Endpoint = "" }},
+	}
+	for i, c := range cases {
+		cp := make([]wg.Conf, len(confs))
+		for j, conf := range confs {
+			cp[j] = conf
+			cp[j].Peers = append([]wg.Peer{}, conf.Peers...)
+		}
+		c.F(cp)
+		if err := Check(nodes, cp); err == nil {
+			t.Errorf(se, c.Name, i, "expected error")
+		}
+	}
+	if err := Check(nodes, confs[:1]); err == nil {
+		t.Errorf(se, "length", 0, "expected error")
+	}
+}
@@ -0,0 +1,42 @@
+package topology
+
+import (
+	wg "seankhliao.com/go-wg"
+)
+
+// Mesh generates a full mesh: every node peers with every other node,
+// returns one Conf per node in the order of nodes,
+// with AllowedIPs of each peer being its tunnel host addresses and subnets,
+// pairs without an Endpoint on either side fail only with Options.Check
+func Mesh(nodes []Node, opts Options) ([]wg.Conf, error) {
+	nodes, err := prepare(nodes)
+	if err != nil {
+		return nil, err
+	}
+	var keys pairKeys
+	if opts.PresharedKeys {
+		keys = make(pairKeys)
+	}
+
+	confs := make([]wg.Conf, len(nodes))
+	for i, n := range nodes {
+		confs[i].Interface = iface(n)
+		for j, o := range nodes {
+			if i == j {
+				continue
+			}
+			p, err := peer(n, o, allowedIPs(o), keys)
+			if err != nil {
+				return nil, err
+			}
+			confs[i].Peers = append(confs[i].Peers, p)
+		}
+	}
+	if opts.Check {
+		err = Check(nodes, confs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return confs, nil
+}
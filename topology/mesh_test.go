@@ -0,0 +1,122 @@
+package topology
+
+import (
+	"reflect"
+	"testing"
+
+	wg "seankhliao.com/go-wg"
+)
+
+var (
+	se = "%v #%v errored: %v"
+	sf = "%v #%v \nexp: >%v< \ngot: >%v<"
+
+	privA = "AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA="
+	pubA  = "B6N8vBQgk8i3VdwbEOhstCY3StFqqFPtC9/AsrhtHHw="
+)
+
+func TestMesh(t *testing.T) {
+	nodes := []Node{
+		{Name: "a", PrivateKey: privA, PublicKey: pubA, Endpoint: "a.example.com:51820", Address: []string{"10.0.0.1/24"}, Subnets: []string{"192.168.1.0/24"}},
+		{Name: "b", Endpoint: "b.example.com:51821", Address: []string{"10.0.0.2/24", "fd00::2/64"}},
+		{Name: "c", Address: []string{"10.0.0.3/24"}, PersistentKeepalive: 25},
+	}
+	confs, err := Mesh(nodes, Options{PresharedKeys: true})
+	if err != nil {
+		t.Fatalf(se, "Mesh", 0, err)
+	}
+	if len(confs) != 3 {
+		t.Fatalf(sf, "Mesh confs", 0, 3, len(confs))
+	}
+
+	if confs[0].PrivateKey != privA || confs[0].ListenPort != 51820 || confs[1].ListenPort != 51821 || confs[2].ListenPort != 0 {
+		t.Errorf(sf, "Mesh interfaces", 0, "keys and ports", []wg.Interface{confs[0].Interface, confs[1].Interface, confs[2].Interface})
+	}
+
+	pubs := make([]string, 3)
+	for i, c := range confs {
+		pubs[i], _ = wg.PublicKeyFor(c.PrivateKey)
+	}
+	if pubs[0] != pubA {
+		t.Errorf(sf, "Mesh pubkey", 0, pubA, pubs[0])
+	}
+
+	ips := [][]string{
+		{"10.0.0.1/32", "192.168.1.0/24"},
+		{"10.0.0.2/32", "fd00::2/128"},
+		{"10.0.0.3/32"},
+	}
+	endpoints := []string{"a.example.com:51820", "b.example.com:51821", ""}
+	for i, c := range confs {
+		if len(c.Peers) != 2 {
+			t.Errorf(sf, "Mesh peers", i, 2, len(c.Peers))
+			continue
+		}
+		for _, p := range c.Peers {
+			j := 0
+			for pubs[j] != p.PublicKey {
+				j++
+			}
+			if j == i {
+				t.Errorf(sf, "Mesh self peer", i, "other", p.PublicKey)
+			}
+			if !reflect.DeepEqual(p.AllowedIPs, ips[j]) {
+				t.Errorf(sf, "Mesh AllowedIPs", i*10+j, ips[j], p.AllowedIPs)
+			}
+			if p.Endpoint != endpoints[j] {
+				t.Errorf(sf, "Mesh Endpoint", i*10+j, endpoints[j], p.Endpoint)
+			}
+			if p.PersistentKeepalive != nodes[i].PersistentKeepalive {
+				t.Errorf(sf, "Mesh PersistentKeepalive", i*10+j, nodes[i].PersistentKeepalive, p.PersistentKeepalive)
+			}
+			// same key on both sides of a pair
+			var back wg.Peer
+			for _, q := range confs[j].Peers {
+				if q.PublicKey == pubs[i] {
+					back = q
+				}
+			}
+			if p.PresharedKey == "" || p.PresharedKey != back.PresharedKey {
+				t.Errorf(sf, "Mesh PresharedKey", i*10+j, back.PresharedKey, p.PresharedKey)
+			}
+		}
+		if f := c.Validate(); len(f) != 0 {
+			t.Errorf(sf, "Mesh Validate", i, nil, f)
+		}
+	}
+	if confs[0].Peers[0].PresharedKey == confs[0].Peers[1].PresharedKey {
+		t.Errorf(sf, "Mesh PresharedKey unique", 0, "different", confs[0].Peers[0].PresharedKey)
+	}
+
+	confs, _ = Mesh(nodes, Options{})
+	if confs[0].Peers[0].PresharedKey != "" {
+		t.Errorf(sf, "Mesh no PresharedKey", 0, "", confs[0].Peers[0].PresharedKey)
+	}
+}
+
+func TestMeshErrors(t *testing.T) {
+	cases := [][]Node{
+		{{Name: "a"}, {Name: "a"}},
+		{{}},
+		{{Name: "a", PrivateKey: "bad"}},
+		{{Name: "a", PublicKey: pubA}},
+		{{Name: "a", PrivateKey: privA, PublicKey: privA}},
+		{{Name: "a", Endpoint: "no-port"}},
+		{{Name: "a", Address: []string{"10.0.0.1"}}},
+		{{Name: "a", Subnets: []string{"192.168.0.0/16"}}, {Name: "b", Address: []string{"192.168.1.1/24"}}},
+	}
+	for i, c := range cases {
+		if _, err := Mesh(c, Options{}); err == nil {
+			t.Errorf(se, "Mesh", i, "expected error")
+		}
+	}
+
+	// no Endpoint on either side is only checked on request
+	nodes := []Node{{Name: "a", Address: []string{"10.0.0.1/24"}}, {Name: "b", Address: []string{"10.0.0.2/24"}}}
+	if _, err := Mesh(nodes, Options{}); err != nil {
+		t.Errorf(se, "Mesh", len(cases), err)
+	}
+	if _, err := Mesh(nodes, Options{Check: true}); err == nil {
+		t.Errorf(se, "Mesh", len(cases)+1, "expected error")
+	}
+}
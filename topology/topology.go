@@ -0,0 +1,249 @@
+// Package topology generates per node configs for multi node networks
+package topology
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+
+	wg "seankhliao.com/go-wg"
+)
+
+// Node is a member of a network
+type Node struct {
+	Name       string
+	PrivateKey string // generated if empty
+	PublicKey  string // derived from PrivateKey, must match it if set
+	Endpoint   string // host:port other nodes connect to, empty if not reachable
+	ListenPort int    // defaults to the Endpoint port
+
+	Address []string // tunnel ip/mask
+	Subnets []string // ip/mask of networks routed through the node
+
+	PersistentKeepalive int // sent to this node's peers
+}
+
+// Options are common options for generators
+type Options struct {
+	// PresharedKeys generates a preshared key for every pair of peered nodes
+	PresharedKeys bool
+	// Check runs Check on the confs generated by Mesh,
+	// off by default as nodes without an Endpoint may still peer through other means, eg NAT traversal,
+	// HubAndSpoke always checks
+	Check bool
+}
+
+// prepare returns a copy of nodes with keys and ListenPort filled in,
+// names must be unique
+func prepare(nodes []Node) ([]Node, error) {
+	names := make(map[string]bool)
+	out := make([]Node, len(nodes))
+	for i, n := range nodes {
+		if n.Name == "" {
+			return nil, fmt.Errorf("node #%v: no Name", i)
+		}
+		if names[n.Name] {
+			return nil, fmt.Errorf("node %v: duplicate Name", n.Name)
+		}
+		names[n.Name] = true
+
+		var err error
+		if n.PrivateKey == "" {
+			if n.PublicKey != "" {
+				return nil, fmt.Errorf("node %v: PublicKey without PrivateKey", n.Name)
+			}
+			n.PrivateKey, err = wg.NewPrivateKey()
+			if err != nil {
+				return nil, fmt.Errorf("node %v: %v", n.Name, err)
+			}
+		}
+		pub, err := wg.PublicKeyFor(n.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("node %v: %v", n.Name, err)
+		}
+		if n.PublicKey != "" && n.PublicKey != pub {
+			return nil, fmt.Errorf("node %v: PublicKey doesn't match PrivateKey", n.Name)
+		}
+		n.PublicKey = pub
+		if n.ListenPort == 0 && n.Endpoint != "" {
+			_, port, err := net.SplitHostPort(n.Endpoint)
+			if err != nil {
+				return nil, fmt.Errorf("node %v: Endpoint: %v", n.Name, err)
+			}
+			n.ListenPort, err = strconv.Atoi(port)
+			if err != nil {
+				return nil, fmt.Errorf("node %v: Endpoint: %v", n.Name, err)
+			}
+		}
+		out[i] = n
+	}
+	return out, overlaps(out)
+}
+
+// routes are the prefixes other nodes send to n:
+// host prefixes of its tunnel addresses and its subnets
+func routes(n Node) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, a := range n.Address {
+		prefix, err := netip.ParsePrefix(a)
+		if err != nil {
+			return nil, fmt.Errorf("node %v: Address: %v", n.Name, err)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(prefix.Addr(), prefix.Addr().BitLen()))
+	}
+	for _, s := range n.Subnets {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("node %v: Subnets: %v", n.Name, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// allowedIPs formats the routes of nodes
+func allowedIPs(nodes ...Node) []string {
+	var ips []string
+	for _, n := range nodes {
+		prefixes, _ := routes(n)
+		for _, prefix := range prefixes {
+			ips = append(ips, prefix.String())
+		}
+	}
+	return ips
+}
+
+// overlaps checks that no two nodes claim overlapping routes
+func overlaps(nodes []Node) error {
+	all := make([][]netip.Prefix, len(nodes))
+	for i, n := range nodes {
+		var err error
+		all[i], err = routes(n)
+		if err != nil {
+			return err
+		}
+	}
+	for i := range nodes {
+		for j := i + 1; j < len(nodes); j++ {
+			for _, a := range all[i] {
+				for _, b := range all[j] {
+					if a.Overlaps(b) {
+						return fmt.Errorf("node %v %v overlaps node %v %v", nodes[i].Name, a, nodes[j].Name, b)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Check validates generated confs, one per node in the same order:
+// AllowedIPs must be valid and not conflict within a conf,
+// every node must route the tunnel addresses and subnets of every other node to some peer,
+// and at least one side of every peering must have an Endpoint
+func Check(nodes []Node, confs []wg.Conf) error {
+	if len(nodes) != len(confs) {
+		return fmt.Errorf("check: %v nodes for %v confs", len(nodes), len(confs))
+	}
+	pubs := make(map[string]int)
+	for i, c := range confs {
+		pub, err := wg.PublicKeyFor(c.PrivateKey)
+		if err != nil {
+			return fmt.Errorf("check: node %v: %v", nodes[i].Name, err)
+		}
+		pubs[pub] = i
+	}
+
+	for i, c := range confs {
+		for _, f := range c.Validate() {
+			switch f.Kind {
+			case wg.Invalid, wg.Duplicate, wg.Overlap:
+				return fmt.Errorf("check: node %v: %v", nodes[i].Name, f)
+			}
+		}
+
+		var allowed []netip.Prefix
+		for _, p := range c.Peers {
+			j, ok := pubs[p.PublicKey]
+			if !ok {
+				return fmt.Errorf("check: node %v: peer %v is not a node", nodes[i].Name, p.PublicKey)
+			}
+			if p.Endpoint == "" && nodes[i].Endpoint == "" {
+				return fmt.Errorf("check: node %v: peer %v: neither has an Endpoint", nodes[i].Name, nodes[j].Name)
+			}
+			for _, ip := range p.AllowedIPs {
+				prefix, _ := netip.ParsePrefix(ip)
+				allowed = append(allowed, prefix)
+			}
+		}
+
+		for j, o := range nodes {
+			if i == j {
+				continue
+			}
+			prefixes, err := routes(o)
+			if err != nil {
+				return fmt.Errorf("check: %v", err)
+			}
+		route:
+			for _, r := range prefixes {
+				for _, a := range allowed {
+					if a.Bits() <= r.Bits() && a.Contains(r.Addr()) {
+						continue route
+					}
+				}
+				return fmt.Errorf("check: node %v: %v of node %v is unreachable", nodes[i].Name, r, o.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// pairKeys holds preshared keys for pairs of nodes
+type pairKeys map[[2]string]string
+
+// get returns the key for nodes a and b, generating one if needed,
+// empty if disabled
+func (k pairKeys) get(a, b Node) (string, error) {
+	if k == nil {
+		return "", nil
+	}
+	pair := [2]string{a.Name, b.Name}
+	if b.Name < a.Name {
+		pair = [2]string{b.Name, a.Name}
+	}
+	if psk, ok := k[pair]; ok {
+		return psk, nil
+	}
+	psk, err := wg.NewPresharedKey()
+	if err != nil {
+		return "", err
+	}
+	k[pair] = psk
+	return psk, nil
+}
+
+// peer returns the Peer entry on node from for node to
+func peer(from, to Node, ips []string, keys pairKeys) (wg.Peer, error) {
+	psk, err := keys.get(from, to)
+	if err != nil {
+		return wg.Peer{}, fmt.Errorf("node %v peer %v: %v", from.Name, to.Name, err)
+	}
+	return wg.Peer{
+		PublicKey:           to.PublicKey,
+		PresharedKey:        psk,
+		AllowedIPs:          ips,
+		Endpoint:            to.Endpoint,
+		PersistentKeepalive: from.PersistentKeepalive,
+	}, nil
+}
+
+// iface returns the Interface for node
+func iface(n Node) wg.Interface {
+	return wg.Interface{
+		ListenPort: n.ListenPort,
+		PrivateKey: n.PrivateKey,
+		Address:    n.Address,
+	}
+}
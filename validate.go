@@ -0,0 +1,140 @@
+package wg
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// FindingKind is the type of problem found by Validate
+type FindingKind string
+
+// Kinds of findings
+const (
+	// AllowedIP could not be parsed
+	Invalid FindingKind = "invalid"
+	// AllowedIP is valid but not in canonical form, eg missing mask or non canonical ipv6
+	NonCanonical FindingKind = "non-canonical"
+	// AllowedIP has bits set after the mask, eg 10.0.0.1/24
+	HostBits FindingKind = "host-bits"
+	// AllowedIP is claimed by another peer (or twice by the same peer),
+	// wg gives it to the peer set last
+	Duplicate FindingKind = "duplicate"
+	// AllowedIP overlaps with another peer's,
+	// traffic goes to the most specific prefix
+	Overlap FindingKind = "overlap"
+)
+
+// Finding is a problem with a peer's AllowedIPs
+type Finding struct {
+	Kind      FindingKind
+	Peer      string // public key
+	AllowedIP string // as written
+	// Normalized is the canonical form of AllowedIP,
+	// empty for Invalid
+	Normalized string
+
+	// Duplicate and Overlap only
+	Other          string // public key of the conflicting peer
+	OtherAllowedIP string // as written
+	Winner         string // public key of the peer receiving the traffic
+}
+
+// String describes the finding
+func (f Finding) String() string {
+	switch f.Kind {
+	case Invalid:
+		return fmt.Sprintf("peer %v: invalid allowed ip %v", f.Peer, f.AllowedIP)
+	case NonCanonical, HostBits:
+		return fmt.Sprintf("peer %v: %v allowed ip %v, use %v", f.Peer, f.Kind, f.AllowedIP, f.Normalized)
+	default:
+		return fmt.Sprintf("peer %v: allowed ip %v %v with peer %v %v, %v wins", f.Peer, f.AllowedIP, f.Kind, f.Other, f.OtherAllowedIP, f.Winner)
+	}
+}
+
+// parseAllowedIP parses an ip/mask or a bare ip (treated as a host prefix)
+func parseAllowedIP(s string) (netip.Prefix, error) {
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		addr, aerr := netip.ParseAddr(s)
+		if aerr != nil {
+			return prefix, err
+		}
+		prefix = netip.PrefixFrom(addr, addr.BitLen())
+	}
+	return prefix, nil
+}
+
+// Normalize returns a copy of c with all valid AllowedIPs in canonical form,
+// invalid entries are kept as is
+func (c Conf) Normalize() Conf {
+	peers := c.Peers
+	c.Peers = nil
+	for _, p := range peers {
+		ips := p.AllowedIPs
+		p.AllowedIPs = nil
+		for _, ip := range ips {
+			if prefix, err := parseAllowedIP(ip); err == nil {
+				ip = prefix.Masked().String()
+			}
+			p.AllowedIPs = append(p.AllowedIPs, ip)
+		}
+		c.Peers = append(c.Peers, p)
+	}
+	return c
+}
+
+// Validate checks the AllowedIPs of all peers,
+// returns nil if there are no problems
+func (c Conf) Validate() []Finding {
+	type claim struct {
+		peer   string
+		ip     string
+		prefix netip.Prefix
+	}
+	var findings []Finding
+	var claims []claim
+	for _, p := range c.Peers {
+		for _, ip := range p.AllowedIPs {
+			prefix, err := parseAllowedIP(ip)
+			if err != nil {
+				findings = append(findings, Finding{Kind: Invalid, Peer: p.PublicKey, AllowedIP: ip})
+				continue
+			}
+			masked := prefix.Masked()
+			switch {
+			case masked != prefix:
+				findings = append(findings, Finding{Kind: HostBits, Peer: p.PublicKey, AllowedIP: ip, Normalized: masked.String()})
+			case masked.String() != ip:
+				findings = append(findings, Finding{Kind: NonCanonical, Peer: p.PublicKey, AllowedIP: ip, Normalized: masked.String()})
+			}
+
+			for _, cl := range claims {
+				if !cl.prefix.Overlaps(masked) {
+					continue
+				}
+				f := Finding{
+					Peer:           p.PublicKey,
+					AllowedIP:      ip,
+					Normalized:     masked.String(),
+					Other:          cl.peer,
+					OtherAllowedIP: cl.ip,
+				}
+				switch {
+				case cl.prefix == masked:
+					// later peer wins
+					f.Kind, f.Winner = Duplicate, p.PublicKey
+				case cl.peer == p.PublicKey:
+					// a peer overlapping itself is harmless
+					continue
+				case cl.prefix.Bits() > masked.Bits():
+					f.Kind, f.Winner = Overlap, cl.peer
+				default:
+					f.Kind, f.Winner = Overlap, p.PublicKey
+				}
+				findings = append(findings, f)
+			}
+			claims = append(claims, claim{p.PublicKey, ip, masked})
+		}
+	}
+	return findings
+}
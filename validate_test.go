@@ -0,0 +1,67 @@
+package wg
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Conf -> []Finding
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		C Conf
+		F []Finding
+	}{
+		{
+			Conf{},
+			nil,
+		}, {
+			Conf{
+				Peers: []Peer{
+					{PublicKey: "pubkey_a", AllowedIPs: []string{"10.0.0.2/32", "fd00::2/128"}},
+					{PublicKey: "pubkey_b", AllowedIPs: []string{"10.0.0.3/32", "0.0.0.0/0"}},
+				},
+			},
+			[]Finding{
+				{Kind: Overlap, Peer: "pubkey_b", AllowedIP: "0.0.0.0/0", Normalized: "0.0.0.0/0", Other: "pubkey_a", OtherAllowedIP: "10.0.0.2/32", Winner: "pubkey_a"},
+			},
+		}, {
+			Conf{
+				Peers: []Peer{
+					{PublicKey: "pubkey_a", AllowedIPs: []string{"10.0.0.1/24", "10.0.1.1", "fd00:0::1/128", "bad"}},
+					{PublicKey: "pubkey_b", AllowedIPs: []string{"10.0.1.1/32", "10.0.0.128/25"}},
+				},
+			},
+			[]Finding{
+				{Kind: HostBits, Peer: "pubkey_a", AllowedIP: "10.0.0.1/24", Normalized: "10.0.0.0/24"},
+				{Kind: NonCanonical, Peer: "pubkey_a", AllowedIP: "10.0.1.1", Normalized: "10.0.1.1/32"},
+				{Kind: NonCanonical, Peer: "pubkey_a", AllowedIP: "fd00:0::1/128", Normalized: "fd00::1/128"},
+				{Kind: Invalid, Peer: "pubkey_a", AllowedIP: "bad"},
+				{Kind: Duplicate, Peer: "pubkey_b", AllowedIP: "10.0.1.1/32", Normalized: "10.0.1.1/32", Other: "pubkey_a", OtherAllowedIP: "10.0.1.1", Winner: "pubkey_b"},
+				{Kind: Overlap, Peer: "pubkey_b", AllowedIP: "10.0.0.128/25", Normalized: "10.0.0.128/25", Other: "pubkey_a", OtherAllowedIP: "10.0.0.1/24", Winner: "pubkey_b"},
+			},
+		},
+	}
+	for i, c := range cases {
+		findings := c.C.Validate()
+		if !reflect.DeepEqual(findings, c.F) {
+			t.Errorf(sf, "Validate", i, c.F, findings)
+		}
+	}
+}
+
+// Conf -> Conf
+func TestNormalize(t *testing.T) {
+	c := Conf{
+		Peers: []Peer{
+			{PublicKey: "pubkey_a", AllowedIPs: []string{"10.0.0.1/24", "10.0.1.1", "fd00:0::1/128", "bad"}},
+		},
+	}
+	exp := []string{"10.0.0.0/24", "10.0.1.1/32", "fd00::1/128", "bad"}
+	n := c.Normalize()
+	if !reflect.DeepEqual(n.Peers[0].AllowedIPs, exp) {
+		t.Errorf(sf, "Normalize", 0, exp, n.Peers[0].AllowedIPs)
+	}
+	if c.Peers[0].AllowedIPs[0] != "10.0.0.1/24" {
+		t.Errorf(sf, "Normalize modified original", 0, "10.0.0.1/24", c.Peers[0].AllowedIPs[0])
+	}
+}
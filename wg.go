@@ -18,6 +18,10 @@ type Interface struct {
 	FwMark     string
 	PrivateKey string
 
+	// wg-quick only, not understood by wg setconf
+	Address []string // ip/mask
+	DNS     []string // servers and search domains
+
 	// Show only
 	PublicKey string
 }
@@ -34,6 +38,12 @@ func (i Interface) Bytes() []byte {
 	if i.PrivateKey != "" {
 		buf.WriteString("PrivateKey = " + i.PrivateKey + "\n")
 	}
+	if len(i.Address) != 0 {
+		buf.WriteString("Address = " + strings.Join(i.Address, ", ") + "\n")
+	}
+	if len(i.DNS) != 0 {
+		buf.WriteString("DNS = " + strings.Join(i.DNS, ", ") + "\n")
+	}
 	buf.WriteString("\n")
 	return buf.Bytes()
 }
@@ -84,64 +94,7 @@ type Conf struct {
 
 // NewConfBytes decodes bytes into a conf
 func NewConfBytes(bb []byte) (Conf, error) {
-	var err error
-	var c = Conf{}
-	var p int
-
-	lines := strings.Split(string(bb), "\n")
-	for i := 0; i < len(lines); i++ {
-		line := strings.TrimSpace(lines[i])
-		if line == "" {
-			continue
-		}
-		words := strings.SplitN(line, "=", 2)
-		if len(words) == 2 {
-			words[1] = strings.TrimSpace(words[1])
-		}
-		switch strings.TrimSpace(words[0]) {
-
-		// Interface
-		case "[Interface]":
-		case "ListenPort":
-			c.Interface.ListenPort, err = strconv.Atoi(words[1])
-			if err != nil {
-				return c, fmt.Errorf("error parsing ListenPort: %v", err)
-			}
-		case "FwMark":
-			c.Interface.FwMark = words[1]
-		case "PrivateKey":
-			c.Interface.PrivateKey = words[1]
-
-		// Peer
-		case "[Peer]":
-			c.Peers = append(c.Peers, Peer{})
-			p = len(c.Peers) - 1
-		case "PublicKey":
-			c.Peers[p].PublicKey = words[1]
-		case "Endpoint":
-			c.Peers[p].Endpoint = words[1]
-		case "AllowedIPs":
-			for _, ip := range strings.Split(words[1], ",") {
-				c.Peers[p].AllowedIPs = append(c.Peers[p].AllowedIPs, strings.TrimSpace(ip))
-			}
-		case "PresharedKey":
-			c.Peers[p].PresharedKey = words[1]
-		case "PersistentKeepalive":
-			if words[1] == "off" {
-				c.Peers[p].PersistentKeepalive = 0
-			} else {
-				c.Peers[p].PersistentKeepalive, err = strconv.Atoi(words[1])
-				if err != nil {
-					return c, fmt.Errorf("error parsing PersistentKeepalive: %v", err)
-				}
-			}
-
-		// Unknown key
-		default:
-			return c, fmt.Errorf("unknown key: %v", line)
-		}
-	}
-	return c, nil
+	return NewDecoder(bytes.NewReader(bb)).Decode()
 }
 
 // NewConfStatus decodes bytes into a conf
@@ -276,6 +229,40 @@ func (c Conf) Bytes() []byte {
 	return buf.Bytes()
 }
 
+// Strip returns c without the wg-quick only Address and DNS,
+// the form understood by wg setconf and wg addconf (wg-quick strip)
+func (c Conf) Strip() Conf {
+	c.Address, c.DNS = nil, nil
+	return c
+}
+
+// setconfFile returns the path to the conf file at fpath in a form wg setconf accepts,
+// a stripped copy is written to a temporary file if it has wg-quick only fields,
+// unresolved key references are rejected,
+// call cleanup to remove it
+func setconfFile(fpath string) (string, func(), error) {
+	c, err := LoadFile(fpath)
+	if err != nil {
+		return "", nil, err
+	}
+	if refs := c.KeyRefs(); len(refs) != 0 {
+		return "", nil, fmt.Errorf("%v: unresolved key references %v", fpath, strings.Join(refs, ", "))
+	}
+	if len(c.Address) == 0 && len(c.DNS) == 0 {
+		return fpath, func() {}, nil
+	}
+	tmpFile, cleanup, err := KeyFiles()
+	if err != nil {
+		return "", nil, err
+	}
+	stripped, err := tmpFile(string(c.Strip().Bytes()))
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return stripped, cleanup, nil
+}
+
 // Show the current status of an interface
 // wg show iface
 func Show(iface string) (Conf, error) {
@@ -414,35 +401,48 @@ func SetCtx(ctx context.Context, opt Opt) error {
 	return err
 }
 
-// SetConf set a conf file
-// ctx for process management
+// SetConf set a conf file,
+// wg-quick only fields are stripped, key references must be resolved
 // wg setconf iface fpath
 func SetConf(iface, fpath string) error {
 	return SetConfCtx(context.Background(), iface, fpath)
 }
 
-// SetConfCtx set a conf file
+// SetConfCtx set a conf file,
+// wg-quick only fields are stripped, key references must be resolved
 // ctx for process management
 // wg setconf iface fpath
 func SetConfCtx(ctx context.Context, iface, fpath string) error {
-	err := exec.CommandContext(ctx, Wg, "setconf", iface, fpath).Run()
+	fpath, cleanup, err := setconfFile(fpath)
+	if err != nil {
+		return fmt.Errorf("setconffile: %v", err)
+	}
+	defer cleanup()
+	err = exec.CommandContext(ctx, Wg, "setconf", iface, fpath).Run()
 	if err != nil {
 		err = fmt.Errorf("setconffile: %v", err)
 	}
 	return err
 }
 
-// AddConf add a conf file
+// AddConf add a conf file,
+// wg-quick only fields are stripped, key references must be resolved
 // wg addconf iface fpath
 func AddConf(iface, fpath string) error {
 	return AddConfCtx(context.Background(), iface, fpath)
 }
 
-// AddConfCtx add a conf file
+// AddConfCtx add a conf file,
+// wg-quick only fields are stripped, key references must be resolved
 // ctx for process management
 // wg addconf iface fpath
 func AddConfCtx(ctx context.Context, iface, fpath string) error {
-	err := exec.CommandContext(ctx, Wg, "addconf", iface, fpath).Run()
+	fpath, cleanup, err := setconfFile(fpath)
+	if err != nil {
+		return fmt.Errorf("addconffile: %v", err)
+	}
+	defer cleanup()
+	err = exec.CommandContext(ctx, Wg, "addconf", iface, fpath).Run()
 	if err != nil {
 		err = fmt.Errorf("addconffile: %v", err)
 	}
@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -39,12 +40,16 @@ PrivateKey = privateKeysAreBase64==
 				5678,
 				"afwmark",
 				"thisIsALongPrivateKey",
+				[]string{"10.0.0.2/24"},
+				[]string{"1.1.1.1", "example.com"},
 				"pubkey",
 			},
 			[]byte(`[Interface]
 ListenPort = 5678
 FwMark = afwmark
 PrivateKey = thisIsALongPrivateKey
+Address = 10.0.0.2/24
+DNS = 1.1.1.1, example.com
 
 `),
 		},
@@ -193,6 +198,7 @@ func TestNewConfBytes(t *testing.T) {
 					ListenPort: 5678,
 					FwMark:     "a_fwmark",
 					PrivateKey: "this_is_a_private_key",
+					DNS:        []string{"10.0.0.1", "fd00::1", "internal.example.com"},
 				},
 				[]Peer{
 					{
@@ -210,11 +216,15 @@ func TestNewConfBytes(t *testing.T) {
 					},
 				},
 			},
-			[]byte(`[Interface]
+			[]byte(`# managed
+[Interface]
 PrivateKey = this_is_a_private_key
-ListenPort = 5678
+ListenPort = 5678 # default + 1
 FwMark = a_fwmark
-[Peer]
+DNS = 10.0.0.1,fd00::1
+DNS = internal.example.com
+[Peer] # a
+	# PublicKey = pubkey_z
 	PublicKey = pubkey_a
 	PresharedKey=preshared_key_a
 	AllowedIPs = ip_range/1,ip_range/2 , ip_range/3
@@ -611,13 +621,13 @@ done
 
 func TestSetConf(t *testing.T) {
 	cases := []struct {
-		F string
+		C []byte
 		B []byte
 	}{
 		{
-			"/etc/wireguard/iface.conf",
+			[]byte("# managed\n[Interface]\nListenPort = 51820 # default\n"),
 			[]byte(`#!/usr/bin/env bash
-ans=( "setconf" "iface" "/etc/wireguard/iface.conf" )
+ans=( "setconf" "iface" "./test_set_conf.conf" )
 i=0
 for arg in $@; do
     if [ "$arg" != ${ans[$i]}  ]; then
@@ -626,36 +636,60 @@ for arg in $@; do
     i=$i+1
 done
 
+`),
+		}, {
+			// wg-quick only fields are stripped
+			[]byte("[Interface]\nListenPort = 51820\nAddress = 10.0.0.1/24\nDNS = 10.0.0.1\n"),
+			[]byte(`#!/usr/bin/env bash
+[ "$1" = "setconf" ] && [ "$2" = "iface" ] || exit 1
+grep -q "ListenPort = 51820" "$3" || exit 1
+grep -q -e Address -e DNS "$3" && exit 1
+exit 0
 `),
 		},
 	}
 	ltf := tf + "test_set_conf.sh"
+	lcf := tf + "test_set_conf.conf"
 	Wg = ltf
 	for i, c := range cases {
 		err := ioutil.WriteFile(ltf, c.B, 0755)
+		if err == nil {
+			err = ioutil.WriteFile(lcf, c.C, 0600)
+		}
 		if err != nil {
 			t.Errorf(sf, "SetConf setup", i, err)
 			continue
 		}
 		defer os.Remove(ltf)
+		defer os.Remove(lcf)
 
-		err = SetConf("iface", c.F)
+		err = SetConf("iface", lcf)
 		if err != nil {
 			t.Errorf(se, "SetConf", i, err)
 			continue
 		}
 	}
+	if err := SetConf("iface", tf+"test_set_conf.missing"); err == nil {
+		t.Errorf(se, "SetConf", len(cases), "expected error for missing file")
+	}
+	err := ioutil.WriteFile(lcf, []byte("[Interface]\nPrivateKey = keystore:wg0\n"), 0600)
+	if err != nil {
+		t.Fatalf(sf, "SetConf setup", len(cases)+1, err)
+	}
+	if err := SetConf("iface", lcf); err == nil || !strings.Contains(err.Error(), "key references") {
+		t.Errorf(sf, "SetConf", len(cases)+1, "unresolved key references", err)
+	}
 }
 
 func TestAddConf(t *testing.T) {
 	cases := []struct {
-		F string
+		C []byte
 		B []byte
 	}{
 		{
-			"/etc/wireguard/iface.conf",
+			[]byte("[Interface]\nListenPort = 51820\n"),
 			[]byte(`#!/usr/bin/env bash
-ans=( "addconf" "iface" "/etc/wireguard/iface.conf" )
+ans=( "addconf" "iface" "./test_add_conf.conf" )
 i=0
 for arg in $@; do
     if [ "$arg" != ${ans[$i]}  ]; then
@@ -664,20 +698,34 @@ for arg in $@; do
     i=$i+1
 done
 
+`),
+		}, {
+			// wg-quick only fields are stripped
+			[]byte("[Interface]\nListenPort = 51820\nAddress = 10.0.0.1/24\nDNS = 10.0.0.1\n"),
+			[]byte(`#!/usr/bin/env bash
+[ "$1" = "addconf" ] && [ "$2" = "iface" ] || exit 1
+grep -q "ListenPort = 51820" "$3" || exit 1
+grep -q -e Address -e DNS "$3" && exit 1
+exit 0
 `),
 		},
 	}
 	ltf := tf + "test_add_conf.sh"
+	lcf := tf + "test_add_conf.conf"
 	Wg = ltf
 	for i, c := range cases {
 		err := ioutil.WriteFile(ltf, c.B, 0755)
+		if err == nil {
+			err = ioutil.WriteFile(lcf, c.C, 0600)
+		}
 		if err != nil {
 			t.Errorf(sf, "AddConf setup", i, err)
 			continue
 		}
 		defer os.Remove(ltf)
+		defer os.Remove(lcf)
 
-		err = AddConf("iface", c.F)
+		err = AddConf("iface", lcf)
 		if err != nil {
 			t.Errorf(se, "AddConf", i, err)
 			continue
@@ -0,0 +1,160 @@
+package wgrpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/durationpb"
+	wg "seankhliao.com/go-wg"
+	"seankhliao.com/go-wg/wgrpc/wgpb"
+)
+
+// Client is a wg.Client calling a remote Server
+type Client struct {
+	c wgpb.WireGuardClient
+}
+
+// NewClient creates a Client using conn
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{wgpb.NewWireGuardClient(conn)}
+}
+
+func fromPeer(p *wgpb.Peer) wg.Peer {
+	return wg.Peer{
+		PublicKey:           p.GetPublicKey(),
+		AllowedIPs:          p.GetAllowedIps(),
+		Endpoint:            p.GetEndpoint(),
+		PersistentKeepalive: int(p.GetPersistentKeepalive()),
+		LatestHandshake:     p.GetLatestHandshake(),
+		Received:            p.GetReceived(),
+		Sent:                p.GetSent(),
+	}
+}
+
+// ShowInterfaces calls ListDevices
+func (c *Client) ShowInterfaces(ctx context.Context) ([]string, error) {
+	res, err := c.c.ListDevices(ctx, &wgpb.ListDevicesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("show interfaces: %w", err)
+	}
+	return res.GetNames(), nil
+}
+
+// Show calls GetDevice,
+// the returned Conf has no private or preshared keys
+func (c *Client) Show(ctx context.Context, iface string) (wg.Conf, error) {
+	d, err := c.c.GetDevice(ctx, &wgpb.GetDeviceRequest{Name: iface})
+	if err != nil {
+		return wg.Conf{}, fmt.Errorf("show: %w", err)
+	}
+	conf := wg.Conf{
+		Interface: wg.Interface{
+			ListenPort: int(d.GetListenPort()),
+			FwMark:     d.GetFwmark(),
+			PublicKey:  d.GetPublicKey(),
+		},
+	}
+	for _, p := range d.GetPeers() {
+		conf.Peers = append(conf.Peers, fromPeer(p))
+	}
+	return conf, nil
+}
+
+// readKey reads a key file
+func readKey(fpath string) (string, error) {
+	b, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// Set calls ConfigureDevice,
+// key files are read locally and sent to the server
+func (c *Client) Set(ctx context.Context, opt wg.Opt) error {
+	req := &wgpb.ConfigureDeviceRequest{
+		Name:       opt.Interface,
+		ListenPort: int32(opt.ListenPort),
+		Fwmark:     opt.FwMark,
+	}
+	var err error
+	if opt.PrivKeyFpath != "" {
+		req.PrivateKey, err = readKey(opt.PrivKeyFpath)
+		if err != nil {
+			return fmt.Errorf("set: %v", err)
+		}
+	}
+	for _, op := range opt.Peers {
+		p := &wgpb.PeerConfig{
+			PublicKey:  op.PublicKey,
+			Remove:     op.Remove,
+			Endpoint:   op.Endpoint,
+			AllowedIps: op.AllowedIPs,
+		}
+		if op.PersistentKeepalive != nil {
+			ka := int32(*op.PersistentKeepalive)
+			p.PersistentKeepalive = &ka
+		}
+		if op.PskFpath != "" {
+			p.PresharedKey, err = readKey(op.PskFpath)
+			if err != nil {
+				return fmt.Errorf("set: %v", err)
+			}
+		}
+		req.Peers = append(req.Peers, p)
+	}
+	_, err = c.c.ConfigureDevice(ctx, req)
+	if err != nil {
+		return fmt.Errorf("set: %w", err)
+	}
+	return nil
+}
+
+// GenerateKeyPair calls GenerateKeyPair,
+// psk is empty unless presharedKey is set
+func (c *Client) GenerateKeyPair(ctx context.Context, presharedKey bool) (priv, pub, psk string, err error) {
+	kp, err := c.c.GenerateKeyPair(ctx, &wgpb.GenerateKeyPairRequest{PresharedKey: presharedKey})
+	if err != nil {
+		return "", "", "", fmt.Errorf("generate key pair: %w", err)
+	}
+	return kp.GetPrivateKey(), kp.GetPublicKey(), kp.GetPresharedKey(), nil
+}
+
+// PeerEvent is a change to a peer
+type PeerEvent struct {
+	Type wgpb.PeerEvent_Type
+	Peer wg.Peer
+}
+
+// WatchPeers calls WatchPeers and calls f for every event until ctx is cancelled or f returns an error,
+// interval 0 uses the server default
+func (c *Client) WatchPeers(ctx context.Context, iface string, interval time.Duration, f func(PeerEvent) error) error {
+	req := &wgpb.WatchPeersRequest{Name: iface}
+	if interval != 0 {
+		req.Interval = durationpb.New(interval)
+	}
+	stream, err := c.c.WatchPeers(ctx, req)
+	if err != nil {
+		return fmt.Errorf("watch peers: %w", err)
+	}
+	for {
+		ev, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("watch peers: %w", err)
+		}
+		err = f(PeerEvent{Type: ev.GetType(), Peer: fromPeer(ev.GetPeer())})
+		if err != nil {
+			return err
+		}
+	}
+}
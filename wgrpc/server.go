@@ -0,0 +1,266 @@
+// Package wgrpc serves a wg.Client over gRPC (see wgpb/wg.proto)
+// and provides a wg.Client backed by a remote server
+package wgrpc
+
+import (
+	"context"
+	"encoding/base64"
+	"reflect"
+	"sort"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	wg "seankhliao.com/go-wg"
+	"seankhliao.com/go-wg/wgrpc/wgpb"
+)
+
+const (
+	// DefaultInterval is the WatchPeers interval when the request doesn't set one
+	DefaultInterval = 5 * time.Second
+	// DefaultMinInterval is the default smallest WatchPeers interval the server accepts
+	DefaultMinInterval = time.Second
+)
+
+// Server implements wgpb.WireGuardServer with a wg.Client
+type Server struct {
+	wgpb.UnimplementedWireGuardServer
+	Client wg.Client
+	// MinInterval is the smallest WatchPeers interval, defaults to DefaultMinInterval
+	MinInterval time.Duration
+}
+
+// Register registers s on gs
+func (s *Server) Register(gs *grpc.Server) {
+	wgpb.RegisterWireGuardServer(gs, s)
+}
+
+// show gets the live config, NotFound for unknown interfaces
+func (s *Server) show(ctx context.Context, iface string) (wg.Conf, error) {
+	ifaces, err := s.Client.ShowInterfaces(ctx)
+	if err != nil {
+		return wg.Conf{}, status.Error(codes.Internal, err.Error())
+	}
+	for _, i := range ifaces {
+		if i == iface {
+			c, err := s.Client.Show(ctx, iface)
+			if err != nil {
+				return wg.Conf{}, status.Error(codes.Internal, err.Error())
+			}
+			return c, nil
+		}
+	}
+	return wg.Conf{}, status.Errorf(codes.NotFound, "no interface %v", iface)
+}
+
+func toPeer(p wg.Peer) *wgpb.Peer {
+	return &wgpb.Peer{
+		PublicKey:           p.PublicKey,
+		AllowedIps:          p.AllowedIPs,
+		Endpoint:            p.Endpoint,
+		PersistentKeepalive: int32(p.PersistentKeepalive),
+		LatestHandshake:     p.LatestHandshake,
+		Received:            p.Received,
+		Sent:                p.Sent,
+	}
+}
+
+// ListDevices calls ShowInterfaces
+func (s *Server) ListDevices(ctx context.Context, req *wgpb.ListDevicesRequest) (*wgpb.ListDevicesResponse, error) {
+	ifaces, err := s.Client.ShowInterfaces(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &wgpb.ListDevicesResponse{Names: ifaces}, nil
+}
+
+// GetDevice calls Show
+func (s *Server) GetDevice(ctx context.Context, req *wgpb.GetDeviceRequest) (*wgpb.Device, error) {
+	c, err := s.show(ctx, req.GetName())
+	if err != nil {
+		return nil, err
+	}
+	d := &wgpb.Device{
+		Name:       req.GetName(),
+		ListenPort: int32(c.ListenPort),
+		Fwmark:     c.FwMark,
+		PublicKey:  c.PublicKey,
+	}
+	for _, p := range c.Peers {
+		d.Peers = append(d.Peers, toPeer(p))
+	}
+	return d, nil
+}
+
+// validKey checks for a base64 encoded 32 byte key
+func validKey(key string) bool {
+	b, err := base64.StdEncoding.DecodeString(key)
+	return err == nil && len(b) == 32
+}
+
+// ConfigureDevice calls Set, writing keys to temporary files
+func (s *Server) ConfigureDevice(ctx context.Context, req *wgpb.ConfigureDeviceRequest) (*wgpb.ConfigureDeviceResponse, error) {
+	if _, err := s.show(ctx, req.GetName()); err != nil {
+		return nil, err
+	}
+	keyFile, cleanup, err := wg.KeyFiles()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	defer cleanup()
+
+	opt := wg.Opt{
+		Interface:  req.GetName(),
+		ListenPort: int(req.GetListenPort()),
+		FwMark:     req.GetFwmark(),
+	}
+	if req.GetPrivateKey() != "" {
+		if !validKey(req.GetPrivateKey()) {
+			return nil, status.Error(codes.InvalidArgument, "invalid private_key")
+		}
+		opt.PrivKeyFpath, err = keyFile(req.GetPrivateKey())
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+	for _, p := range req.GetPeers() {
+		if !validKey(p.GetPublicKey()) {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid public_key %v", p.GetPublicKey())
+		}
+		op := wg.OptPeer{
+			PublicKey:  p.GetPublicKey(),
+			Remove:     p.GetRemove(),
+			Endpoint:   p.GetEndpoint(),
+			AllowedIPs: p.GetAllowedIps(),
+		}
+		if p.PersistentKeepalive != nil {
+			ka := int(p.GetPersistentKeepalive())
+			op.PersistentKeepalive = &ka
+		}
+		if p.GetPresharedKey() != "" {
+			if !validKey(p.GetPresharedKey()) {
+				return nil, status.Errorf(codes.InvalidArgument, "peer %v: invalid preshared_key", p.GetPublicKey())
+			}
+			op.PskFpath, err = keyFile(p.GetPresharedKey())
+			if err != nil {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+		}
+		opt.Peers = append(opt.Peers, op)
+	}
+	err = s.Client.Set(ctx, opt)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &wgpb.ConfigureDeviceResponse{}, nil
+}
+
+// GenerateKeyPair generates keys natively
+func (s *Server) GenerateKeyPair(ctx context.Context, req *wgpb.GenerateKeyPairRequest) (*wgpb.KeyPair, error) {
+	var kp wgpb.KeyPair
+	var err error
+	kp.PrivateKey, err = wg.NewPrivateKey()
+	if err == nil {
+		kp.PublicKey, err = wg.PublicKeyFor(kp.PrivateKey)
+	}
+	if err == nil && req.GetPresharedKey() {
+		kp.PresharedKey, err = wg.NewPresharedKey()
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &kp, nil
+}
+
+// WatchPeers shows the interface every interval and sends the differences
+func (s *Server) WatchPeers(req *wgpb.WatchPeersRequest, stream grpc.ServerStreamingServer[wgpb.PeerEvent]) error {
+	interval := DefaultInterval
+	if req.GetInterval() != nil {
+		interval = req.GetInterval().AsDuration()
+		min := s.MinInterval
+		if min == 0 {
+			min = DefaultMinInterval
+		}
+		if interval < min {
+			interval = min
+		}
+	}
+	ctx := stream.Context()
+	c, err := s.show(ctx, req.GetName())
+	if err != nil {
+		return err
+	}
+	last := make(map[string]wg.Peer)
+	err = s.sendDiff(stream, last, c.Peers)
+	if err != nil {
+		return err
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+		c, err := s.show(ctx, req.GetName())
+		if err != nil {
+			return err
+		}
+		err = s.sendDiff(stream, last, c.Peers)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// sendDiff sends events for the differences between last and peers,
+// updating last
+func (s *Server) sendDiff(stream grpc.ServerStreamingServer[wgpb.PeerEvent], last map[string]wg.Peer, peers []wg.Peer) error {
+	seen := make(map[string]bool)
+	for _, p := range peers {
+		seen[p.PublicKey] = true
+		p.PresharedKey = ""
+		typ := wgpb.PeerEvent_UPDATED
+		if old, ok := last[p.PublicKey]; !ok {
+			typ = wgpb.PeerEvent_ADDED
+		} else if !changed(old, p) {
+			continue
+		}
+		last[p.PublicKey] = p
+		err := stream.Send(&wgpb.PeerEvent{Type: typ, Peer: toPeer(p)})
+		if err != nil {
+			return err
+		}
+	}
+	var removed []string
+	for pub := range last {
+		if !seen[pub] {
+			removed = append(removed, pub)
+		}
+	}
+	sort.Strings(removed)
+	for _, pub := range removed {
+		p := last[pub]
+		delete(last, pub)
+		err := stream.Send(&wgpb.PeerEvent{Type: wgpb.PeerEvent_REMOVED, Peer: toPeer(p)})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// changed reports whether a peer changed other than by its handshake aging,
+// LatestHandshake is seconds ago so it grows on every poll,
+// only going from never to some handshake counts,
+// new handshakes also show up as traffic in Received and Sent
+func changed(old, p wg.Peer) bool {
+	if (old.LatestHandshake == 0) != (p.LatestHandshake == 0) {
+		return true
+	}
+	old.LatestHandshake, p.LatestHandshake = 0, 0
+	return !reflect.DeepEqual(old, p)
+}
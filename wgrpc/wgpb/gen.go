@@ -0,0 +1,4 @@
+// Package wgpb holds the generated protobuf and gRPC code for wg.proto
+package wgpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative wg.proto
@@ -0,0 +1,931 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: wg.proto
+
+package wgpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type PeerEvent_Type int32
+
+const (
+	PeerEvent_TYPE_UNSPECIFIED PeerEvent_Type = 0
+	PeerEvent_ADDED            PeerEvent_Type = 1
+	PeerEvent_UPDATED          PeerEvent_Type = 2
+	PeerEvent_REMOVED          PeerEvent_Type = 3
+)
+
+// Enum value maps for PeerEvent_Type.
+var (
+	PeerEvent_Type_name = map[int32]string{
+		0: "TYPE_UNSPECIFIED",
+		1: "ADDED",
+		2: "UPDATED",
+		3: "REMOVED",
+	}
+	PeerEvent_Type_value = map[string]int32{
+		"TYPE_UNSPECIFIED": 0,
+		"ADDED":            1,
+		"UPDATED":          2,
+		"REMOVED":          3,
+	}
+)
+
+func (x PeerEvent_Type) Enum() *PeerEvent_Type {
+	p := new(PeerEvent_Type)
+	*p = x
+	return p
+}
+
+func (x PeerEvent_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PeerEvent_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_wg_proto_enumTypes[0].Descriptor()
+}
+
+func (PeerEvent_Type) Type() protoreflect.EnumType {
+	return &file_wg_proto_enumTypes[0]
+}
+
+func (x PeerEvent_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PeerEvent_Type.Descriptor instead.
+func (PeerEvent_Type) EnumDescriptor() ([]byte, []int) {
+	return file_wg_proto_rawDescGZIP(), []int{11, 0}
+}
+
+type ListDevicesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDevicesRequest) Reset() {
+	*x = ListDevicesRequest{}
+	mi := &file_wg_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDevicesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDevicesRequest) ProtoMessage() {}
+
+func (x *ListDevicesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wg_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDevicesRequest.ProtoReflect.Descriptor instead.
+func (*ListDevicesRequest) Descriptor() ([]byte, []int) {
+	return file_wg_proto_rawDescGZIP(), []int{0}
+}
+
+type ListDevicesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Names         []string               `protobuf:"bytes,1,rep,name=names,proto3" json:"names,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDevicesResponse) Reset() {
+	*x = ListDevicesResponse{}
+	mi := &file_wg_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDevicesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDevicesResponse) ProtoMessage() {}
+
+func (x *ListDevicesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wg_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDevicesResponse.ProtoReflect.Descriptor instead.
+func (*ListDevicesResponse) Descriptor() ([]byte, []int) {
+	return file_wg_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListDevicesResponse) GetNames() []string {
+	if x != nil {
+		return x.Names
+	}
+	return nil
+}
+
+type GetDeviceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDeviceRequest) Reset() {
+	*x = GetDeviceRequest{}
+	mi := &file_wg_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDeviceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDeviceRequest) ProtoMessage() {}
+
+func (x *GetDeviceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wg_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDeviceRequest.ProtoReflect.Descriptor instead.
+func (*GetDeviceRequest) Descriptor() ([]byte, []int) {
+	return file_wg_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetDeviceRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type Device struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	ListenPort    int32                  `protobuf:"varint,2,opt,name=listen_port,json=listenPort,proto3" json:"listen_port,omitempty"`
+	Fwmark        string                 `protobuf:"bytes,3,opt,name=fwmark,proto3" json:"fwmark,omitempty"`
+	PublicKey     string                 `protobuf:"bytes,4,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	Peers         []*Peer                `protobuf:"bytes,5,rep,name=peers,proto3" json:"peers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Device) Reset() {
+	*x = Device{}
+	mi := &file_wg_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Device) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Device) ProtoMessage() {}
+
+func (x *Device) ProtoReflect() protoreflect.Message {
+	mi := &file_wg_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Device.ProtoReflect.Descriptor instead.
+func (*Device) Descriptor() ([]byte, []int) {
+	return file_wg_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Device) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Device) GetListenPort() int32 {
+	if x != nil {
+		return x.ListenPort
+	}
+	return 0
+}
+
+func (x *Device) GetFwmark() string {
+	if x != nil {
+		return x.Fwmark
+	}
+	return ""
+}
+
+func (x *Device) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+func (x *Device) GetPeers() []*Peer {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+type Peer struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	PublicKey           string                 `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	AllowedIps          []string               `protobuf:"bytes,2,rep,name=allowed_ips,json=allowedIps,proto3" json:"allowed_ips,omitempty"`
+	Endpoint            string                 `protobuf:"bytes,3,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	PersistentKeepalive int32                  `protobuf:"varint,4,opt,name=persistent_keepalive,json=persistentKeepalive,proto3" json:"persistent_keepalive,omitempty"`
+	// seconds since the latest handshake, 0 for never
+	LatestHandshake int64 `protobuf:"varint,5,opt,name=latest_handshake,json=latestHandshake,proto3" json:"latest_handshake,omitempty"`
+	Received        int64 `protobuf:"varint,6,opt,name=received,proto3" json:"received,omitempty"`
+	Sent            int64 `protobuf:"varint,7,opt,name=sent,proto3" json:"sent,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Peer) Reset() {
+	*x = Peer{}
+	mi := &file_wg_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Peer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Peer) ProtoMessage() {}
+
+func (x *Peer) ProtoReflect() protoreflect.Message {
+	mi := &file_wg_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Peer.ProtoReflect.Descriptor instead.
+func (*Peer) Descriptor() ([]byte, []int) {
+	return file_wg_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Peer) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+func (x *Peer) GetAllowedIps() []string {
+	if x != nil {
+		return x.AllowedIps
+	}
+	return nil
+}
+
+func (x *Peer) GetEndpoint() string {
+	if x != nil {
+		return x.Endpoint
+	}
+	return ""
+}
+
+func (x *Peer) GetPersistentKeepalive() int32 {
+	if x != nil {
+		return x.PersistentKeepalive
+	}
+	return 0
+}
+
+func (x *Peer) GetLatestHandshake() int64 {
+	if x != nil {
+		return x.LatestHandshake
+	}
+	return 0
+}
+
+func (x *Peer) GetReceived() int64 {
+	if x != nil {
+		return x.Received
+	}
+	return 0
+}
+
+func (x *Peer) GetSent() int64 {
+	if x != nil {
+		return x.Sent
+	}
+	return 0
+}
+
+// ConfigureDeviceRequest mirrors Opt with keys instead of key files,
+// unset fields are left unchanged
+type ConfigureDeviceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	ListenPort    int32                  `protobuf:"varint,2,opt,name=listen_port,json=listenPort,proto3" json:"listen_port,omitempty"`
+	Fwmark        string                 `protobuf:"bytes,3,opt,name=fwmark,proto3" json:"fwmark,omitempty"`
+	PrivateKey    string                 `protobuf:"bytes,4,opt,name=private_key,json=privateKey,proto3" json:"private_key,omitempty"`
+	Peers         []*PeerConfig          `protobuf:"bytes,5,rep,name=peers,proto3" json:"peers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfigureDeviceRequest) Reset() {
+	*x = ConfigureDeviceRequest{}
+	mi := &file_wg_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfigureDeviceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigureDeviceRequest) ProtoMessage() {}
+
+func (x *ConfigureDeviceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wg_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfigureDeviceRequest.ProtoReflect.Descriptor instead.
+func (*ConfigureDeviceRequest) Descriptor() ([]byte, []int) {
+	return file_wg_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ConfigureDeviceRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ConfigureDeviceRequest) GetListenPort() int32 {
+	if x != nil {
+		return x.ListenPort
+	}
+	return 0
+}
+
+func (x *ConfigureDeviceRequest) GetFwmark() string {
+	if x != nil {
+		return x.Fwmark
+	}
+	return ""
+}
+
+func (x *ConfigureDeviceRequest) GetPrivateKey() string {
+	if x != nil {
+		return x.PrivateKey
+	}
+	return ""
+}
+
+func (x *ConfigureDeviceRequest) GetPeers() []*PeerConfig {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+// PeerConfig mirrors OptPeer
+type PeerConfig struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	PublicKey           string                 `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	Remove              bool                   `protobuf:"varint,2,opt,name=remove,proto3" json:"remove,omitempty"`
+	PresharedKey        string                 `protobuf:"bytes,3,opt,name=preshared_key,json=presharedKey,proto3" json:"preshared_key,omitempty"`
+	Endpoint            string                 `protobuf:"bytes,4,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	PersistentKeepalive *int32                 `protobuf:"varint,5,opt,name=persistent_keepalive,json=persistentKeepalive,proto3,oneof" json:"persistent_keepalive,omitempty"`
+	AllowedIps          []string               `protobuf:"bytes,6,rep,name=allowed_ips,json=allowedIps,proto3" json:"allowed_ips,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *PeerConfig) Reset() {
+	*x = PeerConfig{}
+	mi := &file_wg_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PeerConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerConfig) ProtoMessage() {}
+
+func (x *PeerConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_wg_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerConfig.ProtoReflect.Descriptor instead.
+func (*PeerConfig) Descriptor() ([]byte, []int) {
+	return file_wg_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *PeerConfig) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+func (x *PeerConfig) GetRemove() bool {
+	if x != nil {
+		return x.Remove
+	}
+	return false
+}
+
+func (x *PeerConfig) GetPresharedKey() string {
+	if x != nil {
+		return x.PresharedKey
+	}
+	return ""
+}
+
+func (x *PeerConfig) GetEndpoint() string {
+	if x != nil {
+		return x.Endpoint
+	}
+	return ""
+}
+
+func (x *PeerConfig) GetPersistentKeepalive() int32 {
+	if x != nil && x.PersistentKeepalive != nil {
+		return *x.PersistentKeepalive
+	}
+	return 0
+}
+
+func (x *PeerConfig) GetAllowedIps() []string {
+	if x != nil {
+		return x.AllowedIps
+	}
+	return nil
+}
+
+type ConfigureDeviceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfigureDeviceResponse) Reset() {
+	*x = ConfigureDeviceResponse{}
+	mi := &file_wg_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfigureDeviceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigureDeviceResponse) ProtoMessage() {}
+
+func (x *ConfigureDeviceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wg_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfigureDeviceResponse.ProtoReflect.Descriptor instead.
+func (*ConfigureDeviceResponse) Descriptor() ([]byte, []int) {
+	return file_wg_proto_rawDescGZIP(), []int{7}
+}
+
+type GenerateKeyPairRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// also generate a preshared key
+	PresharedKey  bool `protobuf:"varint,1,opt,name=preshared_key,json=presharedKey,proto3" json:"preshared_key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateKeyPairRequest) Reset() {
+	*x = GenerateKeyPairRequest{}
+	mi := &file_wg_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateKeyPairRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateKeyPairRequest) ProtoMessage() {}
+
+func (x *GenerateKeyPairRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wg_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateKeyPairRequest.ProtoReflect.Descriptor instead.
+func (*GenerateKeyPairRequest) Descriptor() ([]byte, []int) {
+	return file_wg_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GenerateKeyPairRequest) GetPresharedKey() bool {
+	if x != nil {
+		return x.PresharedKey
+	}
+	return false
+}
+
+type KeyPair struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PrivateKey    string                 `protobuf:"bytes,1,opt,name=private_key,json=privateKey,proto3" json:"private_key,omitempty"`
+	PublicKey     string                 `protobuf:"bytes,2,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	PresharedKey  string                 `protobuf:"bytes,3,opt,name=preshared_key,json=presharedKey,proto3" json:"preshared_key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KeyPair) Reset() {
+	*x = KeyPair{}
+	mi := &file_wg_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeyPair) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyPair) ProtoMessage() {}
+
+func (x *KeyPair) ProtoReflect() protoreflect.Message {
+	mi := &file_wg_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyPair.ProtoReflect.Descriptor instead.
+func (*KeyPair) Descriptor() ([]byte, []int) {
+	return file_wg_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *KeyPair) GetPrivateKey() string {
+	if x != nil {
+		return x.PrivateKey
+	}
+	return ""
+}
+
+func (x *KeyPair) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+func (x *KeyPair) GetPresharedKey() string {
+	if x != nil {
+		return x.PresharedKey
+	}
+	return ""
+}
+
+type WatchPeersRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// time between shows, the server may enforce a minimum
+	Interval      *durationpb.Duration `protobuf:"bytes,2,opt,name=interval,proto3" json:"interval,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchPeersRequest) Reset() {
+	*x = WatchPeersRequest{}
+	mi := &file_wg_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchPeersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchPeersRequest) ProtoMessage() {}
+
+func (x *WatchPeersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wg_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchPeersRequest.ProtoReflect.Descriptor instead.
+func (*WatchPeersRequest) Descriptor() ([]byte, []int) {
+	return file_wg_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *WatchPeersRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *WatchPeersRequest) GetInterval() *durationpb.Duration {
+	if x != nil {
+		return x.Interval
+	}
+	return nil
+}
+
+type PeerEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          PeerEvent_Type         `protobuf:"varint,1,opt,name=type,proto3,enum=wg.v1.PeerEvent_Type" json:"type,omitempty"`
+	Peer          *Peer                  `protobuf:"bytes,2,opt,name=peer,proto3" json:"peer,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PeerEvent) Reset() {
+	*x = PeerEvent{}
+	mi := &file_wg_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PeerEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerEvent) ProtoMessage() {}
+
+func (x *PeerEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_wg_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerEvent.ProtoReflect.Descriptor instead.
+func (*PeerEvent) Descriptor() ([]byte, []int) {
+	return file_wg_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *PeerEvent) GetType() PeerEvent_Type {
+	if x != nil {
+		return x.Type
+	}
+	return PeerEvent_TYPE_UNSPECIFIED
+}
+
+func (x *PeerEvent) GetPeer() *Peer {
+	if x != nil {
+		return x.Peer
+	}
+	return nil
+}
+
+var File_wg_proto protoreflect.FileDescriptor
+
+const file_wg_proto_rawDesc = "" +
+	"\n" +
+	"\bwg.proto\x12\x05wg.v1\x1a\x1egoogle/protobuf/duration.proto\"\x14\n" +
+	"\x12ListDevicesRequest\"+\n" +
+	"\x13ListDevicesResponse\x12\x14\n" +
+	"\x05names\x18\x01 \x03(\tR\x05names\"&\n" +
+	"\x10GetDeviceRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"\x97\x01\n" +
+	"\x06Device\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1f\n" +
+	"\vlisten_port\x18\x02 \x01(\x05R\n" +
+	"listenPort\x12\x16\n" +
+	"\x06fwmark\x18\x03 \x01(\tR\x06fwmark\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x04 \x01(\tR\tpublicKey\x12!\n" +
+	"\x05peers\x18\x05 \x03(\v2\v.wg.v1.PeerR\x05peers\"\xf0\x01\n" +
+	"\x04Peer\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x01 \x01(\tR\tpublicKey\x12\x1f\n" +
+	"\vallowed_ips\x18\x02 \x03(\tR\n" +
+	"allowedIps\x12\x1a\n" +
+	"\bendpoint\x18\x03 \x01(\tR\bendpoint\x121\n" +
+	"\x14persistent_keepalive\x18\x04 \x01(\x05R\x13persistentKeepalive\x12)\n" +
+	"\x10latest_handshake\x18\x05 \x01(\x03R\x0flatestHandshake\x12\x1a\n" +
+	"\breceived\x18\x06 \x01(\x03R\breceived\x12\x12\n" +
+	"\x04sent\x18\a \x01(\x03R\x04sent\"\xaf\x01\n" +
+	"\x16ConfigureDeviceRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1f\n" +
+	"\vlisten_port\x18\x02 \x01(\x05R\n" +
+	"listenPort\x12\x16\n" +
+	"\x06fwmark\x18\x03 \x01(\tR\x06fwmark\x12\x1f\n" +
+	"\vprivate_key\x18\x04 \x01(\tR\n" +
+	"privateKey\x12'\n" +
+	"\x05peers\x18\x05 \x03(\v2\x11.wg.v1.PeerConfigR\x05peers\"\xf6\x01\n" +
+	"\n" +
+	"PeerConfig\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x01 \x01(\tR\tpublicKey\x12\x16\n" +
+	"\x06remove\x18\x02 \x01(\bR\x06remove\x12#\n" +
+	"\rpreshared_key\x18\x03 \x01(\tR\fpresharedKey\x12\x1a\n" +
+	"\bendpoint\x18\x04 \x01(\tR\bendpoint\x126\n" +
+	"\x14persistent_keepalive\x18\x05 \x01(\x05H\x00R\x13persistentKeepalive\x88\x01\x01\x12\x1f\n" +
+	"\vallowed_ips\x18\x06 \x03(\tR\n" +
+	"allowedIpsB\x17\n" +
+	"\x15_persistent_keepalive\"\x19\n" +
+	"\x17ConfigureDeviceResponse\"=\n" +
+	"\x16GenerateKeyPairRequest\x12#\n" +
+	"\rpreshared_key\x18\x01 \x01(\bR\fpresharedKey\"n\n" +
+	"\aKeyPair\x12\x1f\n" +
+	"\vprivate_key\x18\x01 \x01(\tR\n" +
+	"privateKey\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x02 \x01(\tR\tpublicKey\x12#\n" +
+	"\rpreshared_key\x18\x03 \x01(\tR\fpresharedKey\"^\n" +
+	"\x11WatchPeersRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x125\n" +
+	"\binterval\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\binterval\"\x9a\x01\n" +
+	"\tPeerEvent\x12)\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x15.wg.v1.PeerEvent.TypeR\x04type\x12\x1f\n" +
+	"\x04peer\x18\x02 \x01(\v2\v.wg.v1.PeerR\x04peer\"A\n" +
+	"\x04Type\x12\x14\n" +
+	"\x10TYPE_UNSPECIFIED\x10\x00\x12\t\n" +
+	"\x05ADDED\x10\x01\x12\v\n" +
+	"\aUPDATED\x10\x02\x12\v\n" +
+	"\aREMOVED\x10\x032\xd6\x02\n" +
+	"\tWireGuard\x12D\n" +
+	"\vListDevices\x12\x19.wg.v1.ListDevicesRequest\x1a\x1a.wg.v1.ListDevicesResponse\x123\n" +
+	"\tGetDevice\x12\x17.wg.v1.GetDeviceRequest\x1a\r.wg.v1.Device\x12P\n" +
+	"\x0fConfigureDevice\x12\x1d.wg.v1.ConfigureDeviceRequest\x1a\x1e.wg.v1.ConfigureDeviceResponse\x12@\n" +
+	"\x0fGenerateKeyPair\x12\x1d.wg.v1.GenerateKeyPairRequest\x1a\x0e.wg.v1.KeyPair\x12:\n" +
+	"\n" +
+	"WatchPeers\x12\x18.wg.v1.WatchPeersRequest\x1a\x10.wg.v1.PeerEvent0\x01B!Z\x1fseankhliao.com/go-wg/wgrpc/wgpbb\x06proto3"
+
+var (
+	file_wg_proto_rawDescOnce sync.Once
+	file_wg_proto_rawDescData []byte
+)
+
+func file_wg_proto_rawDescGZIP() []byte {
+	file_wg_proto_rawDescOnce.Do(func() {
+		file_wg_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_wg_proto_rawDesc), len(file_wg_proto_rawDesc)))
+	})
+	return file_wg_proto_rawDescData
+}
+
+var file_wg_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_wg_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_wg_proto_goTypes = []any{
+	(PeerEvent_Type)(0),             // 0: wg.v1.PeerEvent.Type
+	(*ListDevicesRequest)(nil),      // 1: wg.v1.ListDevicesRequest
+	(*ListDevicesResponse)(nil),     // 2: wg.v1.ListDevicesResponse
+	(*GetDeviceRequest)(nil),        // 3: wg.v1.GetDeviceRequest
+	(*Device)(nil),                  // 4: wg.v1.Device
+	(*Peer)(nil),                    // 5: wg.v1.Peer
+	(*ConfigureDeviceRequest)(nil),  // 6: wg.v1.ConfigureDeviceRequest
+	(*PeerConfig)(nil),              // 7: wg.v1.PeerConfig
+	(*ConfigureDeviceResponse)(nil), // 8: wg.v1.ConfigureDeviceResponse
+	(*GenerateKeyPairRequest)(nil),  // 9: wg.v1.GenerateKeyPairRequest
+	(*KeyPair)(nil),                 // 10: wg.v1.KeyPair
+	(*WatchPeersRequest)(nil),       // 11: wg.v1.WatchPeersRequest
+	(*PeerEvent)(nil),               // 12: wg.v1.PeerEvent
+	(*durationpb.Duration)(nil),     // 13: google.protobuf.Duration
+}
+var file_wg_proto_depIdxs = []int32{
+	5,  // 0: wg.v1.Device.peers:type_name -> wg.v1.Peer
+	7,  // 1: wg.v1.ConfigureDeviceRequest.peers:type_name -> wg.v1.PeerConfig
+	13, // 2: wg.v1.WatchPeersRequest.interval:type_name -> google.protobuf.Duration
+	0,  // 3: wg.v1.PeerEvent.type:type_name -> wg.v1.PeerEvent.Type
+	5,  // 4: wg.v1.PeerEvent.peer:type_name -> wg.v1.Peer
+	1,  // 5: wg.v1.WireGuard.ListDevices:input_type -> wg.v1.ListDevicesRequest
+	3,  // 6: wg.v1.WireGuard.GetDevice:input_type -> wg.v1.GetDeviceRequest
+	6,  // 7: wg.v1.WireGuard.ConfigureDevice:input_type -> wg.v1.ConfigureDeviceRequest
+	9,  // 8: wg.v1.WireGuard.GenerateKeyPair:input_type -> wg.v1.GenerateKeyPairRequest
+	11, // 9: wg.v1.WireGuard.WatchPeers:input_type -> wg.v1.WatchPeersRequest
+	2,  // 10: wg.v1.WireGuard.ListDevices:output_type -> wg.v1.ListDevicesResponse
+	4,  // 11: wg.v1.WireGuard.GetDevice:output_type -> wg.v1.Device
+	8,  // 12: wg.v1.WireGuard.ConfigureDevice:output_type -> wg.v1.ConfigureDeviceResponse
+	10, // 13: wg.v1.WireGuard.GenerateKeyPair:output_type -> wg.v1.KeyPair
+	12, // 14: wg.v1.WireGuard.WatchPeers:output_type -> wg.v1.PeerEvent
+	10, // [10:15] is the sub-list for method output_type
+	5,  // [5:10] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_wg_proto_init() }
+func file_wg_proto_init() {
+	if File_wg_proto != nil {
+		return
+	}
+	file_wg_proto_msgTypes[6].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_wg_proto_rawDesc), len(file_wg_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_wg_proto_goTypes,
+		DependencyIndexes: file_wg_proto_depIdxs,
+		EnumInfos:         file_wg_proto_enumTypes,
+		MessageInfos:      file_wg_proto_msgTypes,
+	}.Build()
+	File_wg_proto = out.File
+	file_wg_proto_goTypes = nil
+	file_wg_proto_depIdxs = nil
+}
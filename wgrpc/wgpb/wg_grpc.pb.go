@@ -0,0 +1,293 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: wg.proto
+
+package wgpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	WireGuard_ListDevices_FullMethodName     = "/wg.v1.WireGuard/ListDevices"
+	WireGuard_GetDevice_FullMethodName       = "/wg.v1.WireGuard/GetDevice"
+	WireGuard_ConfigureDevice_FullMethodName = "/wg.v1.WireGuard/ConfigureDevice"
+	WireGuard_GenerateKeyPair_FullMethodName = "/wg.v1.WireGuard/GenerateKeyPair"
+	WireGuard_WatchPeers_FullMethodName      = "/wg.v1.WireGuard/WatchPeers"
+)
+
+// WireGuardClient is the client API for WireGuard service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// WireGuard manages the wireguard interfaces (devices) of a host
+type WireGuardClient interface {
+	// ListDevices lists interface names (wg show interfaces)
+	ListDevices(ctx context.Context, in *ListDevicesRequest, opts ...grpc.CallOption) (*ListDevicesResponse, error)
+	// GetDevice gets the status of an interface (wg show), private and preshared keys are omitted
+	GetDevice(ctx context.Context, in *GetDeviceRequest, opts ...grpc.CallOption) (*Device, error)
+	// ConfigureDevice changes an interface (wg set)
+	ConfigureDevice(ctx context.Context, in *ConfigureDeviceRequest, opts ...grpc.CallOption) (*ConfigureDeviceResponse, error)
+	// GenerateKeyPair generates keys (wg genkey, wg pubkey, wg genpsk)
+	GenerateKeyPair(ctx context.Context, in *GenerateKeyPairRequest, opts ...grpc.CallOption) (*KeyPair, error)
+	// WatchPeers streams the current peers of an interface as ADDED events,
+	// followed by changes found by periodically showing the interface
+	WatchPeers(ctx context.Context, in *WatchPeersRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PeerEvent], error)
+}
+
+type wireGuardClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWireGuardClient(cc grpc.ClientConnInterface) WireGuardClient {
+	return &wireGuardClient{cc}
+}
+
+func (c *wireGuardClient) ListDevices(ctx context.Context, in *ListDevicesRequest, opts ...grpc.CallOption) (*ListDevicesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListDevicesResponse)
+	err := c.cc.Invoke(ctx, WireGuard_ListDevices_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireGuardClient) GetDevice(ctx context.Context, in *GetDeviceRequest, opts ...grpc.CallOption) (*Device, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Device)
+	err := c.cc.Invoke(ctx, WireGuard_GetDevice_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireGuardClient) ConfigureDevice(ctx context.Context, in *ConfigureDeviceRequest, opts ...grpc.CallOption) (*ConfigureDeviceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ConfigureDeviceResponse)
+	err := c.cc.Invoke(ctx, WireGuard_ConfigureDevice_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireGuardClient) GenerateKeyPair(ctx context.Context, in *GenerateKeyPairRequest, opts ...grpc.CallOption) (*KeyPair, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(KeyPair)
+	err := c.cc.Invoke(ctx, WireGuard_GenerateKeyPair_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireGuardClient) WatchPeers(ctx context.Context, in *WatchPeersRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PeerEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &WireGuard_ServiceDesc.Streams[0], WireGuard_WatchPeers_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchPeersRequest, PeerEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type WireGuard_WatchPeersClient = grpc.ServerStreamingClient[PeerEvent]
+
+// WireGuardServer is the server API for WireGuard service.
+// All implementations must embed UnimplementedWireGuardServer
+// for forward compatibility.
+//
+// WireGuard manages the wireguard interfaces (devices) of a host
+type WireGuardServer interface {
+	// ListDevices lists interface names (wg show interfaces)
+	ListDevices(context.Context, *ListDevicesRequest) (*ListDevicesResponse, error)
+	// GetDevice gets the status of an interface (wg show), private and preshared keys are omitted
+	GetDevice(context.Context, *GetDeviceRequest) (*Device, error)
+	// ConfigureDevice changes an interface (wg set)
+	ConfigureDevice(context.Context, *ConfigureDeviceRequest) (*ConfigureDeviceResponse, error)
+	// GenerateKeyPair generates keys (wg genkey, wg pubkey, wg genpsk)
+	GenerateKeyPair(context.Context, *GenerateKeyPairRequest) (*KeyPair, error)
+	// WatchPeers streams the current peers of an interface as ADDED events,
+	// followed by changes found by periodically showing the interface
+	WatchPeers(*WatchPeersRequest, grpc.ServerStreamingServer[PeerEvent]) error
+	mustEmbedUnimplementedWireGuardServer()
+}
+
+// UnimplementedWireGuardServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedWireGuardServer struct{}
+
+func (UnimplementedWireGuardServer) ListDevices(context.Context, *ListDevicesRequest) (*ListDevicesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListDevices not implemented")
+}
+func (UnimplementedWireGuardServer) GetDevice(context.Context, *GetDeviceRequest) (*Device, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetDevice not implemented")
+}
+func (UnimplementedWireGuardServer) ConfigureDevice(context.Context, *ConfigureDeviceRequest) (*ConfigureDeviceResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ConfigureDevice not implemented")
+}
+func (UnimplementedWireGuardServer) GenerateKeyPair(context.Context, *GenerateKeyPairRequest) (*KeyPair, error) {
+	return nil, status.Error(codes.Unimplemented, "method GenerateKeyPair not implemented")
+}
+func (UnimplementedWireGuardServer) WatchPeers(*WatchPeersRequest, grpc.ServerStreamingServer[PeerEvent]) error {
+	return status.Error(codes.Unimplemented, "method WatchPeers not implemented")
+}
+func (UnimplementedWireGuardServer) mustEmbedUnimplementedWireGuardServer() {}
+func (UnimplementedWireGuardServer) testEmbeddedByValue()                   {}
+
+// UnsafeWireGuardServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to WireGuardServer will
+// result in compilation errors.
+type UnsafeWireGuardServer interface {
+	mustEmbedUnimplementedWireGuardServer()
+}
+
+func RegisterWireGuardServer(s grpc.ServiceRegistrar, srv WireGuardServer) {
+	// If the following call panics, it indicates UnimplementedWireGuardServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&WireGuard_ServiceDesc, srv)
+}
+
+func _WireGuard_ListDevices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDevicesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireGuardServer).ListDevices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WireGuard_ListDevices_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireGuardServer).ListDevices(ctx, req.(*ListDevicesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireGuard_GetDevice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDeviceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireGuardServer).GetDevice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WireGuard_GetDevice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireGuardServer).GetDevice(ctx, req.(*GetDeviceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireGuard_ConfigureDevice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfigureDeviceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireGuardServer).ConfigureDevice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WireGuard_ConfigureDevice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireGuardServer).ConfigureDevice(ctx, req.(*ConfigureDeviceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireGuard_GenerateKeyPair_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateKeyPairRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireGuardServer).GenerateKeyPair(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WireGuard_GenerateKeyPair_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireGuardServer).GenerateKeyPair(ctx, req.(*GenerateKeyPairRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireGuard_WatchPeers_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchPeersRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(WireGuardServer).WatchPeers(m, &grpc.GenericServerStream[WatchPeersRequest, PeerEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type WireGuard_WatchPeersServer = grpc.ServerStreamingServer[PeerEvent]
+
+// WireGuard_ServiceDesc is the grpc.ServiceDesc for WireGuard service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var WireGuard_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "wg.v1.WireGuard",
+	HandlerType: (*WireGuardServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListDevices",
+			Handler:    _WireGuard_ListDevices_Handler,
+		},
+		{
+			MethodName: "GetDevice",
+			Handler:    _WireGuard_GetDevice_Handler,
+		},
+		{
+			MethodName: "ConfigureDevice",
+			Handler:    _WireGuard_ConfigureDevice_Handler,
+		},
+		{
+			MethodName: "GenerateKeyPair",
+			Handler:    _WireGuard_GenerateKeyPair_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchPeers",
+			Handler:       _WireGuard_WatchPeers_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "wg.proto",
+}
@@ -0,0 +1,194 @@
+package wgrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	wg "seankhliao.com/go-wg"
+	"seankhliao.com/go-wg/wgrpc/wgpb"
+	"seankhliao.com/go-wg/wgtest"
+)
+
+var (
+	se = "%v #%v errored: %v"
+	sf = "%v #%v \nexp: >%v< \ngot: >%v<"
+
+	privA = "AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA="
+	pubA  = "B6N8vBQgk8i3VdwbEOhstCY3StFqqFPtC9/AsrhtHHw="
+	keyC  = "ICEiIyQlJicoKSorLC0uLzAxMjM0NTY3ODk6Ozw9Pj8="
+)
+
+// newClient serves a fake over bufconn
+func newClient(t *testing.T) (*wgtest.Client, *Client) {
+	fake := wgtest.NewClient(map[string]wg.Conf{
+		"wg0": {
+			Interface: wg.Interface{ListenPort: 51820, PrivateKey: privA, PublicKey: pubA},
+			Peers: []wg.Peer{
+				{PublicKey: keyC, PresharedKey: keyC, AllowedIPs: []string{"10.0.0.2/32"}, LatestHandshake: 5},
+			},
+		},
+	})
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	(&Server{Client: fake, MinInterval: time.Millisecond}).Register(gs)
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf(se, "dial", 0, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return fake, NewClient(conn)
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	fake, c := newClient(t)
+	var _ wg.Client = c
+
+	ifaces, err := c.ShowInterfaces(ctx)
+	if err != nil || !reflect.DeepEqual(ifaces, []string{"wg0"}) {
+		t.Errorf(sf, "ShowInterfaces", 0, []string{"wg0"}, ifaces)
+	}
+
+	conf, err := c.Show(ctx, "wg0")
+	if err != nil {
+		t.Fatalf(se, "Show", 0, err)
+	}
+	exp := wg.Conf{
+		Interface: wg.Interface{ListenPort: 51820, PublicKey: pubA},
+		Peers:     []wg.Peer{{PublicKey: keyC, AllowedIPs: []string{"10.0.0.2/32"}, LatestHandshake: 5}},
+	}
+	if !reflect.DeepEqual(conf, exp) {
+		t.Errorf(sf, "Show", 0, exp, conf)
+	}
+	_, err = c.Show(ctx, "wg1")
+	if status.Code(errors.Unwrap(err)) != codes.NotFound {
+		t.Errorf(sf, "Show", 1, codes.NotFound, err)
+	}
+
+	keyFile, cleanup, _ := wg.KeyFiles()
+	defer cleanup()
+	privFile, _ := keyFile(keyC)
+	pskFile, _ := keyFile(privA)
+	ka := 0
+	err = c.Set(ctx, wg.Opt{
+		Interface:    "wg0",
+		ListenPort:   51821,
+		PrivKeyFpath: privFile,
+		Peers: []wg.OptPeer{
+			{PublicKey: keyC, PersistentKeepalive: &ka, Endpoint: "1.2.3.4:51820"},
+			{PublicKey: pubA, PskFpath: pskFile, AllowedIPs: []string{"10.0.0.3/32"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf(se, "Set", 0, err)
+	}
+	got, _ := fake.Show(ctx, "wg0")
+	expConf := wg.Conf{
+		Interface: wg.Interface{ListenPort: 51821, PrivateKey: keyC, PublicKey: pubA},
+		Peers: []wg.Peer{
+			{PublicKey: keyC, PresharedKey: keyC, AllowedIPs: []string{"10.0.0.2/32"}, Endpoint: "1.2.3.4:51820", LatestHandshake: 5},
+			{PublicKey: pubA, PresharedKey: privA, AllowedIPs: []string{"10.0.0.3/32"}},
+		},
+	}
+	if !reflect.DeepEqual(got, expConf) {
+		t.Errorf(sf, "Set", 0, expConf, got)
+	}
+
+	err = c.Set(ctx, wg.Opt{Interface: "wg0", Peers: []wg.OptPeer{{PublicKey: "invalid"}}})
+	if status.Code(errors.Unwrap(err)) != codes.InvalidArgument {
+		t.Errorf(sf, "Set", 1, codes.InvalidArgument, err)
+	}
+
+	priv, pub, psk, err := c.GenerateKeyPair(ctx, true)
+	if err != nil {
+		t.Fatalf(se, "GenerateKeyPair", 0, err)
+	}
+	if derived, _ := wg.PublicKeyFor(priv); derived != pub || psk == "" {
+		t.Errorf(sf, "GenerateKeyPair", 0, derived, pub)
+	}
+}
+
+func TestWatchPeers(t *testing.T) {
+	fake, c := newClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	events := make(chan PeerEvent)
+	done := make(chan error)
+	go func() {
+		done <- c.WatchPeers(ctx, "wg0", time.Millisecond, func(ev PeerEvent) error {
+			select {
+			case events <- ev:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	next := func() PeerEvent {
+		select {
+		case ev := <-events:
+			return ev
+		case <-ctx.Done():
+			t.Fatalf(se, "WatchPeers", 0, ctx.Err())
+		}
+		return PeerEvent{}
+	}
+
+	cases := []struct {
+		Update func(*wg.Conf)
+		Exp    PeerEvent
+	}{
+		{
+			nil,
+			PeerEvent{wgpb.PeerEvent_ADDED, wg.Peer{PublicKey: keyC, AllowedIPs: []string{"10.0.0.2/32"}, LatestHandshake: 5}},
+		}, {
+			func(c *wg.Conf) { c.Peers = append(c.Peers, wg.Peer{PublicKey: pubA}) },
+			PeerEvent{wgpb.PeerEvent_ADDED, wg.Peer{PublicKey: pubA}},
+		}, {
+			// an aging handshake alone is not an update
+			func(c *wg.Conf) { c.Peers[0].LatestHandshake = 50 },
+			PeerEvent{},
+		}, {
+			func(c *wg.Conf) { c.Peers[0].Received = 100 },
+			PeerEvent{wgpb.PeerEvent_UPDATED, wg.Peer{PublicKey: keyC, AllowedIPs: []string{"10.0.0.2/32"}, LatestHandshake: 50, Received: 100}},
+		}, {
+			func(c *wg.Conf) { c.Peers = c.Peers[:1] },
+			PeerEvent{wgpb.PeerEvent_REMOVED, wg.Peer{PublicKey: pubA}},
+		},
+	}
+	for i, c := range cases {
+		if c.Update != nil {
+			fake.Update("wg0", c.Update)
+		}
+		if c.Exp.Peer.PublicKey == "" {
+			// let a few polls pass
+			time.Sleep(20 * time.Millisecond)
+			continue
+		}
+		if ev := next(); !reflect.DeepEqual(ev, c.Exp) {
+			t.Errorf(sf, "WatchPeers", i, c.Exp, ev)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf(sf, "WatchPeers", 0, context.Canceled, err)
+	}
+}
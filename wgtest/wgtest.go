@@ -0,0 +1,146 @@
+// Package wgtest provides an in memory wg.Client for tests
+package wgtest
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+
+	wg "seankhliao.com/go-wg"
+)
+
+// Client is an in memory wg.Client,
+// Set applies options to the stored Confs the same way wg set would
+type Client struct {
+	mu    sync.Mutex
+	confs map[string]wg.Conf
+	sets  []wg.Opt
+}
+
+// NewClient creates a Client with the given interfaces
+func NewClient(confs map[string]wg.Conf) *Client {
+	c := &Client{confs: make(map[string]wg.Conf)}
+	for iface, conf := range confs {
+		c.confs[iface] = copyConf(conf)
+	}
+	return c
+}
+
+// ShowInterfaces lists interfaces in sorted order
+func (c *Client) ShowInterfaces(ctx context.Context) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var ifaces []string
+	for iface := range c.confs {
+		ifaces = append(ifaces, iface)
+	}
+	sort.Strings(ifaces)
+	return ifaces, nil
+}
+
+// Show returns a copy of the Conf for iface
+func (c *Client) Show(ctx context.Context, iface string) (wg.Conf, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	conf, ok := c.confs[iface]
+	if !ok {
+		return wg.Conf{}, fmt.Errorf("show: no interface %v", iface)
+	}
+	return copyConf(conf), nil
+}
+
+// Set applies opt to the Conf for opt.Interface,
+// key file paths are read from disk
+func (c *Client) Set(ctx context.Context, opt wg.Opt) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	conf, ok := c.confs[opt.Interface]
+	if !ok {
+		return fmt.Errorf("set: no interface %v", opt.Interface)
+	}
+	c.sets = append(c.sets, opt)
+
+	if opt.ListenPort != 0 {
+		conf.ListenPort = opt.ListenPort
+	}
+	if opt.FwMark != "" {
+		conf.FwMark = opt.FwMark
+	}
+	if opt.PrivKeyFpath != "" {
+		b, err := ioutil.ReadFile(opt.PrivKeyFpath)
+		if err != nil {
+			return fmt.Errorf("set: %v", err)
+		}
+		conf.PrivateKey = strings.TrimSpace(string(b))
+	}
+	for _, op := range opt.Peers {
+		idx := -1
+		for i, p := range conf.Peers {
+			if p.PublicKey == op.PublicKey {
+				idx = i
+				break
+			}
+		}
+		if op.Remove {
+			if idx != -1 {
+				conf.Peers = append(conf.Peers[:idx], conf.Peers[idx+1:]...)
+			}
+			continue
+		}
+		if idx == -1 {
+			conf.Peers = append(conf.Peers, wg.Peer{PublicKey: op.PublicKey})
+			idx = len(conf.Peers) - 1
+		}
+		p := &conf.Peers[idx]
+		if op.PskFpath != "" {
+			b, err := ioutil.ReadFile(op.PskFpath)
+			if err != nil {
+				return fmt.Errorf("set: %v", err)
+			}
+			p.PresharedKey = strings.TrimSpace(string(b))
+		}
+		if op.Endpoint != "" {
+			p.Endpoint = op.Endpoint
+		}
+		if op.PersistentKeepalive != nil {
+			p.PersistentKeepalive = *op.PersistentKeepalive
+		}
+		if len(op.AllowedIPs) != 0 {
+			p.AllowedIPs = append([]string{}, op.AllowedIPs...)
+		}
+	}
+	c.confs[opt.Interface] = conf
+	return nil
+}
+
+// Update modifies the stored Conf for iface directly,
+// for simulating show only values like LatestHandshake
+func (c *Client) Update(iface string, f func(*wg.Conf)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	conf := c.confs[iface]
+	f(&conf)
+	c.confs[iface] = conf
+}
+
+// Sets returns all options passed to Set
+func (c *Client) Sets() []wg.Opt {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]wg.Opt{}, c.sets...)
+}
+
+func copyConf(c wg.Conf) wg.Conf {
+	c.Address = append([]string(nil), c.Address...)
+	c.DNS = append([]string(nil), c.DNS...)
+	peers := c.Peers
+	c.Peers = nil
+	for _, p := range peers {
+		p.AllowedIPs = append([]string(nil), p.AllowedIPs...)
+		c.Peers = append(c.Peers, p)
+	}
+	return c
+}
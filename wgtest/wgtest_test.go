@@ -0,0 +1,77 @@
+package wgtest
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	wg "seankhliao.com/go-wg"
+)
+
+var (
+	se = "%v #%v errored: %v"
+	sf = "%v #%v \nexp: >%v< \ngot: >%v<"
+)
+
+func TestSet(t *testing.T) {
+	f, err := ioutil.TempFile("", "psk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("preshared_key\n")
+	f.Close()
+
+	pka := 25
+	cases := []struct {
+		O wg.Opt
+		C wg.Conf
+	}{
+		{
+			wg.Opt{
+				Interface:  "wg0",
+				ListenPort: 51820,
+				Peers: []wg.OptPeer{
+					{PublicKey: "pubkey_a", Remove: true},
+					{PublicKey: "pubkey_b", Endpoint: "1.2.3.4:5678", PskFpath: f.Name(), PersistentKeepalive: &pka},
+					{PublicKey: "pubkey_c", AllowedIPs: []string{"10.0.0.3/32"}},
+				},
+			},
+			wg.Conf{
+				Interface: wg.Interface{ListenPort: 51820},
+				Peers: []wg.Peer{
+					{PublicKey: "pubkey_b", AllowedIPs: []string{"10.0.0.2/32"}, Endpoint: "1.2.3.4:5678", PresharedKey: "preshared_key", PersistentKeepalive: 25},
+					{PublicKey: "pubkey_c", AllowedIPs: []string{"10.0.0.3/32"}},
+				},
+			},
+		},
+	}
+	for i, c := range cases {
+		client := NewClient(map[string]wg.Conf{
+			"wg0": {
+				Peers: []wg.Peer{
+					{PublicKey: "pubkey_a", AllowedIPs: []string{"10.0.0.1/32"}},
+					{PublicKey: "pubkey_b", AllowedIPs: []string{"10.0.0.2/32"}},
+				},
+			},
+		})
+		err := client.Set(context.Background(), c.O)
+		if err != nil {
+			t.Errorf(se, "Set", i, err)
+			continue
+		}
+		conf, err := client.Show(context.Background(), "wg0")
+		if err != nil {
+			t.Errorf(se, "Show", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(conf, c.C) {
+			t.Errorf(sf, "Set", i, c.C, conf)
+		}
+		if sets := client.Sets(); len(sets) != 1 {
+			t.Errorf(sf, "Sets", i, 1, len(sets))
+		}
+	}
+}